@@ -41,6 +41,12 @@ type JWTClaims struct {
 
 func jwtToken(r *http.Request) (*jwt.Token, error) {
 	auth := r.Header.Get(`Authorization`)
+	if len(auth) == 0 && isStreamRequest(r) {
+		// WebSocket and EventSource clients of browsers can't specify headers
+		if token := r.URL.Query().Get(`token`); len(token) > 0 {
+			auth = jwtPrefix + token
+		}
+	}
 	if len(auth) == 0 {
 		return nil, nil
 	}
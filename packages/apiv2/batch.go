@@ -0,0 +1,148 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const maxBatchContracts = 100
+
+type batchContract struct {
+	Contract string                 `json:"contract"`
+	Params   map[string]interface{} `json:"params"`
+}
+
+type prepareBatchResult struct {
+	ForSign []string       `json:"forsign"`
+	Signs   [][]TxSignJSON `json:"signs"`
+	Time    string         `json:"time"`
+}
+
+type contractBatchResult struct {
+	Hashes []string `json:"hashes"`
+}
+
+// form converts the parameters of the contract call to the form values.
+// Arrays are passed like name[] values of the form.
+func (bc *batchContract) form() url.Values {
+	form := url.Values{}
+	for key, value := range bc.Params {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				form.Add(key+`[]`, fmt.Sprint(item))
+			}
+		case string:
+			form.Set(key, v)
+		case json.Number:
+			form.Set(key, v.String())
+		case nil:
+		default:
+			form.Set(key, fmt.Sprint(v))
+		}
+	}
+	return form
+}
+
+func getBatchContracts(w http.ResponseWriter, data *apiData, logger *log.Entry) ([]batchContract, error) {
+	var contracts []batchContract
+	// the numbers are decoded as json.Number so the big integers and the money keep all digits
+	decoder := json.NewDecoder(strings.NewReader(data.params[`data`].(string)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&contracts); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling batch of contracts")
+		return nil, errorAPI(w, err, http.StatusBadRequest)
+	}
+	if len(contracts) == 0 || len(contracts) > maxBatchContracts {
+		logger.WithFields(log.Fields{"type": consts.ParameterExceeded, "count": len(contracts)}).Error("wrong number of contracts in batch")
+		return nil, errorAPI(w, `E_BATCHSIZE`, http.StatusBadRequest, maxBatchContracts)
+	}
+	return contracts, nil
+}
+
+func prepareBatch(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	contracts, err := getBatchContracts(w, data, logger)
+	if err != nil {
+		return err
+	}
+	timeNow := time.Now().Unix()
+	result := prepareBatchResult{
+		ForSign: make([]string, 0, len(contracts)),
+		Signs:   make([][]TxSignJSON, 0, len(contracts)),
+		Time:    converter.Int64ToStr(timeNow),
+	}
+	for _, item := range contracts {
+		ret, err := prepareSmartTx(w, item.Contract, item.form(), data, timeNow, logger)
+		if err != nil {
+			return err
+		}
+		result.ForSign = append(result.ForSign, ret.ForSign)
+		result.Signs = append(result.Signs, ret.Signs)
+	}
+	data.result = &result
+	return nil
+}
+
+func contractBatch(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	contracts, err := getBatchContracts(w, data, logger)
+	if err != nil {
+		return err
+	}
+	signatures := strings.Split(data.params[`signatures`].(string), `,`)
+	if len(signatures) != len(contracts) {
+		logger.WithFields(log.Fields{"type": consts.SizeDoesNotMatch, "signatures": len(signatures), "contracts": len(contracts)}).Error("number of signatures doesn't match")
+		return errorAPI(w, `E_BATCHSIGN`, http.StatusBadRequest, len(signatures), len(contracts))
+	}
+	txTypes := make([]int64, 0, len(contracts))
+	txData := make([][]byte, 0, len(contracts))
+	for i, item := range contracts {
+		signature, err := hex.DecodeString(strings.TrimSpace(signatures[i]))
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.ConvertionError, "value": signatures[i], "error": err}).Error("decoding signature from hex")
+			return errorAPI(w, err, http.StatusBadRequest)
+		}
+		txType, serializedData, err := smartTx(w, item.Contract, item.form(), data.params[`time`].(string), signature, data, logger)
+		if err != nil {
+			return err
+		}
+		txTypes = append(txTypes, txType)
+		txData = append(txData, serializedData)
+	}
+	hashes, err := model.SendTxBatch(txTypes, data.keyId, txData)
+	if err != nil {
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := contractBatchResult{Hashes: make([]string, 0, len(hashes))}
+	for _, hash := range hashes {
+		result.Hashes = append(result.Hashes, hex.EncodeToString(hash))
+	}
+	data.result = &result
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestBatchContractForm(t *testing.T) {
+	data := &apiData{params: map[string]interface{}{`data`: `[{"contract":"Test","params":{
+		"Amount":12345678901234567890123,"Price":0.000001,"List":[1,"a",10000000000000000001]}}]`}}
+	contracts, err := getBatchContracts(httptest.NewRecorder(), data, log.WithFields(log.Fields{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	form := contracts[0].form()
+	if amount := form.Get(`Amount`); amount != `12345678901234567890123` {
+		t.Errorf(`wrong amount %s`, amount)
+	}
+	if price := form.Get(`Price`); price != `0.000001` {
+		t.Errorf(`wrong price %s`, price)
+	}
+	if list := strings.Join(form[`List[]`], `,`); list != `1,a,10000000000000000001` {
+		t.Errorf(`wrong list %s`, list)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	if err := keyLogin(1); err != nil {
+		t.Error(err)
+		return
+	}
+	batch := `[{"contract":"MoneyTransfer","params":{"Amount":"1000","Recipient":"0005-2070-2000-0006-0200"}},
+	{"contract":"MoneyTransfer","params":{"Amount":"2000","Recipient":"0005-2070-2000-0006-0200","Comment":"Batch"}}]`
+
+	var ret prepareBatchResult
+	if err := sendPost(`preparebatch`, &url.Values{`data`: {batch}}, &ret); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ret.ForSign) != 2 {
+		t.Error(fmt.Errorf(`wrong number of forsign %d`, len(ret.ForSign)))
+		return
+	}
+	signs := make([]string, 0, len(ret.ForSign))
+	for _, forsign := range ret.ForSign {
+		sign, err := getSign(forsign)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		signs = append(signs, sign)
+	}
+	var result contractBatchResult
+	form := url.Values{`data`: {batch}, `time`: {ret.Time}, `signatures`: {strings.Join(signs, `,`)}}
+	if err := sendPost(`contractbatch`, &form, &result); err != nil {
+		t.Error(err)
+		return
+	}
+	for _, hash := range result.Hashes {
+		if id, err := waitTx(hash); id == 0 {
+			t.Error(err)
+			return
+		}
+	}
+
+	form = url.Values{`data`: {batch}, `time`: {ret.Time}, `signatures`: {signs[0]}}
+	err := sendPost(`contractbatch`, &form, &result)
+	if err == nil || !strings.Contains(err.Error(), `E_BATCHSIGN`) {
+		t.Error(fmt.Errorf(`wrong number of signatures must be rejected %v`, err))
+		return
+	}
+	err = sendPost(`preparebatch`, &url.Values{`data`: {`[]`}}, &ret)
+	if err == nil || !strings.Contains(err.Error(), `E_BATCHSIZE`) {
+		t.Error(fmt.Errorf(`empty batch must be rejected %v`, err))
+	}
+}
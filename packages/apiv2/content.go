@@ -32,6 +32,7 @@ type contentResult struct {
 	MenuTree string `json:"menutree,omitempty"`
 	Title    string `json:"title,omitempty"`
 	Tree     string `json:"tree"`
+	Profile  string `json:"profile,omitempty"`
 }
 
 func initVars(r *http.Request, data *apiData) *map[string]string {
@@ -45,6 +46,17 @@ func initVars(r *http.Request, data *apiData) *map[string]string {
 	return &vars
 }
 
+// template2JSON converts the template to JSON, if the profile parameter is set it also returns
+// the time of the rendering of the tags and SQL queries. The profile parameter is accepted only
+// by the routes with authorization
+func template2JSON(input string, data *apiData, vars *map[string]string) (string, string) {
+	if profile, _ := data.params[`profile`].(int64); profile != 1 {
+		return string(templatev2.Template2JSON(input, false, vars)), ``
+	}
+	out, profile := templatev2.Template2JSONProfile(input, false, vars)
+	return string(out), profile.String()
+}
+
 func getPage(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
 
 	page := &model.Page{}
@@ -59,13 +71,13 @@ func getPage(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.
 		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
 	}
 
-	ret := templatev2.Template2JSON(page.Value, false, initVars(r, data))
+	ret, profile := template2JSON(page.Value, data, initVars(r, data))
 
 	menu, err := model.Single(`SELECT value FROM "`+converter.Int64ToStr(data.ecosystemId)+
 		`_menu" WHERE name = ?`, page.Menu).String()
 	retmenu := templatev2.Template2JSON(menu, false, initVars(r, data))
 
-	data.result = &contentResult{Tree: string(ret), Menu: page.Menu, MenuTree: string(retmenu)}
+	data.result = &contentResult{Tree: ret, Menu: page.Menu, MenuTree: string(retmenu), Profile: profile}
 	return nil
 }
 
@@ -83,13 +95,13 @@ func getMenu(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.
 		return errorAPI(w, `E_NOTFOUND`, http.StatusNotFound)
 	}
 
-	ret := templatev2.Template2JSON(menu.Value, false, initVars(r, data))
-	data.result = &contentResult{Tree: string(ret), Title: menu.Title}
+	ret, profile := template2JSON(menu.Value, data, initVars(r, data))
+	data.result = &contentResult{Tree: ret, Title: menu.Title, Profile: profile}
 	return nil
 }
 
 func jsonContent(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
-	ret := templatev2.Template2JSON(data.params[`template`].(string), false, initVars(r, data))
-	data.result = &contentResult{Tree: string(ret)}
+	ret, profile := template2JSON(data.params[`template`].(string), data, initVars(r, data))
+	data.result = &contentResult{Tree: ret, Profile: profile}
 	return nil
 }
@@ -19,6 +19,7 @@ package apiv2
 import (
 	"encoding/hex"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -36,16 +37,32 @@ type contractResult struct {
 }
 
 func contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	txType, serializedData, err := smartTx(w, data.params[`name`].(string), r.Form, data.params[`time`].(string),
+		data.params[`signature`].([]byte), data, logger)
+	if err != nil {
+		return err
+	}
+	hash, err := model.SendTx(txType, data.keyId, serializedData)
+	if err != nil {
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &contractResult{Hash: hex.EncodeToString(hash)} // !!! string(converter.BinToHex(hash))}
+	return nil
+}
+
+// smartTx returns the type and the binary data of the signed transaction for the call of the contract
+func smartTx(w http.ResponseWriter, name string, form url.Values, txTime string, signature []byte, data *apiData,
+	logger *log.Entry) (int64, []byte, error) {
 	var (
-		hash, publicKey []byte
-		toSerialize     interface{}
+		publicKey   []byte
+		toSerialize interface{}
 	)
-	contract, parerr, err := validateSmartContract(r, data, nil)
+	contract, parerr, err := validateSmartContract(name, form, data, nil)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), `E_`) {
-			return errorAPI(w, err.Error(), http.StatusBadRequest, parerr)
+			return 0, nil, errorAPI(w, err.Error(), http.StatusBadRequest, parerr)
 		}
-		return errorAPI(w, err, http.StatusBadRequest)
+		return 0, nil, errorAPI(w, err, http.StatusBadRequest)
 	}
 	info := (*contract).Block.Info.(*script.ContractInfo)
 
@@ -54,7 +71,7 @@ func contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log
 	err = key.Get(data.keyId)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting public key from keys")
-		return errorAPI(w, err, http.StatusInternalServerError)
+		return 0, nil, errorAPI(w, err, http.StatusInternalServerError)
 	}
 	if len(key.PublicKey) == 0 {
 		if _, ok := data.params[`pubkey`]; ok && len(data.params[`pubkey`].([]byte)) > 0 {
@@ -66,29 +83,55 @@ func contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log
 		}
 		if len(publicKey) == 0 {
 			logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("public key is empty")
-			return errorAPI(w, `E_EMPTYPUBLIC`, http.StatusBadRequest)
+			return 0, nil, errorAPI(w, `E_EMPTYPUBLIC`, http.StatusBadRequest)
 		}
 	} else {
 		logger.Warning("public key for wallet not found")
 		publicKey = []byte("null")
 	}
-	signature := data.params[`signature`].([]byte)
 	if len(signature) == 0 {
 		logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("signature is empty")
-		return errorAPI(w, `E_EMPTYSIGN`, http.StatusBadRequest)
+		return 0, nil, errorAPI(w, `E_EMPTYSIGN`, http.StatusBadRequest)
+	}
+	idata := contractData(form, info, logger)
+	toSerialize = tx.SmartContract{
+		Header: tx.Header{Type: int(info.ID), Time: converter.StrToInt64(txTime),
+			EcosystemID: data.ecosystemId, KeyID: data.keyId, PublicKey: publicKey,
+			BinSignatures: converter.EncodeLengthPlusData(signature)},
+		TokenEcosystem: data.params[`token_ecosystem`].(int64),
+		MaxSum:         data.params[`max_sum`].(string),
+		PayOver:        data.params[`payover`].(string),
+		Nonce:          txNonce(data),
+		Data:           idata,
+	}
+	serializedData, err := msgpack.Marshal(toSerialize)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling smart contract to msgpack")
+		return 0, nil, errorAPI(w, err, http.StatusInternalServerError)
 	}
+	return int64(info.ID), append([]byte{128}, serializedData...), nil
+}
+
+// txNonce returns the nonce of the transaction, the batches of the transactions haven't got the nonce
+func txNonce(data *apiData) int64 {
+	nonce, _ := data.params[`nonce`].(int64)
+	return nonce
+}
+
+// contractData returns the binary data of the contract fields which are taken from the form
+func contractData(form url.Values, info *script.ContractInfo, logger *log.Entry) []byte {
 	idata := make([]byte, 0)
 	if info.Tx != nil {
 	fields:
 		for _, fitem := range *info.Tx {
-			val := strings.TrimSpace(r.FormValue(fitem.Name))
+			val := strings.TrimSpace(form.Get(fitem.Name))
 			if strings.Contains(fitem.Tags, `address`) {
 				val = converter.Int64ToStr(converter.StringToAddress(val))
 			}
 			switch fitem.Type.String() {
 			case `[]interface {}`:
 				var list []string
-				for key, values := range r.Form {
+				for key, values := range form {
 					if key == fitem.Name+`[]` {
 						for _, value := range values {
 							list = append(list, value)
@@ -106,11 +149,10 @@ func contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log
 				converter.EncodeLenInt64(&idata, converter.StrToInt64(val))
 			case `float64`:
 				converter.BinMarshal(&idata, converter.StrToFloat64(val))
-			case `string`, script.Decimal:
+			case `string`, `map[string]interface {}`, script.Decimal:
 				idata = append(append(idata, converter.EncodeLength(int64(len(val)))...), []byte(val)...)
 			case `[]uint8`:
-				var bytes []byte
-				bytes, err = hex.DecodeString(val)
+				bytes, err := hex.DecodeString(val)
 				if err != nil {
 					logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err, "value": val}).Error("decoding value from hex")
 					break fields
@@ -119,24 +161,5 @@ func contract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log
 			}
 		}
 	}
-	toSerialize = tx.SmartContract{
-		Header: tx.Header{Type: int(info.ID), Time: converter.StrToInt64(data.params[`time`].(string)),
-			EcosystemID: data.ecosystemId, KeyID: data.keyId, PublicKey: publicKey,
-			BinSignatures: converter.EncodeLengthPlusData(signature)},
-		TokenEcosystem: data.params[`token_ecosystem`].(int64),
-		MaxSum:         data.params[`max_sum`].(string),
-		PayOver:        data.params[`payover`].(string),
-		Data:           idata,
-	}
-	serializedData, err := msgpack.Marshal(toSerialize)
-	if err != nil {
-		logger.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling smart contract to msgpack")
-		return errorAPI(w, err, http.StatusInternalServerError)
-	}
-	if hash, err = model.SendTx(int64(info.ID), data.keyId,
-		append([]byte{128}, serializedData...)); err != nil {
-		return errorAPI(w, err, http.StatusInternalServerError)
-	}
-	data.result = &contractResult{Hash: hex.EncodeToString(hash)} // !!! string(converter.BinToHex(hash))}
-	return nil
+	return idata
 }
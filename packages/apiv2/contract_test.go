@@ -200,6 +200,27 @@ var contracts = []smartContract{
 				map[string]string{`scond`: `-56781Simple name`,
 					`sact`: `Simple name-56781`}},
 		}},
+	{`testArrays`, `contract testArrays {
+				data {
+					list array
+					opts map
+				}
+				action {
+					var out string
+					var m map
+					m = {"x": 1, "y": [2, 3]}
+					for item in $list {
+						out = out + item + ";"
+					}
+					for key in $opts {
+						out = out + key + "=" + $opts[key] + ","
+					}
+					Test("arrays", out, Len($list), Len(m), Len(m["y"]))
+				}}`,
+		[]smartParams{
+			{map[string]string{`list[]`: `one`, `opts`: `{"b": "2", "a": "1"}`},
+				map[string]string{`arrays`: `one;a=1,b=2,1 2 2`}},
+		}},
 	{`errTestVar`, `contract errTestVar {
 			conditions {
 			}
@@ -18,15 +18,21 @@ package apiv2
 
 var (
 	errors = map[string]string{
+		`E_ACCESSDENIED`:  `Access to table %s is denied`,
+		`E_BATCHSIGN`:     `Number of signatures %d doesn't match number of contracts %d`,
+		`E_BATCHSIZE`:     `Number of contracts in batch must be from 1 to %d`,
+		`E_COLUMNACCESS`:  `Access to column %s is denied`,
 		`E_CONTRACT`:      `There is not %s contract`,
 		`E_DBNIL`:         `DB is nil`,
 		`E_ECOSYSTEM`:     `Ecosystem %d doesn't exist`,
 		`E_EMPTYPUBLIC`:   `Public key is undefined`,
 		`E_EMPTYSIGN`:     `Signature is undefined`,
+		`E_FILTER`:        `Filter is wrong: %s`,
 		`E_HASHWRONG`:     `Hash is incorrect`,
 		`E_HASHNOTFOUND`:  `Hash has not been found`,
 		`E_INSTALLED`:     `Apla is already installed`,
 		`E_INVALIDWALLET`: `Wallet %s is not valid`,
+		`E_NONCE`:         `Nonce is not activated`,
 		`E_NOTFOUND`:      `Page not found`,
 		`E_NOTINSTALLED`:  `Apla is not installed`,
 		`E_QUERY`:         `DB query is wrong`,
@@ -0,0 +1,56 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type finalityResult struct {
+	BlockID     string `json:"blockid"`
+	Hash        string `json:"hash"`
+	Time        string `json:"time"`
+	LastBlockID string `json:"lastblockid"`
+}
+
+// finality returns the last block which has been finalized by the votes of the full nodes.
+// The finalized block and all previous blocks can't be rolled back
+func finality(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	finalized := &model.FinalizedBlock{}
+	if _, err := finalized.GetLast(nil); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting finalized block")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	data.result = &finalityResult{
+		BlockID:     converter.Int64ToStr(finalized.BlockID),
+		Hash:        string(converter.BinToHex(finalized.Hash)),
+		Time:        converter.Int64ToStr(finalized.Time),
+		LastBlockID: converter.Int64ToStr(infoBlock.BlockID),
+	}
+	return nil
+}
@@ -17,13 +17,13 @@
 package apiv2
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -36,16 +36,29 @@ type listResult struct {
 func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
 	var limit int
 
-	table := converter.EscapeName(converter.Int64ToStr(data.ecosystemId) + `_` + data.params[`name`].(string))
-	cols := `*`
+	name := data.params[`name`].(string)
+	var columns []string
 	if len(data.params[`columns`].(string)) > 0 {
-		cols = `id,` + converter.EscapeName(data.params[`columns`].(string))
+		columns = append([]string{`id`}, strings.Split(data.params[`columns`].(string), `,`)...)
+	}
+	filter, err := model.ParseFilter(data.params[`where`].(string))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("Parsing filter")
+		return errorAPI(w, `E_FILTER`, http.StatusBadRequest, err.Error())
+	}
+	order := `id desc`
+	if len(data.params[`order`].(string)) > 0 {
+		order = data.params[`order`].(string)
+	}
+	query, err := model.NewReadQuery(data.ecosystemId, name, columns, filter, order, readCondition(data))
+	if err != nil {
+		return readQueryError(w, err, name, logger)
 	}
 
-	count, err := model.GetNextID(nil, strings.Trim(table, `"`))
+	count, err := model.Single(`select count(*)`+query.From(), query.Args...).Int64()
 	if err != nil {
-		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting next table id")
-		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, data.params[`name`].(string))
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": query.Table}).Error("Getting count of rows")
+		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
 	}
 
 	if data.params[`limit`].(int64) > 0 {
@@ -53,14 +66,38 @@ func list(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Ent
 	} else {
 		limit = 25
 	}
-	list, err := model.GetAll(`select `+cols+` from `+table+` order by id desc`+
-		fmt.Sprintf(` offset %d `, data.params[`offset`].(int64)), limit)
+	list, err := model.GetAllTransaction(nil, query.Select(data.params[`offset`].(int64), int64(limit)), limit,
+		query.Args...)
 	if err != nil {
-		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("Getting rows from table")
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": query.Table}).Error("Getting rows from table")
 		return errorAPI(w, err.Error(), http.StatusInternalServerError)
 	}
 	data.result = &listResult{
-		Count: converter.Int64ToStr(count - 1), List: list,
+		Count: converter.Int64ToStr(count), List: list,
 	}
 	return
 }
+
+// readCondition returns the function which evaluates the read conditions of the tables for the user
+func readCondition(data *apiData) func(string) (bool, error) {
+	return func(cond string) (bool, error) {
+		return smart.EvalIf(cond, uint32(data.ecosystemId), &map[string]interface{}{
+			`ecosystem_id`: data.ecosystemId, `key_id`: data.keyId})
+	}
+}
+
+// readQueryError writes the error of checking the query to the table
+func readQueryError(w http.ResponseWriter, err error, name string, logger *log.Entry) error {
+	if colErr, ok := err.(*model.ColumnAccessError); ok {
+		return errorAPI(w, `E_COLUMNACCESS`, http.StatusForbidden, colErr.Column)
+	}
+	switch err {
+	case model.ErrTableNotFound:
+		logger.WithFields(log.Fields{"type": consts.NotFound, "table": name}).Error("Table not found")
+		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, name)
+	case model.ErrReadAccess:
+		return errorAPI(w, `E_ACCESSDENIED`, http.StatusForbidden, name)
+	}
+	logger.WithFields(log.Fields{"type": consts.InvalidObject, "error": err}).Error("Building query")
+	return errorAPI(w, `E_FILTER`, http.StatusBadRequest, err.Error())
+}
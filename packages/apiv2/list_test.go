@@ -18,6 +18,8 @@ package apiv2
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/AplaProject/go-apla/packages/converter"
@@ -43,4 +45,35 @@ func TestList(t *testing.T) {
 		t.Error(err)
 		return
 	}
+
+	var filtered listResult
+	err = sendGet(`list/contracts?order=id&limit=3&where=`+url.QueryEscape(`{"id": {"$gt": 2, "$lte": 6}}`),
+		nil, &filtered)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if filtered.Count != `4` || len(filtered.List) != 3 || filtered.List[0][`id`] != `3` {
+		t.Error(fmt.Errorf(`wrong filtered list %v`, filtered))
+		return
+	}
+	err = sendGet(`list/contracts?where=`+url.QueryEscape(`{"id": {"$in": [1, 2]}, "value": {"$like": "%contract%"}}`),
+		nil, &filtered)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if filtered.Count != `2` {
+		t.Error(fmt.Errorf(`wrong count of IN list %s`, filtered.Count))
+		return
+	}
+	err = sendGet(`list/contracts?where=`+url.QueryEscape(`{"id; drop table": 1}`), nil, &filtered)
+	if err == nil || !strings.Contains(err.Error(), `E_FILTER`) {
+		t.Error(fmt.Errorf(`unknown column must be rejected %v`, err))
+		return
+	}
+	err = sendGet(`list/contracts?order=`+url.QueryEscape(`id; select`), nil, &filtered)
+	if err == nil || !strings.Contains(err.Error(), `E_FILTER`) {
+		t.Error(fmt.Errorf(`wrong order must be rejected %v`, err))
+	}
 }
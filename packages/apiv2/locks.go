@@ -0,0 +1,83 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/daemons"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type lockStats struct {
+	Daemon    string `json:"daemon"`
+	Count     string `json:"count"`
+	Timeouts  string `json:"timeouts"`
+	WaitTotal string `json:"wait_total"`
+	WaitMax   string `json:"wait_max"`
+	HoldTotal string `json:"hold_total"`
+	HoldMax   string `json:"hold_max"`
+}
+
+type lockWaiting struct {
+	Daemon string `json:"daemon"`
+	Wait   string `json:"wait"`
+}
+
+type locksResult struct {
+	Owner   string        `json:"owner"`
+	Hold    string        `json:"hold"`
+	Waiting []lockWaiting `json:"waiting"`
+	Stats   []lockStats   `json:"stats"`
+}
+
+func milliseconds(d time.Duration) string {
+	return converter.Int64ToStr(int64(d / time.Millisecond))
+}
+
+// locks returns the daemon which holds the lock of the blockchain, the waiting daemons and
+// the statistics of the lock. The durations are in milliseconds
+func locks(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	info := daemons.GetLockInfo()
+	result := locksResult{Owner: info.Owner, Hold: `0`, Waiting: make([]lockWaiting, 0),
+		Stats: make([]lockStats, 0, len(info.Stats))}
+	if len(info.Owner) > 0 {
+		result.Hold = milliseconds(time.Since(info.Since))
+	}
+	for name, since := range info.Waiting {
+		result.Waiting = append(result.Waiting, lockWaiting{Daemon: name, Wait: milliseconds(time.Since(since))})
+	}
+	for name, stats := range info.Stats {
+		result.Stats = append(result.Stats, lockStats{
+			Daemon:    name,
+			Count:     converter.Int64ToStr(stats.Count),
+			Timeouts:  converter.Int64ToStr(stats.Timeouts),
+			WaitTotal: milliseconds(stats.WaitTotal),
+			WaitMax:   milliseconds(stats.WaitMax),
+			HoldTotal: milliseconds(stats.HoldTotal),
+			HoldMax:   milliseconds(stats.HoldMax),
+		})
+	}
+	sort.Slice(result.Waiting, func(i, j int) bool { return result.Waiting[i].Daemon < result.Waiting[j].Daemon })
+	sort.Slice(result.Stats, func(i, j int) bool { return result.Stats[i].Daemon < result.Stats[j].Daemon })
+	data.result = &result
+	return nil
+}
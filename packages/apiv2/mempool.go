@@ -0,0 +1,94 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxMempoolLimit is the maximum number of the pending transactions in the response
+const maxMempoolLimit = 1000
+
+type pendingTx struct {
+	Hash    string `json:"hash"`
+	KeyID   string `json:"key_id"`
+	Nonce   string `json:"nonce"`
+	Time    string `json:"time"`
+	MaxSum  string `json:"max_sum"`
+	PayOver string `json:"payover"`
+	Size    string `json:"size"`
+	Sent    bool   `json:"sent"`
+}
+
+type mempoolResult struct {
+	Count    string      `json:"count"`
+	Size     string      `json:"size"`
+	MaxSize  string      `json:"max_size"`
+	KeyLimit string      `json:"key_limit"`
+	List     []pendingTx `json:"list"`
+}
+
+// getMempool returns the pending transactions of the node in the order of their fee.
+// The list can be filtered by key_id, the limit can't be greater than maxMempoolLimit
+func getMempool(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	limit := 25
+	if data.params[`limit`].(int64) > 0 {
+		limit = int(data.params[`limit`].(int64))
+	}
+	if limit > maxMempoolLimit {
+		limit = maxMempoolLimit
+	}
+	count, size, err := model.GetPendingSize(nil)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting size of pending transactions")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	trs, err := model.GetPendingTransactions(data.params[`key_id`].(int64), limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pending transactions")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := mempoolResult{
+		Count:    converter.Int64ToStr(count),
+		Size:     converter.Int64ToStr(size),
+		MaxSize:  converter.Int64ToStr(*utils.MempoolMaxSize),
+		KeyLimit: converter.Int64ToStr(*utils.MempoolKeyLimit),
+		List:     make([]pendingTx, 0, len(trs)),
+	}
+	for _, tx := range trs {
+		result.List = append(result.List, pendingTx{
+			Hash:    fmt.Sprintf(`%x`, tx.Hash),
+			KeyID:   converter.Int64ToStr(tx.KeyID),
+			Nonce:   converter.Int64ToStr(tx.Nonce),
+			Time:    converter.Int64ToStr(tx.Time),
+			MaxSum:  tx.MaxSum,
+			PayOver: tx.PayOver,
+			Size:    converter.Int64ToStr(tx.Size),
+			Sent:    tx.Sent == 1,
+		})
+	}
+	data.result = &result
+	return nil
+}
@@ -0,0 +1,271 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const openAPIVersion = `3.0.0`
+
+// contractRoutes are the prefixes of the routes which have the contract name as the last parameter
+var contractRoutes = []string{`prepare`, `contract`, `simulate`}
+
+type routeInfo struct {
+	method  string
+	pattern string
+	params  map[string]int
+	auth    bool
+}
+
+var routes []routeInfo
+
+type openAPIObject map[string]interface{}
+
+func addRouteInfo(method, pattern string, params map[string]int, handlers []apiHandle) {
+	info := routeInfo{method: method, pattern: pattern, params: params}
+	authPtr := reflect.ValueOf(authWallet).Pointer()
+	for _, handler := range handlers {
+		if reflect.ValueOf(handler).Pointer() == authPtr {
+			info.auth = true
+		}
+	}
+	routes = append(routes, info)
+}
+
+func paramSchema(vtype int) openAPIObject {
+	switch vtype & ^pOptional {
+	case pInt64:
+		return openAPIObject{`type`: `integer`, `format`: `int64`}
+	case pHex:
+		return openAPIObject{`type`: `string`, `format`: `hex`, `pattern`: `^[0-9a-fA-F]*$`}
+	}
+	return openAPIObject{`type`: `string`}
+}
+
+// fieldSchema returns the schema of the contract field
+func fieldSchema(field *script.FieldInfo) openAPIObject {
+	if strings.Contains(field.Tags, `address`) {
+		return openAPIObject{`type`: `string`, `format`: `address`}
+	}
+	switch field.Type.String() {
+	case `int64`:
+		return openAPIObject{`type`: `integer`, `format`: `int64`}
+	case `uint64`:
+		return openAPIObject{`type`: `integer`, `format`: `uint64`}
+	case `float64`:
+		return openAPIObject{`type`: `number`, `format`: `double`}
+	case script.Decimal:
+		return openAPIObject{`type`: `string`, `format`: `decimal`, `pattern`: `^\d+$`}
+	case `[]uint8`:
+		return openAPIObject{`type`: `string`, `format`: `hex`, `pattern`: `^[0-9a-fA-F]*$`}
+	case `[]interface {}`:
+		return openAPIObject{`type`: `array`, `items`: openAPIObject{`type`: `string`}}
+	case `map[string]interface {}`:
+		return openAPIObject{`type`: `string`, `format`: `json`}
+	}
+	return openAPIObject{`type`: `string`}
+}
+
+// formSchema returns the schema of the form with the route parameters and the contract fields
+func formSchema(params map[string]int, fields *[]*script.FieldInfo) openAPIObject {
+	properties := openAPIObject{}
+	required := make([]string, 0)
+	for name, vtype := range params {
+		properties[name] = paramSchema(vtype)
+		if vtype&pOptional == 0 {
+			required = append(required, name)
+		}
+	}
+	if fields != nil {
+		for _, field := range *fields {
+			name := field.Name
+			if field.Type.String() == `[]interface {}` {
+				name += `[]`
+			}
+			schema := fieldSchema(field)
+			if len(field.Tags) > 0 {
+				schema[`x-tags`] = field.Tags
+			}
+			properties[name] = schema
+			if !strings.Contains(field.Tags, `optional`) && !strings.Contains(field.Tags, `image`) &&
+				!strings.Contains(field.Tags, `crypt`) && !strings.Contains(field.Tags, `signature`) {
+				required = append(required, name)
+			}
+		}
+	}
+	sort.Strings(required)
+	schema := openAPIObject{`type`: `object`, `properties`: properties}
+	if len(required) > 0 {
+		schema[`required`] = required
+	}
+	return schema
+}
+
+var (
+	rePathParam   = regexp.MustCompile(`:([\w_]+)`)
+	reStatePrefix = regexp.MustCompile(`^@\d+`)
+)
+
+// operation returns the description of the route. If fields is not nil then
+// the last path parameter has been replaced with the contract name.
+func (route *routeInfo) operation(pathParams []string, fields *[]*script.FieldInfo) openAPIObject {
+	op := openAPIObject{
+		`responses`: openAPIObject{
+			`200`:     openAPIObject{`description`: `Successful response`},
+			`default`: openAPIObject{`$ref`: `#/components/responses/Error`},
+		},
+	}
+	params := make([]openAPIObject, 0)
+	for _, name := range pathParams {
+		params = append(params, openAPIObject{`name`: name, `in`: `path`, `required`: true,
+			`schema`: openAPIObject{`type`: `string`}})
+	}
+	if route.method == `GET` {
+		names := make([]string, 0, len(route.params))
+		for name := range route.params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			vtype := route.params[name]
+			params = append(params, openAPIObject{`name`: name, `in`: `query`,
+				`required`: vtype&pOptional == 0, `schema`: paramSchema(vtype)})
+		}
+	} else if len(route.params) > 0 || fields != nil {
+		op[`requestBody`] = openAPIObject{`content`: openAPIObject{
+			`application/x-www-form-urlencoded`: openAPIObject{`schema`: formSchema(route.params, fields)},
+		}}
+	}
+	if len(params) > 0 {
+		op[`parameters`] = params
+	}
+	if route.auth {
+		op[`security`] = []openAPIObject{{`bearerAuth`: []string{}}}
+	}
+	return op
+}
+
+func (route *routeInfo) isContract() bool {
+	if !strings.HasSuffix(route.pattern, `/:name`) || route.method != `POST` {
+		return false
+	}
+	for _, prefix := range contractRoutes {
+		if route.pattern == prefix+`/:name` {
+			return true
+		}
+	}
+	return false
+}
+
+func openAPIErrors() openAPIObject {
+	codes := make([]string, 0, len(errors))
+	for code := range errors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	messages := openAPIObject{}
+	for _, code := range codes {
+		messages[code] = errors[code]
+	}
+	return openAPIObject{
+		`type`: `object`,
+		`properties`: openAPIObject{
+			`error`:  openAPIObject{`type`: `string`, `enum`: codes},
+			`msg`:    openAPIObject{`type`: `string`},
+			`params`: openAPIObject{`type`: `array`, `items`: openAPIObject{`type`: `string`}},
+		},
+		`required`:   []string{`error`, `msg`},
+		`x-messages`: messages,
+	}
+}
+
+// openAPI returns OpenAPI 3 document which is generated from the routes and the contracts of the ecosystem
+func openAPI(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	ecosystem := data.params[`ecosystem`].(int64)
+	if ecosystem <= 0 {
+		ecosystem = 1
+	}
+	contracts := smart.GetContractsByState(uint32(ecosystem))
+	if len(contracts) == 0 {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "ecosystem": ecosystem}).Warning("contracts of ecosystem have not been found")
+	}
+	paths := openAPIObject{}
+	addPath := func(path, method string, op openAPIObject) {
+		item, ok := paths[path].(openAPIObject)
+		if !ok {
+			item = openAPIObject{}
+			paths[path] = item
+		}
+		item[strings.ToLower(method)] = op
+	}
+	for i := range routes {
+		route := &routes[i]
+		var pathParams []string
+		for _, item := range rePathParam.FindAllStringSubmatch(route.pattern, -1) {
+			pathParams = append(pathParams, item[1])
+		}
+		if route.isContract() {
+			prefix := strings.TrimSuffix(route.pattern, `:name`)
+			for _, contract := range contracts {
+				info := contract.Block.Info.(*script.ContractInfo)
+				name := reStatePrefix.ReplaceAllString(info.Name, ``)
+				fields := info.Tx
+				if fields == nil {
+					fields = &[]*script.FieldInfo{}
+				}
+				addPath(`/api/v2/`+prefix+name, route.method, route.operation(nil, fields))
+			}
+			continue
+		}
+		path := `/api/v2/` + rePathParam.ReplaceAllString(route.pattern, `{$1}`)
+		addPath(path, route.method, route.operation(pathParams, nil))
+	}
+	data.result = openAPIObject{
+		`openapi`: openAPIVersion,
+		`info`: openAPIObject{
+			`title`:   `Apla REST API`,
+			`version`: `2`,
+		},
+		`paths`: paths,
+		`components`: openAPIObject{
+			`securitySchemes`: openAPIObject{
+				`bearerAuth`: openAPIObject{`type`: `http`, `scheme`: `bearer`, `bearerFormat`: `JWT`},
+			},
+			`schemas`: openAPIObject{`Error`: openAPIErrors()},
+			`responses`: openAPIObject{
+				`Error`: openAPIObject{
+					`description`: `Error response`,
+					`content`: openAPIObject{
+						`application/json`: openAPIObject{`schema`: openAPIObject{`$ref`: `#/components/schemas/Error`}},
+					},
+				},
+			},
+		},
+	}
+	return nil
+}
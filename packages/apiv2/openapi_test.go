@@ -0,0 +1,46 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	var ret map[string]interface{}
+	if err := sendGet(`openapi.json`, nil, &ret); err != nil {
+		t.Error(err)
+		return
+	}
+	if ret[`openapi`] != openAPIVersion {
+		t.Error(fmt.Errorf(`wrong openapi version %v`, ret[`openapi`]))
+		return
+	}
+	paths := ret[`paths`].(map[string]interface{})
+	for _, path := range []string{`/api/v2/list/{name}`, `/api/v2/txstatus/{hash}`, `/api/v2/contract/MoneyTransfer`} {
+		if _, ok := paths[path]; !ok {
+			t.Error(fmt.Errorf(`path %s has not been found`, path))
+			return
+		}
+	}
+	body := paths[`/api/v2/prepare/MoneyTransfer`].(map[string]interface{})[`post`].(map[string]interface{})[`requestBody`]
+	schema := body.(map[string]interface{})[`content`].(map[string]interface{})[`application/x-www-form-urlencoded`].(map[string]interface{})[`schema`].(map[string]interface{})
+	if _, ok := schema[`properties`].(map[string]interface{})[`Recipient`]; !ok {
+		t.Error(`field Recipient of MoneyTransfer has not been found`)
+	}
+}
@@ -19,10 +19,14 @@ package apiv2
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/script"
 	"github.com/AplaProject/go-apla/packages/utils/tx"
 
@@ -37,28 +41,47 @@ type prepareResult struct {
 }
 
 func prepareContract(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	result, err := prepareSmartTx(w, data.params[`name`].(string), r.Form, data, time.Now().Unix(), logger)
+	if err != nil {
+		return err
+	}
+	data.result = result
+	return nil
+}
+
+// prepareSmartTx returns the string for signing the call of the contract with the specified form values
+func prepareSmartTx(w http.ResponseWriter, name string, form url.Values, data *apiData, timeNow int64,
+	logger *log.Entry) (*prepareResult, error) {
 	var (
 		result  prepareResult
-		timeNow int64
 		smartTx tx.SmartContract
 	)
 
-	timeNow = time.Now().Unix()
 	result.Time = converter.Int64ToStr(timeNow)
 	result.Values = make(map[string]string)
-	contract, parerr, err := validateSmartContract(r, data, &result)
+	contract, parerr, err := validateSmartContract(name, form, data, &result)
 	if err != nil {
 		if strings.HasPrefix(err.Error(), `E_`) {
-			return errorAPI(w, err.Error(), http.StatusBadRequest, parerr)
+			return nil, errorAPI(w, err.Error(), http.StatusBadRequest, parerr)
 		}
-		return errorAPI(w, err, http.StatusBadRequest)
+		return nil, errorAPI(w, err, http.StatusBadRequest)
 	}
 	info := (*contract).Block.Info.(*script.ContractInfo)
 	smartTx.TokenEcosystem = data.params[`token_ecosystem`].(int64)
 	smartTx.MaxSum = data.params[`max_sum`].(string)
 	smartTx.PayOver = data.params[`payover`].(string)
+	smartTx.Nonce = txNonce(data)
 	smartTx.Header = tx.Header{Type: int(info.ID), Time: timeNow, EcosystemID: data.ecosystemId, KeyID: data.keyId}
-	forsign := smartTx.ForSign()
+	infoBlock := &model.InfoBlock{}
+	if _, err = infoBlock.Get(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return nil, errorAPI(w, err, http.StatusInternalServerError)
+	}
+	nonceFork := syspar.IsNonceFork(infoBlock.BlockID + 1)
+	if smartTx.Nonce != 0 && !nonceFork {
+		return nil, errorAPI(w, `E_NONCE`, http.StatusBadRequest)
+	}
+	forsign := smartTx.ForSign(nonceFork)
 	if info.Tx != nil {
 		for _, fitem := range *info.Tx {
 			if strings.Contains(fitem.Tags, `image`) || strings.Contains(fitem.Tags, `signature`) {
@@ -66,7 +89,7 @@ func prepareContract(w http.ResponseWriter, r *http.Request, data *apiData, logg
 			}
 			var val string
 			if fitem.Type.String() == `[]interface {}` {
-				for key, values := range r.Form {
+				for key, values := range form {
 					if key == fitem.Name+`[]` {
 						var list []string
 						for _, value := range values {
@@ -76,7 +99,7 @@ func prepareContract(w http.ResponseWriter, r *http.Request, data *apiData, logg
 					}
 				}
 			} else {
-				val = strings.TrimSpace(r.FormValue(fitem.Name))
+				val = strings.TrimSpace(form.Get(fitem.Name))
 				if strings.Contains(fitem.Tags, `address`) {
 					val = converter.Int64ToStr(converter.StringToAddress(val))
 				} else if fitem.Type.String() == script.Decimal {
@@ -89,6 +112,5 @@ func prepareContract(w http.ResponseWriter, r *http.Request, data *apiData, logg
 		}
 	}
 	result.ForSign = forsign
-	data.result = result
-	return nil
+	return &result, nil
 }
@@ -25,7 +25,9 @@ import (
 )
 
 func methodRoute(route *hr.Router, method, pattern, pars string, handler ...apiHandle) {
-	route.Handle(method, `/api/v2/`+pattern, DefaultHandler(processParams(pars), handler...))
+	params := processParams(pars)
+	addRouteInfo(method, pattern, params, handler)
+	route.Handle(method, `/api/v2/`+pattern, DefaultHandler(params, handler...))
 }
 
 // Route sets routing pathes
@@ -55,27 +57,37 @@ func Route(route *hr.Router) {
 	get(`ecosystemparam/:name`, `?ecosystem:int64`, authWallet, ecosystemParam)
 	get(`ecosystemparams`, `?ecosystem:int64,?names:string`, authWallet, ecosystemParams)
 	get(`ecosystems`, ``, authWallet, ecosystems)
+	get(`finality`, ``, authWallet, finality)
 	get(`getuid`, ``, getUID)
-	get(`list/:name`, `?limit ?offset:int64,?columns:string`, authWallet, list)
+	get(`openapi.json`, `?ecosystem:int64`, openAPI)
+	get(`list/:name`, `?limit ?offset:int64,?columns ?where ?order:string`, authWallet, list)
+	get(`locks`, ``, authWallet, locks)
+	get(`mempool`, `?key_id ?limit:int64`, authWallet, getMempool)
 	get(`row/:name/:id`, `?columns:string`, authWallet, row)
+	get(`subscribe`, `?txs ?tables:string,?blocks:int64`, authWallet, subscribe)
 	get(`systemparams`, `?names:string`, authWallet, systemParams)
 	get(`table/:name`, ``, authWallet, table)
 	get(`tables`, `?limit ?offset:int64`, authWallet, tables)
 	get(`txstatus/:hash`, ``, authWallet, txstatus)
+	get(`validators`, ``, authWallet, validators)
 	//	get(`smartcontract/:name`, ``, authState, getSmartContract)
 	get(`test/:name`, ``, getTest)
 
-	post(`content/page/:name`, ``, authWallet, getPage)
-	post(`content/menu/:name`, ``, authWallet, getMenu)
+	post(`content/page/:name`, `?profile:int64`, authWallet, getPage)
+	post(`content/menu/:name`, `?profile:int64`, authWallet, getMenu)
 	post(`install`, `?first_load_blockchain_url ?first_block_dir log_level type db_host db_port 
 	db_name db_pass db_user:string,?generate_first_block:int64`, install)
 	post(`login`, `?pubkey signature:hex,?key_id:string,?ecosystem ?expire:int64`, login)
-	postTx(`:name`, `?token_ecosystem:int64,?max_sum ?payover:string`, prepareContract, contract)
+	postTx(`:name`, `?token_ecosystem ?nonce:int64,?max_sum ?payover:string`, prepareContract, contract)
 	post(`refresh`, `token:string,?expire:int64`, refresh)
 	//	postTx(`smartcontract/:name`, ``, txPreSmartContract, txSmartContract)
 	post(`signtest/`, `forsign private:string`, signTest)
 	post(`test/:name`, ``, getTest)
 	post(`content`, `template:string`, jsonContent)
+	post(`preparebatch`, `data:string,?token_ecosystem:int64,?max_sum ?payover:string`, authWallet, prepareBatch)
+	post(`contractbatch`, `?pubkey:hex,data signatures time:string,?token_ecosystem:int64,?max_sum ?payover:string`,
+		authWallet, contractBatch)
+	post(`simulate/:name`, `?token_ecosystem ?trace:int64,?max_sum ?payover ?breakpoints ?actions:string`, authWallet, simulate)
 
 }
 
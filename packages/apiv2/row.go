@@ -18,6 +18,7 @@ package apiv2
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
@@ -31,12 +32,17 @@ type rowResult struct {
 }
 
 func row(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
-	cols := `*`
+	var columns []string
 	if len(data.params[`columns`].(string)) > 0 {
-		cols = converter.EscapeName(data.params[`columns`].(string))
+		columns = strings.Split(data.params[`columns`].(string), `,`)
 	}
-	row, err := model.GetOneRow(`SELECT `+cols+` FROM "`+converter.Int64ToStr(data.ecosystemId)+`_`+
-		data.params[`name`].(string)+`" WHERE id = ?`, data.params[`id`].(string)).String()
+	name := data.params[`name`].(string)
+	filter := model.Filter{`id`: converter.StrToInt64(data.params[`id`].(string))}
+	query, err := model.NewReadQuery(data.ecosystemId, name, columns, filter, ``, readCondition(data))
+	if err != nil {
+		return readQueryError(w, err, name, logger)
+	}
+	row, err := model.GetOneRow(query.Select(0, 0), query.Args...).String()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": data.params["name"].(string), "id": data.params["id"].(string)}).Error("getting one row")
 		return errorAPI(w, `E_QUERY`, http.StatusInternalServerError)
@@ -0,0 +1,196 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/parser"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/utils/tx"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/vmihailenco/msgpack.v2"
+)
+
+type simulateChange struct {
+	Action string            `json:"action"`
+	Table  string            `json:"table"`
+	ID     string            `json:"id"`
+	Values map[string]string `json:"values"`
+}
+
+type simulateResult struct {
+	Fuel    string              `json:"fuel"`
+	Price   string              `json:"price"`
+	Result  string              `json:"result"`
+	Error   string              `json:"error,omitempty"`
+	Warning string              `json:"warning,omitempty"`
+	Info    string              `json:"info,omitempty"`
+	Changes []simulateChange    `json:"changes"`
+	Trace   []*script.TraceItem `json:"trace,omitempty"`
+	Pauses  []*script.TraceItem `json:"pauses,omitempty"`
+}
+
+// simulateTraceLimit is the maximum count of the traced commands and the pauses
+const simulateTraceLimit = 10000
+
+var debugActions = map[string]script.DebugAction{
+	`continue`: script.DebugContinue,
+	`step`:     script.DebugStep,
+	`stop`:     script.DebugStop,
+}
+
+// parseBreakpoints parses the list of the breakpoints like name:line or line separated by commas
+func parseBreakpoints(input string) ([]script.Breakpoint, error) {
+	var list []script.Breakpoint
+	for _, item := range strings.Split(input, `,`) {
+		if item = strings.TrimSpace(item); len(item) == 0 {
+			continue
+		}
+		var breakpoint script.Breakpoint
+		if off := strings.LastIndexByte(item, ':'); off >= 0 {
+			breakpoint.Name, item = item[:off], item[off+1:]
+		}
+		line, err := strconv.ParseUint(item, 10, 32)
+		if err != nil || line == 0 {
+			return nil, fmt.Errorf(`wrong breakpoint %s`, item)
+		}
+		breakpoint.Line = uint32(line)
+		list = append(list, breakpoint)
+	}
+	return list, nil
+}
+
+// parseDebugActions parses the list of continue, step and stop actions separated by commas
+func parseDebugActions(input string) ([]script.DebugAction, error) {
+	var list []script.DebugAction
+	for _, item := range strings.Split(input, `,`) {
+		if item = strings.TrimSpace(item); len(item) == 0 {
+			continue
+		}
+		action, ok := debugActions[item]
+		if !ok {
+			return nil, fmt.Errorf(`wrong debug action %s`, item)
+		}
+		list = append(list, action)
+	}
+	return list, nil
+}
+
+// newSimulateDebugger returns the debugger of the simulation. The execution is paused on the breakpoints,
+// the actions are applied to the pauses one by one and the execution is continued when they are over.
+// The execution is paused on the first line if there are the actions without breakpoints.
+// The state of every pause is saved in pauses
+func newSimulateDebugger(trace bool, breakpoints []script.Breakpoint, actions []script.DebugAction,
+	pauses *[]*script.TraceItem) *script.Debugger {
+
+	debugger := &script.Debugger{Trace: trace, MaxItems: simulateTraceLimit, Breakpoints: breakpoints}
+	if len(breakpoints) == 0 && len(actions) == 0 {
+		return debugger
+	}
+	debugger.Step = len(breakpoints) == 0
+	debugger.OnPause = func(item *script.TraceItem) script.DebugAction {
+		if len(*pauses) < simulateTraceLimit {
+			*pauses = append(*pauses, item)
+		}
+		if len(actions) == 0 {
+			return script.DebugContinue
+		}
+		action := actions[0]
+		actions = actions[1:]
+		return action
+	}
+	return debugger
+}
+
+// simulate runs the contract against the current state without signing and sending the transaction.
+// All changes of the database are rolled back.
+// If trace is 1 then the executed commands are returned. The execution is paused on the breakpoints
+// and the continue, step and stop actions are applied to the pauses, the states of the pauses are returned.
+func simulate(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	contract, parerr, err := validateSmartContract(data.params[`name`].(string), r.Form, data, nil)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), `E_`) {
+			return errorAPI(w, err.Error(), http.StatusBadRequest, parerr)
+		}
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	info := (*contract).Block.Info.(*script.ContractInfo)
+	smartTx := tx.SmartContract{
+		Header: tx.Header{Type: int(info.ID), Time: time.Now().Unix(),
+			EcosystemID: data.ecosystemId, KeyID: data.keyId},
+		TokenEcosystem: data.params[`token_ecosystem`].(int64),
+		MaxSum:         data.params[`max_sum`].(string),
+		PayOver:        data.params[`payover`].(string),
+		Data:           contractData(r.Form, info, logger),
+	}
+	serializedData, err := msgpack.Marshal(smartTx)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.MarshallingError, "error": err}).Error("marshalling smart contract to msgpack")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	p, err := parser.ParseTransaction(bytes.NewBuffer(append([]byte{128}, serializedData...)))
+	if err != nil {
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	breakpoints, err := parseBreakpoints(data.params[`breakpoints`].(string))
+	if err != nil {
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	actions, err := parseDebugActions(data.params[`actions`].(string))
+	if err != nil {
+		return errorAPI(w, err, http.StatusBadRequest)
+	}
+	var (
+		debugger *script.Debugger
+		pauses   []*script.TraceItem
+	)
+	trace := data.params[`trace`].(int64) == 1
+	if trace || len(breakpoints) > 0 || len(actions) > 0 {
+		debugger = newSimulateDebugger(trace, breakpoints, actions, &pauses)
+	}
+	ret, err := p.Simulate(debugger)
+	if err != nil {
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	result := simulateResult{
+		Fuel:    ret.Fuel.String(),
+		Price:   ret.Price.String(),
+		Result:  ret.Result,
+		Error:   ret.Error,
+		Warning: ret.Warning,
+		Info:    ret.Info,
+		Changes: make([]simulateChange, 0, len(ret.Changes)),
+	}
+	if debugger != nil {
+		result.Trace = debugger.Items
+		result.Pauses = pauses
+	}
+	for _, item := range ret.Changes {
+		result.Changes = append(result.Changes, simulateChange{Action: item.Action, Table: item.Table,
+			ID: item.ID, Values: item.Values})
+	}
+	data.result = &result
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/script"
+)
+
+func TestSimulate(t *testing.T) {
+	if err := keyLogin(1); err != nil {
+		t.Error(err)
+		return
+	}
+	var before, after balanceResult
+	if err := sendGet(`balance/`+gAddress, nil, &before); err != nil {
+		t.Error(err)
+		return
+	}
+	var ret simulateResult
+	form := url.Values{`Amount`: {`1000`}, `Recipient`: {`0005-2070-2000-0006-0200`}}
+	if err := sendPost(`simulate/MoneyTransfer`, &form, &ret); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ret.Error) > 0 || ret.Fuel == `0` || len(ret.Changes) == 0 {
+		t.Error(fmt.Errorf(`wrong simulation result %v`, ret))
+		return
+	}
+	if err := sendGet(`balance/`+gAddress, nil, &after); err != nil {
+		t.Error(err)
+		return
+	}
+	if before.Amount != after.Amount {
+		t.Error(fmt.Errorf(`balance has been changed %s != %s`, before.Amount, after.Amount))
+		return
+	}
+	form = url.Values{`Amount`: {`0`}, `Recipient`: {`0005-2070-2000-0006-0200`}}
+	if err := sendPost(`simulate/MoneyTransfer`, &form, &ret); err != nil {
+		t.Error(err)
+		return
+	}
+	if ret.Error != `Amount is zero` {
+		t.Error(fmt.Errorf(`zero amount must be rejected %v`, ret))
+		return
+	}
+	var traced simulateResult
+	form = url.Values{`Amount`: {`0`}, `Recipient`: {`0005-2070-2000-0006-0200`}, `trace`: {`1`}}
+	if err := sendPost(`simulate/MoneyTransfer`, &form, &traced); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(traced.Trace) == 0 {
+		t.Error(fmt.Errorf(`trace is empty`))
+		return
+	}
+	last := traced.Trace[len(traced.Trace)-1]
+	if last.Cmd != `error` || last.Line == 0 || !strings.HasSuffix(last.Name, `MoneyTransfer`) {
+		t.Error(fmt.Errorf(`wrong last traced command %v`, *last))
+	}
+	var debugged simulateResult
+	form = url.Values{`Amount`: {`0`}, `Recipient`: {`0005-2070-2000-0006-0200`}, `actions`: {`step,step,stop`}}
+	if err := sendPost(`simulate/MoneyTransfer`, &form, &debugged); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(debugged.Pauses) != 3 || debugged.Error != `execution has been stopped by debugger` {
+		t.Error(fmt.Errorf(`wrong debugged result %d %s`, len(debugged.Pauses), debugged.Error))
+	}
+}
+
+func TestSimulateDebugger(t *testing.T) {
+	breakpoints, err := parseBreakpoints(`12, @1MoneyTransfer:20,`)
+	if err != nil || len(breakpoints) != 2 || breakpoints[0].Line != 12 || len(breakpoints[0].Name) != 0 ||
+		breakpoints[1].Name != `@1MoneyTransfer` || breakpoints[1].Line != 20 {
+		t.Errorf(`wrong breakpoints %v %v`, breakpoints, err)
+	}
+	if _, err = parseBreakpoints(`MoneyTransfer:x`); err == nil {
+		t.Error(`wrong breakpoint must be rejected`)
+	}
+	actions, err := parseDebugActions(`step,continue`)
+	if err != nil || len(actions) != 2 || actions[0] != script.DebugStep || actions[1] != script.DebugContinue {
+		t.Errorf(`wrong actions %v %v`, actions, err)
+	}
+	if _, err = parseDebugActions(`jump`); err == nil {
+		t.Error(`wrong action must be rejected`)
+	}
+	var pauses []*script.TraceItem
+	debugger := newSimulateDebugger(false, nil, actions, &pauses)
+	if !debugger.Step {
+		t.Error(`debugger must pause on the first line`)
+	}
+	for i, action := range []script.DebugAction{script.DebugStep, script.DebugContinue, script.DebugContinue} {
+		if ret := debugger.OnPause(&script.TraceItem{}); ret != action {
+			t.Errorf(`wrong action %d of pause %d`, ret, i)
+		}
+	}
+	if len(pauses) != 3 {
+		t.Errorf(`wrong count of pauses %d`, len(pauses))
+	}
+}
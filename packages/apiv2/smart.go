@@ -19,7 +19,7 @@ package apiv2
 import (
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 
@@ -55,8 +55,7 @@ type EncryptKey struct {
 	Error     string `json:"error"`
 }
 
-func validateSmartContract(r *http.Request, data *apiData, result *prepareResult) (contract *smart.Contract, parerr interface{}, err error) {
-	cntname := data.params[`name`].(string)
+func validateSmartContract(cntname string, form url.Values, data *apiData, result *prepareResult) (contract *smart.Contract, parerr interface{}, err error) {
 	contract = smart.GetContract(cntname, int32(data.ecosystemId))
 	if contract == nil {
 		return nil, cntname, fmt.Errorf(`E_CONTRACT`)
@@ -90,7 +89,7 @@ func validateSmartContract(r *http.Request, data *apiData, result *prepareResult
 					}
 					sign.ForSign = fmt.Sprintf(`%s,%d`, (*result).Time, uint64(data.keyId))
 					for _, isign := range sign.Params {
-						sign.ForSign += fmt.Sprintf(`,%v`, strings.TrimSpace(r.FormValue(isign.Param)))
+						sign.ForSign += fmt.Sprintf(`,%v`, strings.TrimSpace(form.Get(isign.Param)))
 					}
 					sign.Field = fitem.Name
 					(*result).Signs = append((*result).Signs, sign)
@@ -98,7 +97,7 @@ func validateSmartContract(r *http.Request, data *apiData, result *prepareResult
 			} else {
 				var val string
 
-				val = strings.TrimSpace(r.FormValue(fitem.Name))
+				val = strings.TrimSpace(form.Get(fitem.Name))
 				if len(val) == 0 && !strings.Contains(fitem.Tags, `optional`) {
 					log.WithFields(log.Fields{"type": consts.EmptyObject, "item_name": fitem.Name}).Error("route item is empty")
 					err = fmt.Errorf(`%s is empty`, fitem.Name)
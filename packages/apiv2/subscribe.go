@@ -0,0 +1,379 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/parser"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	eventBlock    = `block`
+	eventTxStatus = `txstatus`
+	eventTable    = `table`
+
+	subscribeQueueSize = 256
+	subscribePing      = 30 * time.Second
+	subscribeWriteWait = 10 * time.Second
+)
+
+// errStreamClosed is returned by streaming handlers so that DefaultHandler doesn't write the result
+var errStreamClosed = fmt.Errorf(`stream has been closed`)
+
+type subscribeRequest struct {
+	Txs    []string `json:"txs"`
+	Blocks bool     `json:"blocks"`
+	Tables []string `json:"tables"`
+}
+
+type subscribeEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data"`
+}
+
+type blockEventData struct {
+	ID   string `json:"id"`
+	Hash string `json:"hash"`
+	Time string `json:"time"`
+	Tx   string `json:"tx"`
+}
+
+type txstatusEventData struct {
+	Hash string `json:"hash"`
+	txstatusResult
+}
+
+type tableEventData struct {
+	Table string `json:"table"`
+	ID    string `json:"id"`
+	Hash  string `json:"hash"`
+}
+
+type subscriber struct {
+	mutex     sync.Mutex
+	ecosystem int64
+	txs       map[string]bool
+	blocks    bool
+	tables    map[string]bool
+	events    chan *subscribeEvent
+}
+
+var subscribers = struct {
+	sync.Mutex
+	list map[*subscriber]struct{}
+}{list: make(map[*subscriber]struct{})}
+
+func init() {
+	parser.AddBlockHandler(publishBlock)
+}
+
+func newSubscriber(ecosystem int64) *subscriber {
+	return &subscriber{
+		ecosystem: ecosystem,
+		txs:       make(map[string]bool),
+		tables:    make(map[string]bool),
+		events:    make(chan *subscribeEvent, subscribeQueueSize),
+	}
+}
+
+// update adds the hashes and the tables of the request to the subscription, the added hashes are returned
+func (s *subscriber) update(req *subscribeRequest) ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var hashes []string
+	for _, hash := range req.Txs {
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if len(hash) == 0 {
+			continue
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf(`E_HASHWRONG`)
+		}
+		if !s.txs[hash] {
+			s.txs[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+	for _, table := range req.Tables {
+		table = strings.TrimSpace(table)
+		if len(table) == 0 {
+			continue
+		}
+		s.tables[fmt.Sprintf(`%d_%s`, s.ecosystem, table)] = true
+	}
+	if req.Blocks {
+		s.blocks = true
+	}
+	return hashes, nil
+}
+
+// statusEvent returns the event of the current status of the transaction if the subscriber is still waiting for it.
+// The transaction is removed from the subscription if it has been processed
+func (s *subscriber) statusEvent(hash string, status *txstatusResult) *subscribeEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.txs[hash] {
+		// the status has been already sent by publishBlock
+		return nil
+	}
+	if len(status.BlockID) > 0 || len(status.Message) > 0 {
+		delete(s.txs, hash)
+	}
+	return &subscribeEvent{Event: eventTxStatus, Data: &txstatusEventData{Hash: hash, txstatusResult: *status}}
+}
+
+// filter returns the events of the block which the subscriber is waiting for
+func (s *subscriber) filter(notice *parser.BlockNotice) (events []*subscribeEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.blocks {
+		events = append(events, &subscribeEvent{Event: eventBlock, Data: &blockEventData{
+			ID:   converter.Int64ToStr(notice.BlockID),
+			Hash: hex.EncodeToString(notice.Hash),
+			Time: converter.Int64ToStr(notice.Time),
+			Tx:   converter.IntToStr(len(notice.Txs)),
+		}})
+	}
+	for _, tx := range notice.Txs {
+		hash := hex.EncodeToString(tx.Hash)
+		if !s.txs[hash] {
+			continue
+		}
+		data := &txstatusEventData{Hash: hash}
+		if len(tx.Error) > 0 {
+			data.Message = tx.Error
+		} else {
+			data.BlockID = converter.Int64ToStr(notice.BlockID)
+			data.Result = tx.Result
+		}
+		events = append(events, &subscribeEvent{Event: eventTxStatus, Data: data})
+		// the status of the transaction is final so we don't need to wait for it any more
+		delete(s.txs, hash)
+	}
+	for _, item := range notice.Tables {
+		if !s.tables[item.Table] {
+			continue
+		}
+		events = append(events, &subscribeEvent{Event: eventTable, Data: &tableEventData{
+			Table: item.Table[strings.IndexByte(item.Table, '_')+1:],
+			ID:    item.ID,
+			Hash:  hex.EncodeToString(item.TxHash),
+		}})
+	}
+	return
+}
+
+func addSubscriber(s *subscriber) {
+	subscribers.Lock()
+	subscribers.list[s] = struct{}{}
+	subscribers.Unlock()
+}
+
+func removeSubscriber(s *subscriber) {
+	subscribers.Lock()
+	if _, ok := subscribers.list[s]; ok {
+		delete(subscribers.list, s)
+		close(s.events)
+	}
+	subscribers.Unlock()
+}
+
+// sendEvents puts the events to the queue of the subscriber, subscribers must be locked.
+// The subscriber is dropped if it doesn't read its events in time.
+func sendEvents(s *subscriber, events []*subscribeEvent) {
+	for _, event := range events {
+		select {
+		case s.events <- event:
+		default:
+			log.WithFields(log.Fields{"type": consts.ParameterExceeded}).Warning("subscriber queue is full")
+			delete(subscribers.list, s)
+			close(s.events)
+			return
+		}
+	}
+}
+
+// publishBlock sends the events of the applied block to the subscribers
+func publishBlock(notice *parser.BlockNotice) {
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	for s := range subscribers.list {
+		sendEvents(s, s.filter(notice))
+	}
+}
+
+// publishStatus sends the current statuses of the transactions to the subscriber which has been added.
+// The unknown transactions are skipped, their statuses are sent when they get into the block
+func publishStatus(s *subscriber, hashes []string, logger *log.Entry) {
+	var events []*subscribeEvent
+	for _, hash := range hashes {
+		bin, err := hex.DecodeString(hash)
+		if err != nil {
+			continue
+		}
+		status, found, err := getTxStatus(bin, logger)
+		if err != nil || !found {
+			continue
+		}
+		if event := s.statusEvent(hash, status); event != nil {
+			events = append(events, event)
+		}
+	}
+	if len(events) == 0 {
+		return
+	}
+	subscribers.Lock()
+	defer subscribers.Unlock()
+	if _, ok := subscribers.list[s]; ok {
+		sendEvents(s, events)
+	}
+}
+
+func isStreamRequest(r *http.Request) bool {
+	return websocket.IsWebSocketUpgrade(r) || strings.Contains(r.Header.Get(`Accept`), `text/event-stream`)
+}
+
+func subscribe(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	s := newSubscriber(data.ecosystemId)
+	req := subscribeRequest{Blocks: data.params[`blocks`].(int64) != 0}
+	if txs := data.params[`txs`].(string); len(txs) > 0 {
+		req.Txs = strings.Split(txs, `,`)
+	}
+	if tables := data.params[`tables`].(string); len(tables) > 0 {
+		req.Tables = strings.Split(tables, `,`)
+	}
+	hashes, err := s.update(&req)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err}).Error("decoding tx hash from hex")
+		return errorAPI(w, err.Error(), http.StatusBadRequest)
+	}
+	if websocket.IsWebSocketUpgrade(r) {
+		return subscribeWebSocket(w, r, s, hashes, logger)
+	}
+	return subscribeEventStream(w, r, s, hashes, logger)
+}
+
+func subscribeWebSocket(w http.ResponseWriter, r *http.Request, s *subscriber, hashes []string, logger *log.Entry) error {
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err}).Error("upgrading connection to websocket")
+		return errStreamClosed
+	}
+	defer conn.Close()
+
+	addSubscriber(s)
+	defer removeSubscriber(s)
+	publishStatus(s, hashes, logger)
+
+	// The client can extend its subscription by sending subscribeRequest messages
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var req subscribeRequest
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			hashes, err := s.update(&req)
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err}).Error("decoding tx hash from hex")
+				continue
+			}
+			publishStatus(s, hashes, logger)
+		}
+	}()
+
+	ticker := time.NewTicker(subscribePing)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater,
+					`subscriber queue is full`), time.Now().Add(subscribeWriteWait))
+				return errStreamClosed
+			}
+			conn.SetWriteDeadline(time.Now().Add(subscribeWriteWait))
+			if err := conn.WriteJSON(event); err != nil {
+				logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err}).Error("writing event to websocket")
+				return errStreamClosed
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(subscribeWriteWait)); err != nil {
+				return errStreamClosed
+			}
+		case <-done:
+			return errStreamClosed
+		}
+	}
+}
+
+func subscribeEventStream(w http.ResponseWriter, r *http.Request, s *subscriber, hashes []string, logger *log.Entry) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError}).Error("streaming is not supported")
+		return errorAPI(w, `E_SERVER`, http.StatusInternalServerError)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	addSubscriber(s)
+	defer removeSubscriber(s)
+	publishStatus(s, hashes, logger)
+
+	ticker := time.NewTicker(subscribePing)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-s.events:
+			if !ok {
+				return errStreamClosed
+			}
+			out, err := json.Marshal(event.Data)
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling event to json")
+				return errStreamClosed
+			}
+			if _, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Event, out); err != nil {
+				return errStreamClosed
+			}
+			flusher.Flush()
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return errStreamClosed
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return errStreamClosed
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribe(t *testing.T) {
+	if err := keyLogin(1); err != nil {
+		t.Error(err)
+		return
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(`ws://localhost:7079/api/v2/subscribe?blocks=1&tables=keys&token=`+gAuth, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+
+	form := url.Values{`Amount`: {`1000`}, `Recipient`: {`0005-2070-2000-0006-0200`}}
+	ret := make(map[string]interface{})
+	if err = sendPost(`prepare/MoneyTransfer`, &form, &ret); err != nil {
+		t.Error(err)
+		return
+	}
+	if err = appendSign(ret, &form); err != nil {
+		t.Error(err)
+		return
+	}
+	ret = map[string]interface{}{}
+	if err = sendPost(`contract/MoneyTransfer`, &form, &ret); err != nil {
+		t.Error(err)
+		return
+	}
+	hash := ret[`hash`].(string)
+	if err = conn.WriteJSON(&subscribeRequest{Txs: []string{hash}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	received := make(map[string]bool)
+	conn.SetReadDeadline(time.Now().Add(20 * time.Second))
+	for !received[eventBlock] || !received[eventTxStatus] || !received[eventTable] {
+		var event struct {
+			Event string          `json:"event"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err = conn.ReadJSON(&event); err != nil {
+			t.Error(err)
+			return
+		}
+		if event.Event == eventTxStatus {
+			var status txstatusEventData
+			if err = json.Unmarshal(event.Data, &status); err != nil {
+				t.Error(err)
+				return
+			}
+			if status.Hash != hash || len(status.Message) > 0 {
+				t.Error(fmt.Errorf(`wrong txstatus event %s`, event.Data))
+				return
+			}
+			if len(status.BlockID) == 0 {
+				// the current status of the pending transaction
+				continue
+			}
+		}
+		received[event.Event] = true
+	}
+
+	// the status of the processed transaction is sent at once
+	processed, _, err := websocket.DefaultDialer.Dial(`ws://localhost:7079/api/v2/subscribe?txs=`+hash+`&token=`+gAuth, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer processed.Close()
+	processed.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event subscribeEvent
+	if err = processed.ReadJSON(&event); err != nil || event.Event != eventTxStatus {
+		t.Error(fmt.Errorf(`wrong status of processed transaction %v %v`, event, err))
+		return
+	}
+
+	_, _, err = websocket.DefaultDialer.Dial(`ws://localhost:7079/api/v2/subscribe?txs=qwerty&token=`+gAuth, nil)
+	if err == nil {
+		t.Error(`wrong hash must be rejected`)
+	}
+}
+
+func TestSubscriberStatusEvent(t *testing.T) {
+	s := newSubscriber(1)
+	hashes, err := s.update(&subscribeRequest{Txs: []string{`AB01`, `cd02`, `ab01`}})
+	if err != nil || len(hashes) != 2 || hashes[0] != `ab01` || hashes[1] != `cd02` {
+		t.Errorf(`wrong hashes %v %v`, hashes, err)
+		return
+	}
+	if event := s.statusEvent(`ab01`, &txstatusResult{}); event == nil || !s.txs[`ab01`] {
+		t.Error(`pending transaction must be kept in subscription`)
+	}
+	if event := s.statusEvent(`cd02`, &txstatusResult{BlockID: `10`}); event == nil || s.txs[`cd02`] {
+		t.Error(`processed transaction must be removed from subscription`)
+	}
+	if event := s.statusEvent(`cd02`, &txstatusResult{BlockID: `10`}); event != nil {
+		t.Error(`status of processed transaction must be sent once`)
+	}
+}
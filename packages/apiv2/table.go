@@ -34,12 +34,14 @@ type columnInfo struct {
 }
 
 type tableResult struct {
-	Name       string       `json:"name"`
-	Insert     string       `json:"insert"`
-	NewColumn  string       `json:"new_column"`
-	Update     string       `json:"update"`
-	Conditions string       `json:"conditions"`
-	Columns    []columnInfo `json:"columns"`
+	Name        string            `json:"name"`
+	Insert      string            `json:"insert"`
+	NewColumn   string            `json:"new_column"`
+	Update      string            `json:"update"`
+	Read        string            `json:"read,omitempty"`
+	ReadColumns map[string]string `json:"read_columns,omitempty"`
+	Conditions  string            `json:"conditions"`
+	Columns     []columnInfo      `json:"columns"`
 }
 
 func table(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) (err error) {
@@ -54,7 +56,13 @@ func table(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.En
 	}
 
 	if len(table.Name) > 0 {
-		var perm map[string]string
+		var perm struct {
+			Insert      string            `json:"insert"`
+			NewColumn   string            `json:"new_column"`
+			Update      string            `json:"update"`
+			Read        string            `json:"read"`
+			ReadColumns map[string]string `json:"read_columns"`
+		}
 		err := json.Unmarshal([]byte(table.Permissions), &perm)
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("Unmarshalling table permissions to json")
@@ -77,12 +85,14 @@ func table(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.En
 				Type: colType})
 		}
 		result = tableResult{
-			Name:       table.Name,
-			Insert:     perm[`insert`],
-			NewColumn:  perm[`new_column`],
-			Update:     perm[`update`],
-			Conditions: table.Conditions,
-			Columns:    columns,
+			Name:        table.Name,
+			Insert:      perm.Insert,
+			NewColumn:   perm.NewColumn,
+			Update:      perm.Update,
+			Read:        perm.Read,
+			ReadColumns: perm.ReadColumns,
+			Conditions:  table.Conditions,
+			Columns:     columns,
 		}
 	} else {
 		return errorAPI(w, `E_TABLENOTFOUND`, http.StatusBadRequest, data.params[`name`].(string))
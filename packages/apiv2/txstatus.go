@@ -31,31 +31,50 @@ type txstatusResult struct {
 	BlockID string `json:"blockid"`
 	Message string `json:"errmsg"`
 	Result  string `json:"result"`
+	Final   bool   `json:"final"`
 }
 
-func txstatus(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
-	var status txstatusResult
-
-	if _, err := hex.DecodeString(data.params[`hash`].(string)); err != nil {
-		logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err}).Error("decoding tx hash from hex")
-		return errorAPI(w, `E_HASHWRONG`, http.StatusBadRequest)
-	}
+// getTxStatus returns the status of the transaction, found is false if there is no such transaction
+func getTxStatus(hash []byte, logger *log.Entry) (status *txstatusResult, found bool, err error) {
 	ts := &model.TransactionStatus{}
-	found, err := ts.Get([]byte(converter.HexToBin(data.params["hash"].(string))))
+	found, err = ts.Get(hash)
 	if err != nil {
-		logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err}).Error("getting transaction status by hash")
-		return errorAPI(w, err, http.StatusInternalServerError)
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting transaction status by hash")
+		return nil, false, err
 	}
 	if !found {
-		logger.WithFields(log.Fields{"type": consts.NotFound, "key": []byte(converter.HexToBin(data.params["hash"].(string)))}).Error("getting transaction status by hash")
-		return errorAPI(w, `E_HASHNOTFOUND`, http.StatusBadRequest)
+		return nil, false, nil
 	}
+	status = &txstatusResult{}
 	if ts.BlockID > 0 {
 		status.BlockID = converter.Int64ToStr(ts.BlockID)
 		status.Result = ts.Error
+		finalizedID, err := model.GetFinalizedBlockID(nil)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting finalized block")
+			return nil, false, err
+		}
+		status.Final = ts.BlockID <= finalizedID
 	} else {
 		status.Message = ts.Error
 	}
-	data.result = &status
+	return status, true, nil
+}
+
+func txstatus(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	hash, err := hex.DecodeString(data.params[`hash`].(string))
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err}).Error("decoding tx hash from hex")
+		return errorAPI(w, `E_HASHWRONG`, http.StatusBadRequest)
+	}
+	status, found, err := getTxStatus(hash, logger)
+	if err != nil {
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	if !found {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "key": hash}).Error("getting transaction status by hash")
+		return errorAPI(w, `E_HASHNOTFOUND`, http.StatusBadRequest)
+	}
+	data.result = status
 	return nil
 }
@@ -0,0 +1,116 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package apiv2
+
+import (
+	"net/http"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type validatorInfo struct {
+	KeyID        string `json:"key_id"`
+	Host         string `json:"host,omitempty"`
+	Position     string `json:"position,omitempty"`
+	Produced     string `json:"produced"`
+	Missed       string `json:"missed"`
+	DoubleSigned string `json:"double_signed"`
+	Suspended    string `json:"suspended"`
+	Faulty       bool   `json:"faulty"`
+}
+
+type evidenceInfo struct {
+	KeyID    string `json:"key_id"`
+	BlockID  string `json:"block_id"`
+	ForSign1 string `json:"for_sign1"`
+	Sign1    string `json:"sign1"`
+	ForSign2 string `json:"for_sign2"`
+	Sign2    string `json:"sign2"`
+}
+
+type validatorsResult struct {
+	List     []validatorInfo `json:"list"`
+	Evidence []evidenceInfo  `json:"evidence"`
+}
+
+func newValidatorInfo(keyID int64, stats *model.Validator) validatorInfo {
+	info := validatorInfo{KeyID: converter.Int64ToStr(keyID), Produced: `0`, Missed: `0`, DoubleSigned: `0`, Suspended: `0`}
+	if stats != nil {
+		info.Produced = converter.Int64ToStr(stats.Produced)
+		info.Missed = converter.Int64ToStr(stats.Missed)
+		info.DoubleSigned = converter.Int64ToStr(stats.DoubleSigned)
+		info.Suspended = converter.Int64ToStr(stats.Suspended)
+		info.Faulty = syspar.IsValidatorFaulty(stats.Produced, stats.Missed, stats.DoubleSigned)
+	}
+	return info
+}
+
+// validators returns the health of the full nodes: the produced and missed slots, the double signed blocks
+// and the evidences of the double signing which have been found by this node
+func validators(w http.ResponseWriter, r *http.Request, data *apiData, logger *log.Entry) error {
+	list, err := model.GetValidators()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validators")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	stats := make(map[int64]*model.Validator)
+	for i := range list {
+		stats[list[i].ID] = &list[i]
+	}
+	result := validatorsResult{List: make([]validatorInfo, 0), Evidence: make([]evidenceInfo, 0)}
+	for pos := int64(0); pos < syspar.GetNumberOfNodes(); pos++ {
+		keyID, err := syspar.GetNodeKeyByPosition(pos)
+		if err != nil {
+			continue
+		}
+		info := newValidatorInfo(keyID, stats[keyID])
+		info.Position = converter.Int64ToStr(pos)
+		if node := syspar.GetNode(keyID); node != nil {
+			info.Host = node.Host
+		}
+		result.List = append(result.List, info)
+		delete(stats, keyID)
+	}
+	for _, item := range list {
+		if stats[item.ID] != nil {
+			result.List = append(result.List, newValidatorInfo(item.ID, stats[item.ID]))
+		}
+	}
+
+	signs, err := model.GetDoubleSigns()
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting double signs")
+		return errorAPI(w, err, http.StatusInternalServerError)
+	}
+	for _, item := range signs {
+		result.Evidence = append(result.Evidence, evidenceInfo{
+			KeyID:    converter.Int64ToStr(item.KeyID),
+			BlockID:  converter.Int64ToStr(item.BlockID),
+			ForSign1: item.ForSign1,
+			Sign1:    string(converter.BinToHex(item.Sign1)),
+			ForSign2: item.ForSign2,
+			Sign2:    string(converter.BinToHex(item.Sign2)),
+		})
+	}
+	data.result = &result
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package client implements the client of REST API v2 of the node
+package client
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+)
+
+const (
+	apiPrefix = `/api/v2/`
+	// tokenExpire is the lifetime of the token in seconds which is requested during the login
+	tokenExpire = 36000
+	// refreshBefore is the time before the expiration of the token when it is refreshed
+	refreshBefore = time.Minute
+)
+
+// Client is the client of REST API. It keeps the private key to sign the login and
+// the contract calls and refreshes the token automatically.
+type Client struct {
+	mutex      sync.Mutex
+	url        string
+	httpClient *http.Client
+	private    string
+	public     string
+	ecosystem  int64
+	token      string
+	refresh    string
+	expire     time.Time
+
+	KeyID   int64
+	Address string
+}
+
+// UIDResult is the result of getuid request
+type UIDResult struct {
+	UID         string `json:"uid,omitempty"`
+	Token       string `json:"token,omitempty"`
+	Expire      string `json:"expire,omitempty"`
+	EcosystemID string `json:"ecosystem_id,omitempty"`
+	KeyID       string `json:"key_id,omitempty"`
+	Address     string `json:"address,omitempty"`
+}
+
+// LoginResult is the result of login request
+type LoginResult struct {
+	Token       string `json:"token,omitempty"`
+	Refresh     string `json:"refresh,omitempty"`
+	EcosystemID string `json:"ecosystem_id,omitempty"`
+	KeyID       string `json:"key_id,omitempty"`
+	Address     string `json:"address,omitempty"`
+	NotifyKey   string `json:"notify_key,omitempty"`
+}
+
+type refreshResult struct {
+	Token   string `json:"token,omitempty"`
+	Refresh string `json:"refresh,omitempty"`
+}
+
+// New returns the client of the node with the specified address, for example http://localhost:7079
+func New(address string) *Client {
+	return &Client{
+		url:        strings.TrimRight(address, `/`) + apiPrefix,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Ecosystem returns the ecosystem of the logged in client
+func (c *Client) Ecosystem() int64 {
+	return c.ecosystem
+}
+
+func (c *Client) send(method, api string, form *url.Values, token string, v interface{}) error {
+	var (
+		body  io.Reader
+		query string
+	)
+	if form != nil {
+		if method == `GET` {
+			query = `?` + form.Encode()
+		} else {
+			body = strings.NewReader(form.Encode())
+		}
+	}
+	req, err := http.NewRequest(method, c.url+api+query, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(`Content-Type`, `application/x-www-form-urlencoded`)
+	if len(token) > 0 {
+		req.Header.Set(`Authorization`, `Bearer `+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return parseError(resp.StatusCode, data)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Login logs in the ecosystem with the hex private key
+func (c *Client) Login(privateKey string, ecosystem int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.login(strings.TrimSpace(privateKey), ecosystem)
+}
+
+func (c *Client) login(privateKey string, ecosystem int64) error {
+	key, err := hex.DecodeString(privateKey)
+	if err != nil {
+		return fmt.Errorf(`private key is not valid hex`)
+	}
+	public, err := crypto.PrivateToPublic(key)
+	if err != nil {
+		return err
+	}
+	var uid UIDResult
+	if err = c.send(`GET`, `getuid`, nil, ``, &uid); err != nil {
+		return err
+	}
+	if len(uid.UID) == 0 {
+		return fmt.Errorf(`getuid has returned empty uid`)
+	}
+	sign, err := crypto.Sign(privateKey, uid.UID)
+	if err != nil {
+		return err
+	}
+	form := url.Values{`pubkey`: {hex.EncodeToString(public)}, `signature`: {hex.EncodeToString(sign)},
+		`ecosystem`: {converter.Int64ToStr(ecosystem)}, `expire`: {converter.Int64ToStr(tokenExpire)}}
+	var ret LoginResult
+	if err = c.send(`POST`, `login`, &form, uid.Token, &ret); err != nil {
+		return err
+	}
+	c.private = privateKey
+	c.public = hex.EncodeToString(public)
+	c.ecosystem = converter.StrToInt64(ret.EcosystemID)
+	c.KeyID = converter.StrToInt64(ret.KeyID)
+	c.Address = ret.Address
+	c.token = ret.Token
+	c.refresh = ret.Refresh
+	c.expire = time.Now().Add(tokenExpire * time.Second)
+	return nil
+}
+
+// Refresh gets the new token with the refresh token. If it fails then the client logs in again.
+func (c *Client) Refresh() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.refreshToken()
+}
+
+func (c *Client) refreshToken() error {
+	if len(c.private) == 0 {
+		return &Error{Status: http.StatusUnauthorized, Code: ErrUnauthorized, Message: `client is not logged in`}
+	}
+	var ret refreshResult
+	form := url.Values{`token`: {c.refresh}, `expire`: {converter.Int64ToStr(tokenExpire)}}
+	if err := c.send(`POST`, `refresh`, &form, c.token, &ret); err != nil {
+		return c.login(c.private, c.ecosystem)
+	}
+	c.token = ret.Token
+	c.refresh = ret.Refresh
+	c.expire = time.Now().Add(tokenExpire * time.Second)
+	return nil
+}
+
+// getToken returns the actual token and refreshes it if it is going to expire
+func (c *Client) getToken() (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(c.token) > 0 && time.Now().Add(refreshBefore).After(c.expire) {
+		if err := c.refreshToken(); err != nil {
+			return ``, err
+		}
+	}
+	return c.token, nil
+}
+
+func (c *Client) request(method, api string, form *url.Values, v interface{}) error {
+	token, err := c.getToken()
+	if err != nil {
+		return err
+	}
+	err = c.send(method, api, form, token, v)
+	if IsError(err, ErrTokenExpired) || IsError(err, ErrToken) {
+		// the token can be expired if the clocks of the client and the node are different
+		if err = c.Refresh(); err != nil {
+			return err
+		}
+		if token, err = c.getToken(); err != nil {
+			return err
+		}
+		err = c.send(method, api, form, token, v)
+	}
+	return err
+}
+
+// Get sends GET request to the specified API method and unmarshals the result into v
+func (c *Client) Get(api string, form *url.Values, v interface{}) error {
+	return c.request(`GET`, api, form, v)
+}
+
+// Post sends POST request to the specified API method and unmarshals the result into v
+func (c *Client) Post(api string, form *url.Values, v interface{}) error {
+	return c.request(`POST`, api, form, v)
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"testing"
+)
+
+// The tests use the same node and the key file as the tests of apiv2 package
+const (
+	testNode    = `http://localhost:7079`
+	testKeyFile = `../apiv2/key`
+)
+
+func testLogin() (*Client, error) {
+	key, err := ioutil.ReadFile(testKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) > 64 {
+		key = key[:64]
+	}
+	c := New(testNode)
+	return c, c.Login(string(key), 1)
+}
+
+func TestLogin(t *testing.T) {
+	c, err := testLogin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if c.KeyID == 0 || len(c.Address) == 0 {
+		t.Error(fmt.Errorf(`wrong login result %d %s`, c.KeyID, c.Address))
+		return
+	}
+	if err = c.Refresh(); err != nil {
+		t.Error(err)
+		return
+	}
+	err = New(testNode).Login(`qwerty`, 1)
+	if err == nil {
+		t.Error(`wrong private key must be rejected`)
+	}
+}
+
+func TestCallContract(t *testing.T) {
+	c, err := testLogin()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	blockID, _, err := c.CallContract(`MoneyTransfer`, url.Values{`Amount`: {`1000`},
+		`Recipient`: {`0005-2070-2000-0006-0200`}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if blockID == 0 {
+		t.Error(`block id is zero`)
+		return
+	}
+	_, _, err = c.CallContract(`UnknownContract`, url.Values{})
+	if !IsError(err, ErrContract) {
+		t.Error(fmt.Errorf(`unknown contract must return %s error %v`, ErrContract, err))
+		return
+	}
+	_, err = c.TxStatus(`qwerty`)
+	if !IsError(err, ErrHashWrong) {
+		t.Error(fmt.Errorf(`wrong hash must return %s error %v`, ErrHashWrong, err))
+	}
+}
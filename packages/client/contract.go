@@ -0,0 +1,161 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+)
+
+const (
+	// waitTxPeriod is the period of checking the status of the transaction
+	waitTxPeriod = time.Second
+	// WaitTxTimeout is the default timeout of waiting for the transaction
+	WaitTxTimeout = 30 * time.Second
+)
+
+// SignJSON is the additional signature which is required by the contract
+type SignJSON struct {
+	ForSign string `json:"forsign"`
+	Field   string `json:"field"`
+	Title   string `json:"title"`
+}
+
+// PrepareResult is the result of prepare request
+type PrepareResult struct {
+	ForSign string            `json:"forsign"`
+	Signs   []SignJSON        `json:"signs"`
+	Values  map[string]string `json:"values"`
+	Time    string            `json:"time"`
+}
+
+type contractResult struct {
+	Hash string `json:"hash"`
+}
+
+// TxStatus is the status of the transaction
+type TxStatus struct {
+	BlockID string `json:"blockid"`
+	Message string `json:"errmsg"`
+	Result  string `json:"result"`
+	Final   bool   `json:"final"`
+}
+
+func copyForm(params url.Values) url.Values {
+	form := url.Values{}
+	for key, values := range params {
+		form[key] = append([]string{}, values...)
+	}
+	return form
+}
+
+// Sign signs the data with the private key of the client and returns the hex signature
+func (c *Client) Sign(forSign string) (string, error) {
+	c.mutex.Lock()
+	private := c.private
+	c.mutex.Unlock()
+	sign, err := crypto.Sign(private, forSign)
+	if err != nil {
+		return ``, err
+	}
+	return hex.EncodeToString(sign), nil
+}
+
+// Prepare returns the data for signing the call of the contract
+func (c *Client) Prepare(name string, params url.Values) (*PrepareResult, error) {
+	var ret PrepareResult
+	form := copyForm(params)
+	if err := c.Post(`prepare/`+name, &form, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// Send signs the prepared call of the contract and sends it. It returns the hash of the transaction.
+func (c *Client) Send(name string, params url.Values, prepared *PrepareResult) (string, error) {
+	form := copyForm(params)
+	for _, sign := range prepared.Signs {
+		signature, err := c.Sign(sign.ForSign)
+		if err != nil {
+			return ``, err
+		}
+		form.Set(sign.Field, signature)
+	}
+	signature, err := c.Sign(prepared.ForSign)
+	if err != nil {
+		return ``, err
+	}
+	form.Set(`time`, prepared.Time)
+	form.Set(`signature`, signature)
+	form.Set(`pubkey`, c.public)
+	var ret contractResult
+	if err = c.Post(`contract/`+name, &form, &ret); err != nil {
+		return ``, err
+	}
+	return ret.Hash, nil
+}
+
+// TxStatus returns the status of the transaction
+func (c *Client) TxStatus(hash string) (*TxStatus, error) {
+	var ret TxStatus
+	if err := c.Get(`txstatus/`+hash, nil, &ret); err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+// WaitTx waits for the transaction to be included in the block. It returns TxError if the transaction
+// has been rejected.
+func (c *Client) WaitTx(hash string, timeout time.Duration) (*TxStatus, error) {
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(waitTxPeriod) {
+		status, err := c.TxStatus(hash)
+		if err != nil && !IsError(err, ErrHashNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			if len(status.BlockID) > 0 {
+				return status, nil
+			}
+			if len(status.Message) > 0 {
+				return status, &TxError{Hash: hash, Message: status.Message}
+			}
+		}
+	}
+	return nil, &TxError{Hash: hash, Message: `timeout of waiting for transaction`}
+}
+
+// CallContract prepares, signs and sends the call of the contract and waits for its result.
+// It returns the block id and the result of the contract.
+func (c *Client) CallContract(name string, params url.Values) (int64, string, error) {
+	prepared, err := c.Prepare(name, params)
+	if err != nil {
+		return 0, ``, err
+	}
+	hash, err := c.Send(name, params, prepared)
+	if err != nil {
+		return 0, ``, err
+	}
+	status, err := c.WaitTx(hash, WaitTxTimeout)
+	if err != nil {
+		return 0, ``, err
+	}
+	return converter.StrToInt64(status.BlockID), status.Result, nil
+}
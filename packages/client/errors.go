@@ -0,0 +1,94 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Error codes which are returned by API. They match the errors of apiv2 package.
+const (
+	ErrAccessDenied   = `E_ACCESSDENIED`
+	ErrBatchSign      = `E_BATCHSIGN`
+	ErrBatchSize      = `E_BATCHSIZE`
+	ErrColumnAccess   = `E_COLUMNACCESS`
+	ErrContract       = `E_CONTRACT`
+	ErrDBNil          = `E_DBNIL`
+	ErrEcosystem      = `E_ECOSYSTEM`
+	ErrEmptyPublic    = `E_EMPTYPUBLIC`
+	ErrEmptySign      = `E_EMPTYSIGN`
+	ErrFilter         = `E_FILTER`
+	ErrHashWrong      = `E_HASHWRONG`
+	ErrHashNotFound   = `E_HASHNOTFOUND`
+	ErrInstalled      = `E_INSTALLED`
+	ErrInvalidWallet  = `E_INVALIDWALLET`
+	ErrNotFound       = `E_NOTFOUND`
+	ErrNotInstalled   = `E_NOTINSTALLED`
+	ErrQuery          = `E_QUERY`
+	ErrRecovered      = `E_RECOVERED`
+	ErrRefreshToken   = `E_REFRESHTOKEN`
+	ErrServer         = `E_SERVER`
+	ErrSignature      = `E_SIGNATURE`
+	ErrStateLogin     = `E_STATELOGIN`
+	ErrTableNotFound  = `E_TABLENOTFOUND`
+	ErrToken          = `E_TOKEN`
+	ErrTokenExpired   = `E_TOKENEXPIRED`
+	ErrUnauthorized   = `E_UNAUTHORIZED`
+	ErrUndefinedValue = `E_UNDEFINEVAL`
+	ErrUnknownUID     = `E_UNKNOWNUID`
+)
+
+// Error is the error which has been returned by API
+type Error struct {
+	Status  int      // HTTP status code
+	Code    string   `json:"error"`
+	Message string   `json:"msg"`
+	Params  []string `json:"params"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf(`%d %s: %s`, e.Status, e.Code, e.Message)
+}
+
+// TxError is the error of the transaction which has been rejected by the node or by the contract
+type TxError struct {
+	Hash    string
+	Message string
+}
+
+func (e *TxError) Error() string {
+	return fmt.Sprintf(`transaction %s: %s`, e.Hash, e.Message)
+}
+
+// IsError returns true if err is API error with the specified code
+func IsError(err error, code string) bool {
+	if apiErr, ok := err.(*Error); ok {
+		return apiErr.Code == code
+	}
+	return false
+}
+
+func parseError(status int, data []byte) error {
+	apiErr := Error{Status: status}
+	if err := json.Unmarshal(data, &apiErr); err != nil || len(apiErr.Code) == 0 {
+		apiErr.Code = ErrServer
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return &apiErr
+}
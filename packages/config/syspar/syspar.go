@@ -25,6 +25,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/AplaProject/go-apla/packages/utils"
@@ -57,6 +58,21 @@ const (
 	SizeFuel = `size_fuel`
 	// CommissionWallet is the address for commissions
 	CommissionWallet = `commission_wallet`
+	// VMCostTable is the table of the costs of the commands of the virtual machine
+	VMCostTable = `vm_cost_table`
+	// TCPAuthRequired is 1 if full nodes must authenticate by the handshake to send blocks
+	TCPAuthRequired = `tcp_auth_required`
+	// SnapshotPeriod is the number of blocks between snapshots of the state, 0 disables snapshots
+	SnapshotPeriod = `snapshot_period`
+	// ValidatorMaxMissed is the percent of missed slots after which the full node can be suspended by anyone
+	ValidatorMaxMissed = `validator_max_missed`
+	// ValidatorMinSlots is the number of slots which the full node must have to be suspended for the missed slots
+	ValidatorMinSlots = `validator_min_slots`
+	// ValidatorsForkHeight is the block from which the slots and the double signing of the full nodes are tracked,
+	// 0 disables the tracking
+	ValidatorsForkHeight = `validators_fork_height`
+	// NonceForkHeight is the block from which the nonce of the transaction is signed, 0 disables the nonce
+	NonceForkHeight = `nonce_fork_height`
 	// rollback from queue_bocks
 	RbBlocks1 = `rb_blocks_1`
 	// rollback from blocks_collection
@@ -76,6 +92,7 @@ var (
 	nodesByPosition = make([][]string, 0)
 	fuels           = make(map[int64]string)
 	wallets         = make(map[int64]string)
+	costTable       *script.CostTable
 	mutex           = &sync.RWMutex{}
 )
 
@@ -134,6 +151,13 @@ func SysUpdate() error {
 		}
 		return res, nil
 	}
+	if len(cache[VMCostTable]) == 0 {
+		costTable = nil
+	} else if table, err := script.ParseCostTable(cache[VMCostTable]); err == nil {
+		costTable = table
+	} else {
+		log.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("parsing cost table of virtual machine")
+	}
 	fuels, err = getParams(FuelRate)
 	wallets, err = getParams(CommissionWallet)
 
@@ -176,6 +200,16 @@ func GetNodeByPosition(position int64) (*FullNode, error) {
 	return nodes[converter.StrToInt64(nodesByPosition[position][1])], nil
 }
 
+// GetNodeKeyByPosition returns the key of the full node by its position
+func GetNodeKeyByPosition(position int64) (int64, error) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	if position < 0 || int64(len(nodesByPosition)) <= position || len(nodesByPosition[position]) < 3 {
+		return 0, fmt.Errorf("incorrect position")
+	}
+	return converter.StrToInt64(nodesByPosition[position][1]), nil
+}
+
 func GetNodeHostByPosition(position int64) (string, error) {
 	mutex.RLock()
 	defer mutex.RUnlock()
@@ -261,6 +295,15 @@ func GetFuelRate(ecosystem int64) string {
 	return ``
 }
 
+// GetCostTable returns the table of the costs of the commands of the virtual machine which has been
+// set by vm_cost_table system parameter. It returns nil if the parameter is empty, in this case
+// the virtual machine charges the default costs.
+func GetCostTable() *script.CostTable {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return costTable
+}
+
 func GetCommissionWallet(ecosystem int64) string {
 	mutex.RLock()
 	defer mutex.RUnlock()
@@ -325,6 +368,39 @@ func SysString(name string) string {
 	return ret
 }
 
+// IsTCPAuthRequired returns true if full nodes must authenticate by the handshake to send blocks
+func IsTCPAuthRequired() bool {
+	return SysInt64(TCPAuthRequired) == 1
+}
+
+// IsSnapshotBlock returns true if the snapshot of the state must be made after the block
+func IsSnapshotBlock(blockID int64) bool {
+	period := SysInt64(SnapshotPeriod)
+	return period > 0 && blockID%period == 0
+}
+
+// IsNonceFork returns true if the transactions of the block can have the nonce
+func IsNonceFork(blockID int64) bool {
+	height := SysInt64(NonceForkHeight)
+	return height > 0 && blockID >= height
+}
+
+// IsValidatorsFork returns true if the slots and the double signing of the full nodes are tracked in the block
+func IsValidatorsFork(blockID int64) bool {
+	height := SysInt64(ValidatorsForkHeight)
+	return height > 0 && blockID >= height
+}
+
+// IsValidatorFaulty returns true if the full node has signed two different blocks with the same block id
+// or has missed too many slots
+func IsValidatorFaulty(produced, missed, doubleSigned int64) bool {
+	if doubleSigned > 0 {
+		return true
+	}
+	slots := produced + missed
+	return slots > 0 && slots >= SysInt64(ValidatorMinSlots) && missed*100 > SysInt64(ValidatorMaxMissed)*slots
+}
+
 func GetRbBlocks1() int64 {
 	return SysInt64(RbBlocks1)
 }
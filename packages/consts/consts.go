@@ -49,6 +49,10 @@ const TCP_PORT = "7078"
 
 const DATA_TYPE_MAX_BLOCK_ID = 10
 const DATA_TYPE_BLOCK_BODY = 7
+const DATA_TYPE_BLOCKS_RANGE = 11
+const DATA_TYPE_SNAPSHOT = 12
+const DATA_TYPE_PEERS = 13
+const DATA_TYPE_VOTES = 14
 
 const UPD_AND_VER_URL = "http://apla.io"
 
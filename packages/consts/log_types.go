@@ -25,6 +25,7 @@ const (
 	BlockError               = "Block"
 	ParserError              = "Parser"
 	ContextError             = "Context"
+	LockError                = "Lock"
 	SessionError             = "Session"
 	RouteError               = "Route"
 	NotFound                 = "NotFound"
@@ -189,6 +189,11 @@ func _PKCS7UnPadding(src []byte) ([]byte, error) {
 
 }
 
+// SharedKey returns the shared key of the private key and the public key of other party.
+func SharedKey(private, public []byte) ([]byte, error) {
+	return getSharedKey(private, public)
+}
+
 // GetSharedKey creates and returns the shared key = private * public.
 // public must be the public key from the different private key.
 func getSharedKey(private, public []byte) (shared []byte, err error) {
@@ -23,6 +23,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/config/syspar"
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/mempool"
 	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/parser"
 	"github.com/AplaProject/go-apla/packages/utils"
@@ -47,8 +48,10 @@ func BlockGenerator(d *daemon, ctx context.Context) error {
 		return nil
 	}
 
-	DBLock()
-	defer DBUnlock()
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	defer DBUnlock(d.goRoutineName)
 
 	// wee need fresh myNodePosition after locking
 	myNodePosition, err = syspar.GetNodePositionByKeyID(config.KeyID)
@@ -88,6 +91,7 @@ func BlockGenerator(d *daemon, ctx context.Context) error {
 		return err
 	}
 
+	start := time.Now()
 	p := new(parser.Parser)
 
 	// verify transactions
@@ -96,13 +100,12 @@ func BlockGenerator(d *daemon, ctx context.Context) error {
 		return err
 	}
 
-	trs, err := model.GetAllUnusedTransactions()
-	if err != nil || trs == nil {
-		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all unused transactions")
+	trs, err := mempool.BlockTransactions()
+	if err != nil {
 		return err
 	}
 
-	blockBin, err := generateNextBlock(prevBlock, *trs, nodeKey.PrivateKey, config, time.Now().Unix(), myNodePosition)
+	blockBin, err := generateNextBlock(prevBlock, trs, nodeKey.PrivateKey, config, time.Now().Unix(), myNodePosition)
 	if err != nil {
 		return err
 	}
@@ -111,6 +114,8 @@ func BlockGenerator(d *daemon, ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	blockGenerationTime.ObserveSince(start)
+	blocksGenerated.Inc()
 
 	return nil
 }
@@ -24,6 +24,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/AplaProject/go-apla/packages/config/syspar"
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -31,6 +32,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/parser"
 	"github.com/AplaProject/go-apla/packages/static"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
 	"github.com/AplaProject/go-apla/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -83,7 +85,7 @@ func blocksCollection(d *daemon, ctx context.Context) error {
 
 	// TODO: ????? remove from all tables in some test mode ?????
 
-	hosts := syspar.GetHosts()
+	hosts := getPeerHosts(syncPeersCount, d.logger)
 
 	// get a host with the biggest block id
 	host, maxBlockID, err := chooseBestHost(ctx, hosts, d.logger)
@@ -91,14 +93,16 @@ func blocksCollection(d *daemon, ctx context.Context) error {
 		return err
 	}
 
-	DBLock()
-	defer DBUnlock()
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	defer DBUnlock(d.goRoutineName)
 	// update our chain till maxBlockID from the host
 	if err := UpdateChain(ctx, d, host, maxBlockID, "rollback_blocks_2"); err != nil {
 		return err
 	}
 
-	return nil
+	return model.UpdatePeerScore(host, 1, maxPeerScore)
 }
 
 // best host is a host with the biggest last block ID
@@ -141,6 +145,9 @@ func chooseBestHost(ctx context.Context, hosts []string, logger *log.Entry) (str
 			bestHost = bl.host
 		}
 	}
+	if maxBlockID >= 0 {
+		atomic.StoreInt64(&peersBlockID, maxBlockID)
+	}
 
 	return bestHost, maxBlockID, nil
 }
@@ -171,6 +178,19 @@ func getHostBlockID(host string, logger *log.Entry) (int64, error) {
 	return converter.BinToDec(blockIDBin), nil
 }
 
+// getBlockBody downloads the block from the host. The connection is encrypted if this node is the full node
+func getBlockBody(host string, blockID int64, node *tcpserver.NodeIdentity, logger *log.Entry) ([]byte, error) {
+	if node == nil {
+		return utils.GetBlockBody(host, blockID, consts.DATA_TYPE_BLOCK_BODY)
+	}
+	conn, rw, err := handshakeConn(host, node, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return utils.ReadBlockBody(rw, blockID, consts.DATA_TYPE_BLOCK_BODY)
+}
+
 // load from host all blocks from our last block to maxBlockID
 func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64, rollbackBlocks string) error {
 
@@ -181,13 +201,19 @@ func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64,
 		return err
 	}
 
+	node, err := tcpserver.LocalIdentity()
+	if err != nil {
+		return err
+	}
+	stream := newBlocksStream(host, node, maxBlockID, d.logger)
+	defer stream.close()
 	for blockID := curBlock.BlockID + 1; blockID <= maxBlockID; blockID++ {
 		if ctx.Err() != nil {
 			d.logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
 			return ctx.Err()
 		}
 
-		blockBin, err := utils.GetBlockBody(host, blockID, consts.DATA_TYPE_BLOCK_BODY)
+		blockBin, err := stream.get(blockID)
 		if err != nil {
 			d.logger.WithFields(log.Fields{"error": err, "type": consts.BlockError}).Error("getting block body")
 			return err
@@ -227,7 +253,6 @@ func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64,
 
 		block.PrevHeader, err = parser.GetBlockDataFromBlockChain(block.Header.BlockID - 1)
 		if err != nil {
-			banNode(host, err)
 			return utils.ErrInfo(fmt.Errorf("can't get block %d", block.Header.BlockID-1))
 		}
 		if err = block.CheckBlock(); err != nil {
@@ -235,7 +260,6 @@ func UpdateChain(ctx context.Context, d *daemon, host string, maxBlockID int64,
 			return err
 		}
 		if err = block.PlayBlockSafe(); err != nil {
-			banNode(host, err)
 			return err
 		}
 	}
@@ -286,8 +310,10 @@ func loadFirstBlock(logger *log.Entry) error {
 
 func firstLoad(ctx context.Context, d *daemon) error {
 
-	DBLock()
-	defer DBUnlock()
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	defer DBUnlock(d.goRoutineName)
 
 	nodeConfig := &model.Config{}
 	_, err := nodeConfig.Get()
@@ -312,6 +338,9 @@ func firstLoad(ctx context.Context, d *daemon) error {
 		if err != nil {
 			return err
 		}
+	} else if nodeConfig.FirstLoadBlockchain == "snapshot" {
+		// the later blocks are downloaded from the nodes by blocksCollection
+		err = loadSnapshot(ctx, nodeConfig.FirstLoadBlockchainURL, d.logger)
 	} else {
 		err = loadFirstBlock(d.logger)
 	}
@@ -334,10 +363,6 @@ func needLoad(logger *log.Entry) (bool, error) {
 	return false, nil
 }
 
-func banNode(host string, err error) {
-	// TODO
-}
-
 func loadFromFile(ctx context.Context, fileName string, logger *log.Entry) error {
 	file, err := os.Open(fileName)
 	if err != nil {
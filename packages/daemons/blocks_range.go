@@ -0,0 +1,151 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// blocksRangeSize is the byte budget of one batch of blocks
+	blocksRangeSize = 4 * 1024 * 1024
+	// blocksRangeRetries is the count of the attempts to resume the download after the connection failure
+	blocksRangeRetries = 3
+)
+
+var errBlocksRange = errors.New("host does not support the range of blocks")
+
+// blocksStream downloads the blocks from the host by batches over one connection.
+// The download is resumed from the next block on the new connection if the connection fails.
+// The blocks are downloaded one by one if the host doesn't support the range of blocks
+type blocksStream struct {
+	host    string
+	node    *tcpserver.NodeIdentity
+	maxID   int64
+	conn    net.Conn
+	rw      io.ReadWriter
+	blocks  [][]byte
+	nextID  int64 // the identifier of the next block in blocks
+	single  bool  // blocks are downloaded one by one
+	started bool  // the host has sent any range of blocks
+	logger  *log.Entry
+}
+
+func newBlocksStream(host string, node *tcpserver.NodeIdentity, maxID int64, logger *log.Entry) *blocksStream {
+	return &blocksStream{host: host, node: node, maxID: maxID, logger: logger}
+}
+
+func (s *blocksStream) close() {
+	if s.conn == nil {
+		return
+	}
+	tcpserver.SendRequest(&tcpserver.BlocksRangeRequest{}, s.rw)
+	s.conn.Close()
+	s.conn = nil
+}
+
+// get returns the body of the block
+func (s *blocksStream) get(blockID int64) ([]byte, error) {
+	if s.nextID != blockID {
+		s.blocks, s.nextID = nil, blockID
+	}
+	if len(s.blocks) == 0 && !s.single {
+		var err error
+		for i := 0; i < blocksRangeRetries; i++ {
+			if err = s.load(blockID); err == nil || err == errBlocksRange {
+				break
+			}
+			if len(s.blocks) > 0 {
+				// the received blocks are used and the download is resumed on the next call
+				err = nil
+				break
+			}
+			s.logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": s.host, "block_id": blockID}).Warning("resuming download of blocks")
+		}
+		if err == errBlocksRange {
+			s.logger.WithFields(log.Fields{"type": consts.ProtocolError, "host": s.host}).Warning("downloading blocks one by one")
+			s.single = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if s.single {
+		return getBlockBody(s.host, blockID, s.node, s.logger)
+	}
+	data := s.blocks[0]
+	s.blocks = s.blocks[1:]
+	s.nextID++
+	return data, nil
+}
+
+// load downloads the batch of blocks starting with blockID
+func (s *blocksStream) load(blockID int64) (err error) {
+	defer func() {
+		if err != nil && s.conn != nil {
+			s.conn.Close()
+			s.conn = nil
+		}
+	}()
+	if s.conn == nil {
+		if s.conn, s.rw, err = handshakeConn(s.host, s.node, s.logger); err != nil {
+			return err
+		}
+		if err = tcpserver.SendRequest(&tcpserver.TransactionType{Type: consts.DATA_TYPE_BLOCKS_RANGE}, s.rw); err != nil {
+			return err
+		}
+	}
+	s.conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
+	s.conn.SetWriteDeadline(time.Now().Add(consts.WRITE_TIMEOUT * time.Second))
+	err = tcpserver.SendRequest(&tcpserver.BlocksRangeRequest{FromID: uint32(blockID), ToID: uint32(s.maxID),
+		MaxSize: blocksRangeSize}, s.rw)
+	if err != nil {
+		return err
+	}
+	for id := blockID; ; id++ {
+		body := &tcpserver.BlockBody{}
+		if err = tcpserver.ReadRequest(body, s.rw); err != nil {
+			if err == io.EOF && !s.started && len(s.blocks) == 0 {
+				// the old version of the node closes the connection on unknown request
+				return errBlocksRange
+			}
+			return err
+		}
+		if body.BlockID == 0 {
+			break
+		}
+		if int64(body.BlockID) != id {
+			s.logger.WithFields(log.Fields{"type": consts.ProtocolError, "block_id": body.BlockID, "host": s.host}).Error("wrong order of blocks")
+			return fmt.Errorf("wrong block %d instead of %d", body.BlockID, id)
+		}
+		s.blocks = append(s.blocks, body.Data)
+	}
+	if len(s.blocks) == 0 {
+		s.logger.WithFields(log.Fields{"type": consts.NotFound, "block_id": blockID, "host": s.host}).Error("host has not sent blocks")
+		return fmt.Errorf("host %s has not sent block %d", s.host, blockID)
+	}
+	s.started = true
+	return nil
+}
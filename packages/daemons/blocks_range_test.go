@@ -0,0 +1,82 @@
+package daemons
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// rangeServer serves the blocks by ranges of two blocks and drops the connection after drop batches
+func rangeServer(t *testing.T, legacy bool, drop int) (string, func()) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				dType := &tcpserver.TransactionType{}
+				if tcpserver.ReadRequest(dType, conn) != nil {
+					return
+				}
+				if dType.Type == consts.DATA_TYPE_BLOCK_BODY {
+					req := &tcpserver.GetBodyRequest{}
+					tcpserver.ReadRequest(req, conn)
+					tcpserver.SendRequest(&tcpserver.GetBodyResponse{Data: []byte(fmt.Sprint(`block`, req.BlockID))}, conn)
+					return
+				}
+				if legacy {
+					return
+				}
+				for batch := 1; ; batch++ {
+					req := &tcpserver.BlocksRangeRequest{}
+					if tcpserver.ReadRequest(req, conn) != nil || req.FromID == 0 {
+						return
+					}
+					for id := req.FromID; id <= req.ToID && id < req.FromID+2; id++ {
+						tcpserver.SendRequest(&tcpserver.BlockBody{BlockID: id, Data: []byte(fmt.Sprint(`block`, id))}, conn)
+					}
+					if batch == drop {
+						return
+					}
+					tcpserver.SendRequest(&tcpserver.BlockBody{}, conn)
+				}
+			}(conn)
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func TestBlocksStream(t *testing.T) {
+	for _, item := range []struct {
+		legacy bool
+		drop   int
+	}{{false, 0}, {false, 2}, {true, 0}} {
+		host, stop := rangeServer(t, item.legacy, item.drop)
+		stream := newBlocksStream(host, nil, 7, log.WithFields(log.Fields{}))
+		for id := int64(3); id <= 7; id++ {
+			data, err := stream.get(id)
+			if err != nil {
+				t.Fatalf("getting block %d: %v", id, err)
+			}
+			if string(data) != fmt.Sprint(`block`, id) {
+				t.Errorf("wrong block %d: %s", id, data)
+			}
+		}
+		if stream.single != item.legacy {
+			t.Errorf("wrong mode of download for legacy %v", item.legacy)
+		}
+		stream.close()
+		stop()
+	}
+}
@@ -52,6 +52,9 @@ var daemonsList = map[string]func(*daemon, context.Context) error{
 	"QueueParserTx":      QueueParserTx,
 	"QueueParserBlocks":  QueueParserBlocks,
 	"Confirmations":      Confirmations,
+	"PeerDiscovery":      PeerDiscovery,
+	"Finality":           Finality,
+	"Snapshots":          Snapshots,
 }
 
 var serverList = []string{
@@ -62,6 +65,9 @@ var serverList = []string{
 	"QueueParserBlocks",
 	"Disseminator",
 	"Confirmations",
+	"PeerDiscovery",
+	"Finality",
+	"Snapshots",
 }
 
 var mobileList = []string{
@@ -20,12 +20,14 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"net"
 	"sync"
 
 	"github.com/AplaProject/go-apla/packages/config/syspar"
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
 	"github.com/AplaProject/go-apla/packages/utils"
 
 	log "github.com/sirupsen/logrus"
@@ -57,7 +59,11 @@ func Disseminator(d *daemon, ctx context.Context) error {
 	if isFullNode {
 		// send blocks and transactions hashes
 		d.logger.Debug("we are full_node, sending hashes")
-		return sendHashes(myNodePosition, d.logger)
+		node, err := tcpserver.LocalIdentity()
+		if err != nil {
+			return err
+		}
+		return sendHashes(myNodePosition, node, d.logger)
 	} else {
 		// we are not full node for this StateID and WalletID, so just send transactions
 		d.logger.Debug("we are full_node, sending transactions")
@@ -84,7 +90,7 @@ func sendTransactions(logger *log.Entry) error {
 	}
 
 	if buf.Len() > 0 {
-		err := sendPacketToAll(I_AM_NOT_FULL_NODE, buf.Bytes(), nil, logger)
+		err := sendPacketToAll(I_AM_NOT_FULL_NODE, buf.Bytes(), nil, nil, logger)
 		if err != nil {
 			return err
 		}
@@ -102,7 +108,7 @@ func sendTransactions(logger *log.Entry) error {
 }
 
 // send block and transactions hashes
-func sendHashes(fullNodeID int64, logger *log.Entry) error {
+func sendHashes(fullNodeID int64, node *tcpserver.NodeIdentity, logger *log.Entry) error {
 	block, err := model.BlockGetUnsent()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting unsent blocks")
@@ -123,7 +129,7 @@ func sendHashes(fullNodeID int64, logger *log.Entry) error {
 
 	buf := prepareHashReq(block, trs, fullNodeID)
 	if buf != nil || len(buf) > 0 {
-		err := sendPacketToAll(I_AM_FULL_NODE, buf, sendHashesResp, logger)
+		err := sendPacketToAll(I_AM_FULL_NODE, buf, node, sendHashesResp, logger)
 		if err != nil {
 			return err
 		}
@@ -216,7 +222,7 @@ func MarshallTrHash(tr model.Transaction) []byte {
 	return tr.Hash
 }
 
-func sendPacketToAll(reqType int, buf []byte, respHand func(resp []byte, w io.Writer, logger *log.Entry) error, logger *log.Entry) error {
+func sendPacketToAll(reqType int, buf []byte, node *tcpserver.NodeIdentity, respHand func(resp []byte, w io.Writer, logger *log.Entry) error, logger *log.Entry) error {
 	hosts := syspar.GetHosts()
 	log.Debug("sendPacketToAll", hosts)
 	var wg sync.WaitGroup
@@ -224,7 +230,7 @@ func sendPacketToAll(reqType int, buf []byte, respHand func(resp []byte, w io.Wr
 	for _, host := range hosts {
 		wg.Add(1)
 		go func(h string) {
-			sendDRequest(h, reqType, buf, respHand, logger)
+			sendDRequest(h, reqType, buf, node, respHand, logger)
 			wg.Done()
 		}(getHostPort(host))
 	}
@@ -233,6 +239,37 @@ func sendPacketToAll(reqType int, buf []byte, respHand func(resp []byte, w io.Wr
 	return nil
 }
 
+// handshakeConn connects to the host and authenticates with the key of the node if node is not nil.
+// The requests must be written to the returned io.ReadWriter which is encrypted if the host supports it.
+// The connection without the handshake is returned if the handshake fails, unless the encryption
+// or the authentication of the full nodes is required
+func handshakeConn(host string, node *tcpserver.NodeIdentity, logger *log.Entry) (net.Conn, io.ReadWriter, error) {
+	conn, err := utils.TCPConn(host)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("tcp connection to host")
+		return nil, nil, err
+	}
+	if node == nil {
+		return conn, conn, nil
+	}
+	session, err := tcpserver.Handshake(conn, host, node)
+	if err == nil {
+		return conn, session.Conn, nil
+	}
+	conn.Close()
+	if *utils.TCPEncryption == utils.TCPEncryptionRequired || syspar.IsTCPAuthRequired() {
+		logger.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "host": host}).Error("handshake failed")
+		return nil, nil, err
+	}
+	logger.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "host": host}).Warning("handshake failed, sending without handshake")
+	conn, err = utils.TCPConn(host)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("tcp connection to host")
+		return nil, nil, err
+	}
+	return conn, conn, nil
+}
+
 /*
 Packet format:
 type  2 bytes
@@ -240,16 +277,15 @@ len   4 bytes
 data  len bytes
 */
 
-func sendDRequest(host string, reqType int, buf []byte, respHandler func([]byte, io.Writer, *log.Entry) error, logger *log.Entry) error {
-	conn, err := utils.TCPConn(host)
+func sendDRequest(host string, reqType int, buf []byte, node *tcpserver.NodeIdentity, respHandler func([]byte, io.Writer, *log.Entry) error, logger *log.Entry) error {
+	conn, rw, err := handshakeConn(host, node, logger)
 	if err != nil {
-		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("tcp connection to host")
 		return err
 	}
 	defer conn.Close()
 
 	// type
-	_, err = conn.Write(converter.DecToBin(reqType, 2))
+	_, err = rw.Write(converter.DecToBin(reqType, 2))
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("writing request type to host")
 		return err
@@ -258,14 +294,14 @@ func sendDRequest(host string, reqType int, buf []byte, respHandler func([]byte,
 
 	// data size
 	size := converter.DecToBin(len(buf), 4)
-	_, err = conn.Write(size)
+	_, err = rw.Write(size)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("writing data size to host")
 		return err
 	}
 
 	// data
-	_, err = conn.Write(buf)
+	_, err = rw.Write(buf)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("writing data to host")
 		return err
@@ -275,7 +311,7 @@ func sendDRequest(host string, reqType int, buf []byte, respHandler func([]byte,
 	if respHandler != nil {
 		buf := make([]byte, 4)
 		// read data size
-		_, err = io.ReadFull(conn, buf)
+		_, err = io.ReadFull(rw, buf)
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("reading data size")
 		}
@@ -287,12 +323,12 @@ func sendDRequest(host string, reqType int, buf []byte, respHandler func([]byte,
 		}
 		// read the data
 		resp := make([]byte, respSize)
-		_, err = io.ReadFull(conn, resp)
+		_, err = io.ReadFull(rw, resp)
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("reading data")
 			return err
 		}
-		err = respHandler(resp, conn, logger)
+		err = respHandler(resp, rw, logger)
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.IOError, "error": err, "host": host}).Error("reading data")
 			return err
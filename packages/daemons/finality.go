@@ -0,0 +1,299 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/finality"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// finalityDepth is the maximum count of the blocks after the finalized block which are voted at once
+	finalityDepth = 10
+	// maxVotesRequest is the maximum count of the votes which are sent to the host at once
+	maxVotesRequest = 1000
+	// roundTimeout is the duration of the round of voting, the full node votes in the next round
+	// if the block hasn't been finalized during the round
+	roundTimeout = 20 * time.Second
+)
+
+// votingRound is the current round of voting of this node for the block
+type votingRound struct {
+	round int32
+	start time.Time
+}
+
+// rounds contains the rounds of voting for the blocks after the finalized block
+var rounds = make(map[int64]*votingRound)
+
+// Finality signs the votes of this full node for the last blocks, exchanges the votes with the full nodes
+// and finalizes the last block which has got the precommits of more than 2/3 of the full nodes
+func Finality(d *daemon, ctx context.Context) error {
+	d.sleepTime = 2 * time.Second
+	node, err := tcpserver.LocalIdentity()
+	if err != nil {
+		return err
+	}
+	finalizedID, err := model.GetFinalizedBlockID(nil)
+	if err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting finalized block")
+		return err
+	}
+	infoBlock := &model.InfoBlock{}
+	if _, err = infoBlock.Get(); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return err
+	}
+	for blockID := range rounds {
+		if blockID <= finalizedID {
+			delete(rounds, blockID)
+		}
+	}
+	if infoBlock.BlockID <= finalizedID {
+		return nil
+	}
+	fromID, toID := finalizedID+1, infoBlock.BlockID
+	if toID > finalizedID+finalityDepth {
+		toID = finalizedID + finalityDepth
+	}
+	if node != nil {
+		ok, err := DBLock(ctx, d.goRoutineName)
+		if !ok {
+			return err
+		}
+		err = voteBlocks(fromID, toID, node, d.logger)
+		DBUnlock(d.goRoutineName)
+		if err != nil {
+			return err
+		}
+	}
+	for _, host := range syspar.GetHosts() {
+		if ctx.Err() != nil {
+			d.logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
+			return ctx.Err()
+		}
+		exchangeVotes(tcpserver.PeerAddress(host), fromID, node, d.logger)
+	}
+	// the blocks can't be replaced by blocksCollection while they are voted and finalized
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	defer DBUnlock(d.goRoutineName)
+	if node != nil {
+		// the received prevotes can allow to precommit
+		if err = voteBlocks(fromID, toID, node, d.logger); err != nil {
+			return err
+		}
+	}
+	return finalizeBlocks(fromID, toID, d.logger)
+}
+
+// currentRound returns the round of voting of this node for the block. The node goes to the next round
+// on timeout and joins the later round in which more than 1/3 of the full nodes vote
+func currentRound(blockID int64, node *tcpserver.NodeIdentity, logger *log.Entry) (int32, error) {
+	last, err := finality.LastRound(blockID)
+	if err != nil {
+		return 0, err
+	}
+	current, ok := rounds[blockID]
+	if !ok {
+		// the node doesn't return to the earlier round after restart
+		for _, voteType := range []int8{finality.Prevote, finality.Precommit} {
+			own := &model.Vote{}
+			if _, err = own.GetLast(blockID, voteType, node.KeyID); err != nil {
+				logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting vote")
+				return 0, err
+			}
+			if own.Round > last {
+				last = own.Round
+			}
+		}
+	}
+	switch {
+	case !ok || last > current.round:
+		current = &votingRound{round: last, start: time.Now()}
+		rounds[blockID] = current
+	case time.Since(current.start) > roundTimeout:
+		current.round++
+		current.start = time.Now()
+	}
+	return current.round, nil
+}
+
+// isLocked returns true if this node has precommitted another hash for the block and there are no prevotes
+// of more than 2/3 of the full nodes for the hash of the block in the later rounds
+func isLocked(block *model.Block, round int32, node *tcpserver.NodeIdentity, logger *log.Entry) (bool, error) {
+	lock := &model.Vote{}
+	found, err := lock.GetLast(block.ID, finality.Precommit, node.KeyID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": block.ID}).Error("getting vote")
+		return false, err
+	}
+	if !found || bytes.Equal(lock.Hash, block.Hash) {
+		return false, nil
+	}
+	for r := lock.Round + 1; r < round; r++ {
+		count, err := finality.Count(finality.Prevote, block.ID, r, block.Hash)
+		if err != nil {
+			return false, err
+		}
+		if finality.IsQuorum(count) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// voteBlocks signs the prevotes for the blocks of this node and the precommits for the blocks
+// which have got the prevotes of more than 2/3 of the full nodes in the current round.
+// The node votes only once for each block in the round
+func voteBlocks(fromID, toID int64, node *tcpserver.NodeIdentity, logger *log.Entry) error {
+	for blockID := fromID; blockID <= toID; blockID++ {
+		block := &model.Block{}
+		found, err := block.Get(blockID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting block")
+			return err
+		}
+		if !found {
+			break
+		}
+		round, err := currentRound(blockID, node, logger)
+		if err != nil {
+			return err
+		}
+		for _, voteType := range []int8{finality.Prevote, finality.Precommit} {
+			if voteType == finality.Prevote {
+				locked, err := isLocked(block, round, node, logger)
+				if err != nil {
+					return err
+				}
+				if locked {
+					continue
+				}
+			} else {
+				count, err := finality.Count(finality.Prevote, blockID, round, block.Hash)
+				if err != nil {
+					return err
+				}
+				if !finality.IsQuorum(count) {
+					continue
+				}
+			}
+			own := &model.Vote{}
+			if found, err = own.Get(blockID, round, voteType, node.KeyID); err != nil {
+				logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting vote")
+				return err
+			}
+			if found {
+				continue
+			}
+			vote, err := finality.NewVote(voteType, blockID, round, block.Hash, node.KeyID, node.PrivateKey)
+			if err != nil {
+				return err
+			}
+			if err = vote.Create(); err != nil {
+				logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("saving vote")
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exchangeVotes sends the known votes to the host and saves the votes of the host
+func exchangeVotes(host string, fromID int64, node *tcpserver.NodeIdentity, logger *log.Entry) error {
+	votes, err := model.GetVotes(fromID, maxVotesRequest)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting votes")
+		return err
+	}
+	data, err := json.Marshal(votes)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling votes")
+		return err
+	}
+	conn, rw, err := handshakeConn(host, node, logger)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
+	conn.SetWriteDeadline(time.Now().Add(consts.WRITE_TIMEOUT * time.Second))
+	resp := &tcpserver.VotesResponse{}
+	if err = tcpserver.SendRequest(&tcpserver.TransactionType{Type: consts.DATA_TYPE_VOTES}, rw); err == nil {
+		if err = tcpserver.SendRequest(&tcpserver.VotesRequest{FromID: uint32(fromID), Data: data}, rw); err == nil {
+			err = tcpserver.ReadRequest(resp, rw)
+		}
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("exchanging votes")
+		return err
+	}
+	received := make([]model.Vote, 0)
+	if err = json.Unmarshal(resp.Data, &received); err != nil {
+		logger.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err, "host": host}).Error("unmarshalling votes")
+		return err
+	}
+	return finality.AddVotes(received)
+}
+
+// finalizeBlocks finalizes the last block of this node which has got the precommits of more than 2/3
+// of the full nodes in one round
+func finalizeBlocks(fromID, toID int64, logger *log.Entry) error {
+	for blockID := toID; blockID >= fromID; blockID-- {
+		block := &model.Block{}
+		found, err := block.Get(blockID)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting block")
+			return err
+		}
+		if !found {
+			continue
+		}
+		final, err := finality.IsFinal(blockID, block.Hash)
+		if err != nil {
+			return err
+		}
+		if !final {
+			continue
+		}
+		finalized := &model.FinalizedBlock{BlockID: blockID, Hash: block.Hash}
+		if err = finalized.Create(); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("saving finalized block")
+			return err
+		}
+		// the votes for the finalized block are kept as the proof of the finality
+		if err = model.DeleteVotes(blockID); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("deleting votes")
+			return err
+		}
+		logger.WithFields(log.Fields{"block_id": blockID}).Info("block has been finalized")
+		return nil
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package daemons
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
@@ -11,8 +12,6 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var mutex = sync.Mutex{}
-
 // WaitDB waits for the end of the installation
 func WaitDB(ctx context.Context) error {
 	// There is could be the situation when installation is not over yet.
@@ -52,12 +51,167 @@ func CheckDB() bool {
 	return false
 }
 
-// DBLock locks daemons
-func DBLock() {
-	mutex.Lock()
+// ErrLockOwner is returned on the attempt to unlock the lock which is held by another daemon
+var ErrLockOwner = errors.New("lock is held by another daemon")
+
+// lockWaitTimeout is the maximum time of waiting for the lock, the daemon skips the iteration after it
+const lockWaitTimeout = 10 * time.Second
+
+// lockHoldWarning is the time of holding the lock after which the owner is reported
+const lockHoldWarning = 30 * time.Second
+
+// LockStats is the statistics of acquiring the lock by the daemon
+type LockStats struct {
+	Count     int64
+	Timeouts  int64
+	WaitTotal time.Duration
+	WaitMax   time.Duration
+	HoldTotal time.Duration
+	HoldMax   time.Duration
+}
+
+// LockInfo describes the owner of the lock, the daemons which are waiting for it and the statistics
+type LockInfo struct {
+	Owner   string
+	Since   time.Time
+	Waiting map[string]time.Time
+	Stats   map[string]LockStats
+}
+
+// lockManager serialises the daemons which change the blockchain
+type lockManager struct {
+	token   chan struct{}
+	mutex   sync.Mutex
+	owner   string
+	since   time.Time
+	watch   *time.Timer
+	waiting map[string]time.Time
+	stats   map[string]*LockStats
+}
+
+func newLockManager() *lockManager {
+	return &lockManager{
+		token:   make(chan struct{}, 1),
+		waiting: make(map[string]time.Time),
+		stats:   make(map[string]*LockStats),
+	}
+}
+
+var dbLock = newLockManager()
+
+func (lm *lockManager) getStats(owner string) *LockStats {
+	stats, ok := lm.stats[owner]
+	if !ok {
+		stats = &LockStats{}
+		lm.stats[owner] = stats
+	}
+	return stats
+}
+
+// acquired makes owner the owner of the lock
+func (lm *lockManager) acquired(owner string, wait time.Duration) {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	delete(lm.waiting, owner)
+	stats := lm.getStats(owner)
+	stats.Count++
+	stats.WaitTotal += wait
+	if wait > stats.WaitMax {
+		stats.WaitMax = wait
+	}
+	lm.owner = owner
+	lm.since = time.Now()
+	since := lm.since
+	lm.watch = time.AfterFunc(lockHoldWarning, func() {
+		log.WithFields(log.Fields{"type": consts.LockError, "daemon_name": owner, "since": since}).Warning("daemon holds the lock too long")
+	})
+}
+
+// lock waits for the lock until the timeout or the end of the context. It returns false if the lock hasn't been acquired
+func (lm *lockManager) lock(ctx context.Context, owner string, timeout time.Duration) (bool, error) {
+	start := time.Now()
+	select {
+	case lm.token <- struct{}{}:
+		lm.acquired(owner, 0)
+		return true, nil
+	default:
+	}
+	if timeout <= 0 {
+		return false, nil
+	}
+
+	lm.mutex.Lock()
+	lm.waiting[owner] = start
+	lm.mutex.Unlock()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case lm.token <- struct{}{}:
+		lm.acquired(owner, time.Since(start))
+		return true, nil
+	case <-timer.C:
+		lm.mutex.Lock()
+		delete(lm.waiting, owner)
+		lm.getStats(owner).Timeouts++
+		holder := lm.owner
+		lm.mutex.Unlock()
+		log.WithFields(log.Fields{"type": consts.LockError, "daemon_name": owner, "owner": holder}).Warning("timeout of waiting for the lock")
+		return false, nil
+	case <-ctx.Done():
+		lm.mutex.Lock()
+		delete(lm.waiting, owner)
+		lm.mutex.Unlock()
+		return false, ctx.Err()
+	}
+}
+
+// unlock releases the lock if it is held by owner
+func (lm *lockManager) unlock(owner string) error {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	if lm.owner != owner {
+		log.WithFields(log.Fields{"type": consts.LockError, "daemon_name": owner, "owner": lm.owner}).Error("unlocking the lock of another daemon")
+		return ErrLockOwner
+	}
+	lm.watch.Stop()
+	hold := time.Since(lm.since)
+	stats := lm.getStats(owner)
+	stats.HoldTotal += hold
+	if hold > stats.HoldMax {
+		stats.HoldMax = hold
+	}
+	lm.owner = ``
+	lm.since = time.Time{}
+	<-lm.token
+	return nil
+}
+
+func (lm *lockManager) info() *LockInfo {
+	lm.mutex.Lock()
+	defer lm.mutex.Unlock()
+	info := &LockInfo{Owner: lm.owner, Since: lm.since, Waiting: make(map[string]time.Time),
+		Stats: make(map[string]LockStats)}
+	for owner, since := range lm.waiting {
+		info.Waiting[owner] = since
+	}
+	for owner, stats := range lm.stats {
+		info.Stats[owner] = *stats
+	}
+	return info
+}
+
+// DBLock waits for the lock of the daemons which change the blockchain. It returns false
+// if the lock hasn't been acquired in lockWaitTimeout, the daemon should try again in the next iteration
+func DBLock(ctx context.Context, owner string) (bool, error) {
+	return dbLock.lock(ctx, owner, lockWaitTimeout)
+}
+
+// DBUnlock releases the lock which is held by owner
+func DBUnlock(owner string) error {
+	return dbLock.unlock(owner)
 }
 
-// DBUnlock unlocks database
-func DBUnlock() {
-	mutex.Unlock()
+// GetLockInfo returns the owner of the lock, the waiting daemons and the statistics of the lock
+func GetLockInfo() *LockInfo {
+	return dbLock.info()
 }
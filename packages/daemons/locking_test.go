@@ -15,12 +15,6 @@ import (
 
 func createTables(t *testing.T, db *sql.DB) {
 	sql := `
-	CREATE TABLE "main_lock" (
-		"lock_time" integer NOT NULL DEFAULT '0',
-		"script_name" string NOT NULL DEFAULT '',
-		"info" text NOT NULL DEFAULT '',
-		"uniq" integer NOT NULL DEFAULT '0'
-	);
 	CREATE TABLE "install" (
 		"progress" text NOT NULL DEFAULT ''
 	);
@@ -33,11 +27,8 @@ func createTables(t *testing.T, db *sql.DB) {
 }
 
 func TestLock(t *testing.T) {
-	db := initGorm(t)
-	createTables(t, db.DB())
-
-	ctx, _ := context.WithTimeout(context.Background(), 200*time.Millisecond)
-	defer ctx.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
 
 	ok, err := DBLock(ctx, "test")
 	if err != nil {
@@ -47,7 +38,7 @@ func TestLock(t *testing.T) {
 		t.Errorf("can't lock")
 	}
 
-	ok, err = tryLock("test2")
+	ok, err = dbLock.lock(context.Background(), "test2", 0)
 	if err != nil {
 		t.Errorf("lock returned %s", err)
 	}
@@ -55,39 +46,23 @@ func TestLock(t *testing.T) {
 		t.Errorf("lock should fail")
 	}
 
-	ml := &model.MainLock{}
-	err = ml.Get()
-	if err != nil {
-		t.Fatalf("Get main_lock failed: %s", err)
-	}
-	if ml.ScriptName != "test" {
-		t.Errorf("bad script_name: want test, got %s", ml.ScriptName)
+	info := GetLockInfo()
+	if info.Owner != "test" || info.Since.IsZero() {
+		t.Errorf("bad owner: want test, got %s", info.Owner)
 	}
 
-	time.Sleep(1 * time.Second)
-	err = UpdMainLock()
-	if err != nil {
-		t.Fatalf("update main lock failed: %s", err)
-	}
-	ml2 := &model.MainLock{}
-	err = ml2.Get()
-	if err != nil {
-		t.Fatalf("Get main_lock failed: %s", err)
-	}
-	if ml2.ScriptName != "test" {
-		t.Errorf("bad script_name: want test, got %s", ml.ScriptName)
+	// the lock is held till the end of the context
+	ok, err = DBLock(ctx, "test2")
+	if ok || err != context.DeadlineExceeded {
+		t.Errorf("lock should fail by context: %v", err)
 	}
-	if ml2.LockTime == ml.LockTime {
-		t.Errorf("UpdMainLock didn't change the lock time")
+	if err = DBUnlock("test"); err != nil {
+		t.Errorf("DBUnlock error: %s", err)
 	}
-
 }
 
 func TestUnlock(t *testing.T) {
-	db := initGorm(t)
-	createTables(t, db.DB())
-
-	ok, err := tryLock("test")
+	ok, err := dbLock.lock(context.Background(), "test", 0)
 	if err != nil {
 		t.Errorf("lock returned %s", err)
 	}
@@ -97,12 +72,11 @@ func TestUnlock(t *testing.T) {
 	}
 
 	// try another goroutine name
-	err = DBUnlock("some_another_name")
-	if err != nil {
-		t.Errorf("DBUnlock error: %s", err)
+	if err = DBUnlock("some_another_name"); err != ErrLockOwner {
+		t.Errorf("DBUnlock error: %v", err)
 	}
 
-	ok, err = tryLock("some_another_name")
+	ok, err = dbLock.lock(context.Background(), "some_another_name", 0)
 	if err != nil {
 		t.Errorf("lock returned %s", err)
 	}
@@ -117,7 +91,7 @@ func TestUnlock(t *testing.T) {
 		t.Errorf("DBUnlock error: %s", err)
 	}
 
-	ok, err = tryLock("some_another_name")
+	ok, err = dbLock.lock(context.Background(), "some_another_name", 0)
 	if err != nil {
 		t.Errorf("lock returned %s", err)
 	}
@@ -125,7 +99,41 @@ func TestUnlock(t *testing.T) {
 	if !ok {
 		t.Errorf("lock failed")
 	}
+	DBUnlock("some_another_name")
+}
 
+func TestLockWait(t *testing.T) {
+	lm := newLockManager()
+	if ok, _ := lm.lock(context.Background(), "holder", 0); !ok {
+		t.Fatalf("can't lock")
+	}
+	if ok, err := lm.lock(context.Background(), "waiter", 50*time.Millisecond); ok || err != nil {
+		t.Errorf("lock should fail by timeout: %v", err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		if info := lm.info(); len(info.Waiting) != 1 {
+			t.Errorf("wrong waiting daemons %v", info.Waiting)
+		}
+		lm.unlock("holder")
+	}()
+	if ok, err := lm.lock(context.Background(), "waiter", time.Second); !ok || err != nil {
+		t.Fatalf("lock failed: %v", err)
+	}
+	lm.unlock("waiter")
+
+	info := lm.info()
+	if info.Owner != "" || len(info.Waiting) != 0 {
+		t.Errorf("lock is not free: %s %v", info.Owner, info.Waiting)
+	}
+	waiter := info.Stats["waiter"]
+	if waiter.Count != 1 || waiter.Timeouts != 1 || waiter.WaitMax < 50*time.Millisecond {
+		t.Errorf("wrong waiter statistics %+v", waiter)
+	}
+	if holder := info.Stats["holder"]; holder.Count != 1 || holder.HoldMax < 50*time.Millisecond {
+		t.Errorf("wrong holder statistics %+v", holder)
+	}
 }
 
 func TestWait(t *testing.T) {
@@ -0,0 +1,75 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"sync/atomic"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/metrics"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// peersBlockID is the maximum block id of the peers which has been got by the last blocks collection
+var peersBlockID int64
+
+var (
+	_ = metrics.NewGaugeFunc(`apla_block_height`, `The id of the last block of the node`, blockHeight)
+	_ = metrics.NewGaugeFunc(`apla_peers_block_height`, `The maximum id of the last block of the peers`,
+		func() (float64, error) {
+			return float64(atomic.LoadInt64(&peersBlockID)), nil
+		})
+	_ = metrics.NewGaugeFunc(`apla_block_lag`, `The number of the blocks which the node is behind the peers`,
+		func() (float64, error) {
+			height, err := blockHeight()
+			if err != nil {
+				return 0, err
+			}
+			if lag := float64(atomic.LoadInt64(&peersBlockID)) - height; lag > 0 {
+				return lag, nil
+			}
+			return 0, nil
+		})
+	_ = metrics.NewGaugeFunc(`apla_queue_tx`, `The number of the transactions in the queue`, queueSize(`queue_tx`))
+	_ = metrics.NewGaugeFunc(`apla_queue_blocks`, `The number of the blocks in the queue`, queueSize(`queue_blocks`))
+
+	blockGenerationTime = metrics.NewHistogram(`apla_block_generation_seconds`,
+		`The time of the generation of the block by the node`, metrics.DefBuckets)
+	blocksGenerated = metrics.NewCounter(`apla_blocks_generated_total`,
+		`The number of the blocks which have been generated by the node`)
+)
+
+func blockHeight() (float64, error) {
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block for metrics")
+		return 0, err
+	}
+	return float64(infoBlock.BlockID), nil
+}
+
+func queueSize(table string) func() (float64, error) {
+	return func() (float64, error) {
+		count, err := model.GetRecordsCount(table)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table}).Error("getting queue size for metrics")
+		}
+		return float64(count), err
+	}
+}
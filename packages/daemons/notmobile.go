@@ -70,15 +70,7 @@ func WaitForSignals() {
 		signal.Notify(SigChan, os.Interrupt, os.Kill, Term)
 		<-SigChan
 
-		if utils.CancelFunc != nil {
-			utils.CancelFunc()
-			for i := 0; i < utils.DaemonsCount; i++ {
-				name := <-utils.ReturnCh
-				log.WithFields(log.Fields{"daemon_name": name}).Debug("daemon stopped")
-			}
-
-			log.Debug("Daemons killed")
-		}
+		stopDaemons()
 
 		system.FinishThrust()
 
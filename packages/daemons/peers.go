@@ -0,0 +1,193 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/parser"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// peerBanTime is the duration of the ban of the peer
+	peerBanTime = time.Hour
+	// peerBanPenalty is the decrease of the score of the banned peer
+	peerBanPenalty = 10
+	maxPeerScore   = 100
+	// minPeerScore is the score below which the peer is removed from the address book
+	minPeerScore = -50
+	// maxPeers is the maximum size of the address book
+	maxPeers = 1000
+	// peersAddCount is the maximum count of the new addresses which are accepted from one peer at once
+	peersAddCount = 16
+	// peersExchangeCount is the count of the peers which are asked for addresses at once
+	peersExchangeCount = 8
+	// syncPeersCount is the count of the peers which are used to download blocks in addition to the full nodes
+	syncPeersCount = 16
+)
+
+// PeerDiscovery exchanges the addresses of the peers with the full nodes and the peers of the address book
+func PeerDiscovery(d *daemon, ctx context.Context) error {
+	d.sleepTime = time.Minute
+	if err := model.UnbanExpiredPeers(); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("unbanning peers")
+		return err
+	}
+	if err := model.DeleteBadPeers(minPeerScore); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("deleting bad peers")
+		return err
+	}
+	self := ``
+	if len(*utils.TCPHost) > 0 {
+		self = tcpserver.PeerAddress(*utils.TCPHost)
+	}
+	for _, host := range getPeerHosts(peersExchangeCount, d.logger) {
+		if ctx.Err() != nil {
+			d.logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
+			return ctx.Err()
+		}
+		list, err := requestPeers(host, self, d.logger)
+		if err != nil {
+			model.UpdatePeerScore(host, -1, maxPeerScore)
+			continue
+		}
+		model.UpdatePeerScore(host, 1, maxPeerScore)
+		if err = addPeers(list, self, d.logger); err != nil {
+			d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("adding peers")
+			return err
+		}
+	}
+	return nil
+}
+
+// requestPeers gets the addresses of the peers from the host and sends the address of this node
+func requestPeers(host, self string, logger *log.Entry) ([]string, error) {
+	conn, err := utils.TCPConn(host)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("tcp connection to host")
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
+	conn.SetWriteDeadline(time.Now().Add(consts.WRITE_TIMEOUT * time.Second))
+	resp := &tcpserver.PeersResponse{}
+	if err = tcpserver.SendRequest(&tcpserver.TransactionType{Type: consts.DATA_TYPE_PEERS}, conn); err == nil {
+		if err = tcpserver.SendRequest(&tcpserver.PeersRequest{Host: []byte(self)}, conn); err == nil {
+			err = tcpserver.ReadRequest(resp, conn)
+		}
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("requesting peers")
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(resp.Data), `,`), nil
+}
+
+// addPeers adds no more than peersAddCount valid addresses into the address book while it isn't full.
+// The unknown address is added only if the node answers on it
+func addPeers(list []string, self string, logger *log.Entry) error {
+	count, err := model.GetRecordsCount(model.Peer{}.TableName())
+	if err != nil {
+		return err
+	}
+	hosts := make([]string, 0, peersAddCount)
+	for _, addr := range list {
+		if int(count)+len(hosts) >= maxPeers || len(hosts) >= peersAddCount {
+			break
+		}
+		if !isPeerAddress(addr) || addr == self {
+			continue
+		}
+		peer := &model.Peer{}
+		if found, err := peer.Get(addr); err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "host": addr}).Error("getting peer")
+			return err
+		} else if found {
+			continue
+		}
+		if _, err := getHostBlockID(addr, logger); err != nil {
+			continue
+		}
+		hosts = append(hosts, addr)
+	}
+	return model.AddPeers(hosts)
+}
+
+// isPeerAddress returns true if the address is host:port with the valid port
+func isPeerAddress(addr string) bool {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || len(host) == 0 || len(host) > 253 || strings.ContainsAny(host, ` /,`) {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil && (ip.IsUnspecified() || ip.IsMulticast()) {
+		return false
+	}
+	value, err := strconv.Atoi(port)
+	return err == nil && value > 0 && value <= 65535
+}
+
+// getPeerHosts returns the full nodes and the best peers of the address book except the banned ones.
+// The full nodes are always returned even if they are banned
+func getPeerHosts(limit int, logger *log.Entry) []string {
+	peers, err := model.GetPeers(limit)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting peers")
+	}
+	hosts := make([]string, 0, len(peers))
+	added := make(map[string]bool)
+	for _, host := range syspar.GetHosts() {
+		addr := tcpserver.PeerAddress(host)
+		if !added[addr] {
+			hosts = append(hosts, addr)
+			added[addr] = true
+		}
+	}
+	for _, peer := range peers {
+		if !added[peer.Host] {
+			hosts = append(hosts, peer.Host)
+			added[peer.Host] = true
+		}
+	}
+	return hosts
+}
+
+// banNode bans the host for peerBanTime if err proves that the host has sent the invalid block.
+// The banned host isn't used for downloading blocks and peer exchange unless it is the full node
+func banNode(host string, err error) {
+	if !parser.IsInvalidBlock(err) {
+		return
+	}
+	addr := tcpserver.PeerAddress(host)
+	log.WithFields(log.Fields{"type": consts.BlockError, "error": err, "host": addr}).Warning("banning peer")
+	if err := model.BanPeer(addr, time.Now().Add(peerBanTime).Unix(), err.Error(), peerBanPenalty); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "host": addr}).Error("banning peer")
+	}
+}
@@ -0,0 +1,71 @@
+package daemons
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestRequestPeers(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		dType := &tcpserver.TransactionType{}
+		req := &tcpserver.PeersRequest{}
+		if tcpserver.ReadRequest(dType, conn) != nil || dType.Type != consts.DATA_TYPE_PEERS ||
+			tcpserver.ReadRequest(req, conn) != nil {
+			return
+		}
+		received <- string(req.Host)
+		tcpserver.SendRequest(&tcpserver.PeersResponse{Data: []byte(`10.0.0.1:7078,10.0.0.2:7078`)}, conn)
+	}()
+
+	list, err := requestPeers(l.Addr().String(), `10.0.0.3:7078`, log.WithFields(log.Fields{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(list, []string{`10.0.0.1:7078`, `10.0.0.2:7078`}) {
+		t.Errorf("wrong peers %v", list)
+	}
+	if host := <-received; host != `10.0.0.3:7078` {
+		t.Errorf("wrong address of client %s", host)
+	}
+	if addr := tcpserver.PeerAddress(`10.0.0.4`); addr != `10.0.0.4:`+consts.TCP_PORT {
+		t.Errorf("wrong address %s", addr)
+	}
+}
+
+func TestIsPeerAddress(t *testing.T) {
+	cases := map[string]bool{
+		`10.0.0.1:7078`:     true,
+		`node.example:7078`: true,
+		`[::1]:7078`:        true,
+		`10.0.0.1`:          false,
+		`10.0.0.1:0`:        false,
+		`10.0.0.1:70780`:    false,
+		`:7078`:             false,
+		`0.0.0.0:7078`:      false,
+		`224.0.0.1:7078`:    false,
+		`a b:7078`:          false,
+		`10.0.0.1:http`:     false,
+	}
+	for addr, valid := range cases {
+		if isPeerAddress(addr) != valid {
+			t.Errorf("wrong result for %s", addr)
+		}
+	}
+}
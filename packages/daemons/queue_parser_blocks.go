@@ -41,8 +41,10 @@ import (
 // QueueParserBlocks parses and applies blocks from the queue
 func QueueParserBlocks(d *daemon, ctx context.Context) error {
 
-	DBLock()
-	defer DBUnlock()
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	defer DBUnlock(d.goRoutineName)
 
 	infoBlock := &model.InfoBlock{}
 	_, err := infoBlock.Get()
@@ -20,6 +20,7 @@ import (
 	"context"
 
 	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/mempool"
 	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/parser"
 
@@ -28,8 +29,10 @@ import (
 
 // QueueParserTx parses transaction from the queue
 func QueueParserTx(d *daemon, ctx context.Context) error {
-	DBLock()
-	defer DBUnlock()
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	defer DBUnlock(d.goRoutineName)
 
 	infoBlock := &model.InfoBlock{}
 	_, err := infoBlock.Get()
@@ -49,6 +52,10 @@ func QueueParserTx(d *daemon, ctx context.Context) error {
 		return err
 	}
 
+	if err = mempool.EvictExpired(); err != nil {
+		return err
+	}
+
 	p := new(parser.Parser)
 	err = p.AllTxParser()
 	if err != nil {
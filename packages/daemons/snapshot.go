@@ -0,0 +1,295 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/parser"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// snapshotRetries is the count of the attempts to resume the download of the snapshot from one host
+	snapshotRetries = 3
+	// snapshotInfo requests only the description of the last snapshot of the host
+	snapshotInfo = math.MaxUint32
+)
+
+var (
+	errSnapshotNotFound = errors.New("trusted snapshot is not found")
+	errSnapshotHash     = errors.New("hash of snapshot must be specified by snapshotHash flag")
+)
+
+// Snapshots makes the snapshot of the state if the last block is a snapshot block.
+// The state is read in the transaction which is started under the lock, so the blocks
+// are played while the snapshot is being written. Only the hash of the state is committed by the block,
+// the snapshot files aren't a part of the consensus and their errors don't affect the blocks
+func Snapshots(d *daemon, ctx context.Context) error {
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		d.logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return err
+	}
+	if !syspar.IsSnapshotBlock(infoBlock.BlockID) {
+		return nil
+	}
+	if last := parser.LastSnapshot(); last != nil && last.BlockID >= infoBlock.BlockID {
+		return nil
+	}
+	if ok, err := DBLock(ctx, d.goRoutineName); !ok {
+		return err
+	}
+	transaction, blockID, err := model.StartSnapshotTransaction()
+	DBUnlock(d.goRoutineName)
+	if err != nil {
+		return err
+	}
+	defer transaction.Rollback()
+	if blockID != infoBlock.BlockID {
+		// the next block has been played, the snapshot of this block is skipped
+		return nil
+	}
+	return parser.MakeSnapshot(transaction, blockID, d.logger)
+}
+
+// snapshotSource is the snapshot which is available on the hosts
+type snapshotSource struct {
+	blockID int64
+	hash    []byte
+	size    uint64
+	hosts   []string
+}
+
+// requestSnapshot sends the snapshot request and reads the description of the snapshot of the host
+func requestSnapshot(host string, req *tcpserver.SnapshotRequest, node *tcpserver.NodeIdentity,
+	logger *log.Entry) (net.Conn, io.ReadWriter, *tcpserver.SnapshotResponse, error) {
+	conn, rw, err := handshakeConn(host, node, logger)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
+	conn.SetWriteDeadline(time.Now().Add(consts.WRITE_TIMEOUT * time.Second))
+	resp := &tcpserver.SnapshotResponse{}
+	if err = tcpserver.SendRequest(&tcpserver.TransactionType{Type: consts.DATA_TYPE_SNAPSHOT}, rw); err == nil {
+		if err = tcpserver.SendRequest(req, rw); err == nil {
+			err = tcpserver.ReadRequest(resp, rw)
+		}
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host}).Error("requesting snapshot")
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	return conn, rw, resp, nil
+}
+
+// findSnapshot asks the hosts about their last snapshots and returns the trusted one.
+// The snapshot of the host is taken into account only if it matches the hash committed by the blockchain
+// of the host. The snapshot is trusted if it matches trustedHash or more than half of the hosts have it
+func findSnapshot(hosts []string, trustedID int64, trustedHash []byte, node *tcpserver.NodeIdentity,
+	logger *log.Entry) (*snapshotSource, error) {
+	var best *snapshotSource
+	sources := make(map[string]*snapshotSource)
+	for _, host := range hosts {
+		conn, _, resp, err := requestSnapshot(host, &tcpserver.SnapshotRequest{BlockID: snapshotInfo}, node, logger)
+		if err != nil {
+			continue
+		}
+		conn.Close()
+		if resp.BlockID == 0 {
+			continue
+		}
+		if resp.BlockID != resp.CommittedID || !bytes.Equal(resp.Hash, resp.CommittedHash) {
+			logger.WithFields(log.Fields{"type": consts.InvalidObject, "host": host, "block_id": resp.BlockID,
+				"committed_id": resp.CommittedID}).Warning("snapshot doesn't match committed hash")
+			continue
+		}
+		key := model.FormatSnapshotHash(int64(resp.BlockID), resp.Hash)
+		src, ok := sources[key]
+		if !ok {
+			src = &snapshotSource{blockID: int64(resp.BlockID), hash: resp.Hash, size: resp.Size}
+			sources[key] = src
+		}
+		src.hosts = append(src.hosts, host)
+		if best == nil || len(src.hosts) > len(best.hosts) {
+			best = src
+		}
+	}
+	if len(trustedHash) > 0 {
+		best = sources[model.FormatSnapshotHash(trustedID, trustedHash)]
+	} else if best != nil && len(best.hosts)*2 <= len(hosts) {
+		logger.WithFields(log.Fields{"type": consts.NotFound, "block_id": best.blockID, "hosts": len(best.hosts)}).Error("snapshot is not confirmed by the majority of hosts")
+		best = nil
+	}
+	if best == nil {
+		return nil, errSnapshotNotFound
+	}
+	return best, nil
+}
+
+// receiveSnapshot downloads the rest of the snapshot from the host starting with offset
+func receiveSnapshot(host string, src *snapshotSource, w io.Writer, offset *uint64, node *tcpserver.NodeIdentity,
+	logger *log.Entry) error {
+	conn, rw, resp, err := requestSnapshot(host, &tcpserver.SnapshotRequest{BlockID: uint32(src.blockID), Offset: *offset}, node, logger)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if int64(resp.BlockID) != src.blockID || !bytes.Equal(resp.Hash, src.hash) || resp.Size != src.size {
+		return fmt.Errorf("host %s has another snapshot", host)
+	}
+	for {
+		conn.SetReadDeadline(time.Now().Add(consts.READ_TIMEOUT * time.Second))
+		chunk := &tcpserver.SnapshotChunk{}
+		if err = tcpserver.ReadRequest(chunk, rw); err != nil {
+			return err
+		}
+		if len(chunk.Data) == 0 {
+			break
+		}
+		if *offset+uint64(len(chunk.Data)) > src.size {
+			return fmt.Errorf("host %s has sent too much data of snapshot", host)
+		}
+		if _, err = w.Write(chunk.Data); err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing snapshot file")
+			return err
+		}
+		*offset += uint64(len(chunk.Data))
+	}
+	if *offset != src.size {
+		return fmt.Errorf("host %s has sent incomplete snapshot", host)
+	}
+	return nil
+}
+
+// downloadSnapshot downloads the snapshot into the file.
+// The download is resumed from the received size on the failure of the connection or on the next host
+func downloadSnapshot(ctx context.Context, src *snapshotSource, fileName string, node *tcpserver.NodeIdentity,
+	logger *log.Entry) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("creating snapshot file")
+		return err
+	}
+	defer file.Close()
+	var offset uint64
+	for _, host := range src.hosts {
+		for i := 0; i < snapshotRetries; i++ {
+			if ctx.Err() != nil {
+				logger.WithFields(log.Fields{"type": consts.ContextError, "error": ctx.Err()}).Error("context error")
+				return ctx.Err()
+			}
+			if err = receiveSnapshot(host, src, file, &offset, node, logger); err == nil {
+				return nil
+			}
+			logger.WithFields(log.Fields{"type": consts.ConnectionError, "error": err, "host": host, "offset": offset}).Warning("resuming download of snapshot")
+		}
+	}
+	return err
+}
+
+// loadSnapshot restores the state from the snapshot file or from the snapshot downloaded from the hosts.
+// source is the name of the file or the list of the hosts separated by commas
+func loadSnapshot(ctx context.Context, source string, logger *log.Entry) error {
+	var (
+		trustedID   int64
+		trustedHash []byte
+		err         error
+	)
+	if len(*utils.SnapshotHash) > 0 {
+		if trustedID, trustedHash, err = model.ParseSnapshotHash(*utils.SnapshotHash); err != nil {
+			logger.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("parsing snapshotHash flag")
+			return err
+		}
+	}
+	fileName := source
+	if _, err = os.Stat(source); len(source) == 0 || err != nil {
+		hosts := syspar.GetHosts()
+		if len(source) > 0 {
+			hosts = strings.Split(source, `,`)
+		}
+		node, err := tcpserver.LocalIdentity()
+		if err != nil {
+			return err
+		}
+		src, err := findSnapshot(hosts, trustedID, trustedHash, node, logger)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(parser.SnapshotDir(), 0775); err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("creating snapshot directory")
+			return err
+		}
+		fileName = filepath.Join(parser.SnapshotDir(), `download.tmp`)
+		defer os.Remove(fileName)
+		if err = downloadSnapshot(ctx, src, fileName, node, logger); err != nil {
+			return err
+		}
+		trustedID, trustedHash = src.blockID, src.hash
+	}
+	if len(trustedHash) == 0 {
+		logger.WithFields(log.Fields{"type": consts.EmptyObject, "file_name": fileName}).Error("loading snapshot without trusted hash")
+		return errSnapshotHash
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("opening snapshot file")
+		return err
+	}
+	block, err := model.RestoreSnapshot(file, trustedHash)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	if block.ID != trustedID {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "block_id": block.ID, "trusted_id": trustedID}).Error("wrong block of snapshot")
+		return errSnapshotNotFound
+	}
+	if err = syspar.SysUpdate(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
+		return err
+	}
+	if err = smart.LoadContracts(nil); err != nil {
+		return err
+	}
+	if fileName != source {
+		// the downloaded snapshot is served to other nodes
+		if err = os.Rename(fileName, parser.SnapshotFileName(block.ID, trustedHash)); err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Warning("saving downloaded snapshot")
+		}
+	}
+	logger.WithFields(log.Fields{"block_id": block.ID}).Info("state has been restored from snapshot")
+	return nil
+}
@@ -0,0 +1,99 @@
+package daemons
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/tcpserver"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshotServer serves the snapshot of the block by chunks of 4 bytes and drops the first download after one chunk
+func snapshotServer(t *testing.T, blockID uint32, data []byte, drop bool) (string, func()) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := bytes.Repeat([]byte{byte(blockID)}, 32)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				dType := &tcpserver.TransactionType{}
+				req := &tcpserver.SnapshotRequest{}
+				if tcpserver.ReadRequest(dType, conn) != nil || dType.Type != consts.DATA_TYPE_SNAPSHOT ||
+					tcpserver.ReadRequest(req, conn) != nil {
+					return
+				}
+				tcpserver.SendRequest(&tcpserver.SnapshotResponse{BlockID: blockID, Hash: hash, Size: uint64(len(data)),
+					CommittedID: blockID, CommittedHash: hash}, conn)
+				if req.BlockID != blockID {
+					return
+				}
+				for off := req.Offset; off < uint64(len(data)); off += 4 {
+					end := off + 4
+					if end > uint64(len(data)) {
+						end = uint64(len(data))
+					}
+					tcpserver.SendRequest(&tcpserver.SnapshotChunk{Data: data[off:end]}, conn)
+					if drop && req.Offset == 0 {
+						return
+					}
+				}
+				tcpserver.SendRequest(&tcpserver.SnapshotChunk{}, conn)
+			}(conn)
+		}
+	}()
+	return l.Addr().String(), func() { l.Close() }
+}
+
+func TestSnapshotDownload(t *testing.T) {
+	logger := log.WithFields(log.Fields{})
+	data := []byte(`{"snapshot":1,"block_id":10}`)
+	hostA, stopA := snapshotServer(t, 10, data, true)
+	defer stopA()
+	hostB, stopB := snapshotServer(t, 10, data, false)
+	defer stopB()
+	hostC, stopC := snapshotServer(t, 20, []byte(`other`), false)
+	defer stopC()
+
+	src, err := findSnapshot([]string{hostA, hostB, hostC}, 0, nil, nil, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.blockID != 10 || len(src.hosts) != 2 {
+		t.Errorf("wrong snapshot %d from %v", src.blockID, src.hosts)
+	}
+	if _, err = findSnapshot([]string{hostA, hostC}, 0, nil, nil, logger); err != errSnapshotNotFound {
+		t.Errorf("snapshot without majority: %v", err)
+	}
+	trusted, err := findSnapshot([]string{hostA, hostB, hostC}, 20, bytes.Repeat([]byte{20}, 32), nil, logger)
+	if err != nil || trusted.blockID != 20 {
+		t.Errorf("trusted snapshot: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "download.tmp")
+	src.hosts = []string{hostA}
+	if err = downloadSnapshot(context.Background(), src, fileName, nil, logger); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := ioutil.ReadFile(fileName); !bytes.Equal(out, data) {
+		t.Errorf("wrong snapshot file %s", out)
+	}
+}
@@ -18,6 +18,7 @@ package daemons
 
 import (
 	"os"
+	"sync"
 	"time"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -28,6 +29,24 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+var stopOnce sync.Once
+
+// stopDaemons cancels the context of the daemons and waits for them. The daemons which are waiting
+// for the lock stop at once, so the stop doesn't depend on the daemon holding the lock
+func stopDaemons() {
+	stopOnce.Do(func() {
+		if utils.CancelFunc == nil {
+			return
+		}
+		utils.CancelFunc()
+		for i := 0; i < utils.DaemonsCount; i++ {
+			name := <-utils.ReturnCh
+			log.WithFields(log.Fields{"daemon_name": name}).Debug("daemon stopped")
+		}
+		log.Debug("Daemons killed")
+	})
+}
+
 // WaitStopTime closes the database and stop daemons
 func WaitStopTime() {
 	var first bool
@@ -48,11 +67,7 @@ func WaitStopTime() {
 			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting stop_time from StopDaemons")
 		}
 		if dExists > 0 {
-			utils.CancelFunc()
-			for i := 0; i < utils.DaemonsCount; i++ {
-				name := <-utils.ReturnCh
-				log.WithFields(log.Fields{"daemon_name": name}).Debug("daemon stopped")
-			}
+			stopDaemons()
 			system.FinishThrust()
 
 			err := model.GormClose()
@@ -37,6 +37,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/daylight/daemonsctl"
 	"github.com/AplaProject/go-apla/packages/language"
 	logtools "github.com/AplaProject/go-apla/packages/log"
+	"github.com/AplaProject/go-apla/packages/metrics"
 	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/parser"
 	"github.com/AplaProject/go-apla/packages/smart"
@@ -240,6 +241,7 @@ func setRoute(route *httprouter.Router, path string, handle func(http.ResponseWr
 func initRoutes(listenHost, browserHost string) string {
 	route := httprouter.New()
 	setRoute(route, `/monitoring`, daemons.Monitoring, `GET`)
+	setRoute(route, `/metrics`, metrics.Handler, `GET`)
 	apiv2.Route(route)
 	route.Handler(`GET`, `/static/*filepath`, http.FileServer(&assetfs.AssetFS{Asset: FileAsset, AssetDir: static.AssetDir, Prefix: ""}))
 	route.Handler(`GET`, `/.well-known/*filepath`, http.FileServer(http.Dir(*utils.TLS)))
@@ -288,6 +290,9 @@ func Start(dir string, thrustWindowLoder *window.Window) {
 				"db_name": config.ConfigIni["db_name"], "type": consts.DBError}).Error("can't init gorm")
 			Exit(1)
 		}
+		if err = model.Migrate(); err != nil {
+			Exit(1)
+		}
 	}
 
 	// create first block
@@ -0,0 +1,172 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package finality
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/crypto"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Prevote is the vote of the full node which has accepted the block
+	Prevote = 1
+	// Precommit is the vote of the full node which has got the prevotes of more than 2/3 of the full nodes
+	// in the round. The block is final when more than 2/3 of the full nodes have sent precommits in one round
+	Precommit = 2
+
+	hashSize = 32
+)
+
+// ErrVote is returned if the vote is incorrect
+var ErrVote = errors.New("incorrect vote")
+
+// forSign returns the data which is signed by the full node
+func forSign(vote *model.Vote) string {
+	return fmt.Sprintf("vote,%d,%d,%d,%x,%d", vote.Type, vote.BlockID, vote.Round, vote.Hash, vote.KeyID)
+}
+
+// NewVote returns the vote in the round signed by the private key of the full node
+func NewVote(voteType int8, blockID int64, round int32, hash []byte, keyID int64, privateKey string) (*model.Vote, error) {
+	vote := &model.Vote{BlockID: blockID, Round: round, Type: voteType, KeyID: keyID, Hash: hash}
+	signature, err := crypto.Sign(privateKey, forSign(vote))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("signing vote")
+		return nil, err
+	}
+	vote.Signature = signature
+	return vote, nil
+}
+
+// Verify checks that the vote has been signed by the current full node
+func Verify(vote *model.Vote) error {
+	if (vote.Type != Prevote && vote.Type != Precommit) || len(vote.Hash) != hashSize || vote.BlockID <= 0 ||
+		vote.Round < 0 {
+		return ErrVote
+	}
+	node := syspar.GetNode(vote.KeyID)
+	if node == nil {
+		return ErrVote
+	}
+	ok, err := crypto.CheckSign(node.Public, forSign(vote), vote.Signature)
+	if err != nil || !ok {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err, "key_id": vote.KeyID}).Error("checking signature of vote")
+		return ErrVote
+	}
+	return nil
+}
+
+// AddVotes verifies and saves the votes for the blocks after the finalized block, the incorrect votes are skipped
+func AddVotes(votes []model.Vote) error {
+	finalizedID, err := model.GetFinalizedBlockID(nil)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting finalized block")
+		return err
+	}
+	for i := range votes {
+		if votes[i].BlockID <= finalizedID {
+			continue
+		}
+		if err = Verify(&votes[i]); err != nil {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "block_id": votes[i].BlockID, "round": votes[i].Round,
+				"key_id": votes[i].KeyID}).Warning("skipping incorrect vote")
+			continue
+		}
+		if err = votes[i].Create(); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("saving vote")
+			return err
+		}
+	}
+	return nil
+}
+
+// IsQuorum returns true if count is more than 2/3 of the full nodes
+func IsQuorum(count int64) bool {
+	total := syspar.GetNumberOfNodes()
+	return total > 0 && count*3 > total*2
+}
+
+// isOneThird returns true if count is more than 1/3 of the full nodes, at least one of them is honest
+func isOneThird(count int64) bool {
+	total := syspar.GetNumberOfNodes()
+	return total > 0 && count*3 > total
+}
+
+// countNodes returns the count of the current full nodes among the keys
+func countNodes(keys []int64) int64 {
+	var count int64
+	for _, keyID := range keys {
+		if syspar.GetNode(keyID) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Count returns the count of the current full nodes which have sent the votes of the type for the hash of the block
+// in the round
+func Count(voteType int8, blockID int64, round int32, hash []byte) (int64, error) {
+	keys, err := model.GetVoteKeys(blockID, round, voteType, hash)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting votes")
+		return 0, err
+	}
+	return countNodes(keys), nil
+}
+
+// LastRound returns the last round of the voting for the block in which more than 1/3 of the full nodes have voted.
+// The full node which is in the earlier round joins this round
+func LastRound(blockID int64) (int32, error) {
+	rounds, err := model.GetRoundKeys(blockID)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting rounds of votes")
+		return 0, err
+	}
+	var last int32
+	for round, keys := range rounds {
+		if round > last && isOneThird(countNodes(keys)) {
+			last = round
+		}
+	}
+	return last, nil
+}
+
+// IsFinal returns true if more than 2/3 of the full nodes have sent the precommits for the hash of the block
+// in any round
+func IsFinal(blockID int64, hash []byte) (bool, error) {
+	rounds, err := model.GetRoundKeys(blockID)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting rounds of votes")
+		return false, err
+	}
+	for round := range rounds {
+		count, err := Count(Precommit, blockID, round, hash)
+		if err != nil {
+			return false, err
+		}
+		if IsQuorum(count) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,25 @@
+package finality
+
+import (
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/model"
+)
+
+func TestVerify(t *testing.T) {
+	hash := make([]byte, hashSize)
+	for _, vote := range []model.Vote{
+		{BlockID: 1, Type: 3, KeyID: 1, Hash: hash},
+		{BlockID: 1, Type: Prevote, KeyID: 1, Hash: hash[:10]},
+		{BlockID: 0, Type: Precommit, KeyID: 1, Hash: hash},
+		{BlockID: 1, Round: -1, Type: Prevote, KeyID: 1, Hash: hash},
+		{BlockID: 1, Type: Precommit, KeyID: 1, Hash: hash},
+	} {
+		if err := Verify(&vote); err != ErrVote {
+			t.Errorf("vote %v has been accepted", vote)
+		}
+	}
+	if data := forSign(&model.Vote{BlockID: 5, Round: 3, Type: Precommit, KeyID: -7, Hash: []byte{1, 2}}); data != `vote,2,5,3,0102,-7` {
+		t.Errorf("wrong data for sign %s", data)
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package mempool keeps the verified transactions which are waiting for the block.
+// The transactions are ordered by the fee, the number of the pending transactions of one key and
+// the total size of the pool are limited. The pending transaction which hasn't been sent to the other nodes
+// can be replaced by the transaction of the same key with the same nonce and the higher fee
+package mempool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// blockReserve is the size of the block which is reserved for the header and the signature
+	blockReserve = 1024
+	// evictionCandidates is the maximum number of the transactions which can be evicted for the new one
+	evictionCandidates = 100
+)
+
+var (
+	// ErrExpired is returned if the time of the transaction is too old
+	ErrExpired = errors.New("transaction has expired")
+	// ErrKeyLimit is returned if the key has too many pending transactions
+	ErrKeyLimit = errors.New("too many pending transactions of the key")
+	// ErrPoolFull is returned if there is no room for the transaction with such fee
+	ErrPoolFull = errors.New("mempool is full")
+	// ErrUnderpriced is returned if the replacing transaction hasn't got the higher fee
+	ErrUnderpriced = errors.New("replacing transaction has not got the higher fee")
+	// ErrSent is returned if the pending transaction with the same nonce has already been sent to the other nodes
+	ErrSent = errors.New("transaction with the same nonce has already been sent")
+)
+
+// toDecimal returns the decimal value of the fee field or zero if it is incorrect
+func toDecimal(value string) decimal.Decimal {
+	ret, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.New(0, 0)
+	}
+	return ret
+}
+
+// higherFee returns true if the fee of the transaction a is greater than the fee of b
+func higherFee(a, b *model.Transaction) bool {
+	if cmp := toDecimal(a.PayOver).Cmp(toDecimal(b.PayOver)); cmp != 0 {
+		return cmp > 0
+	}
+	return toDecimal(a.MaxSum).Cmp(toDecimal(b.MaxSum)) > 0
+}
+
+// Add puts the verified transaction into the pool. The pending transaction of the key with the same nonce
+// is replaced if it hasn't been sent to the other nodes yet, the other nodes could have already put it
+// into the block. The transactions with the lowest fee are evicted if the pool is full.
+// The checks, the eviction and the insertion are made in one database transaction
+func Add(tx *model.Transaction) error {
+	logger := log.WithFields(log.Fields{"tx_hash": fmt.Sprintf("%x", tx.Hash), "key_id": tx.KeyID})
+	if tx.Time < time.Now().Unix()-consts.MAX_TX_BACK {
+		return ErrExpired
+	}
+	tx.PayOver = toDecimal(tx.PayOver).String()
+	tx.MaxSum = toDecimal(tx.MaxSum).String()
+	tx.Size = int64(len(tx.Data))
+
+	transaction, err := model.StartTransaction()
+	if err != nil {
+		return err
+	}
+	if err = add(transaction, tx, logger); err != nil {
+		transaction.Rollback()
+		return err
+	}
+	if err = transaction.Commit(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing pending transaction")
+		return err
+	}
+	return nil
+}
+
+// add puts the transaction into the pool within the database transaction
+func add(transaction *model.DbTransaction, tx *model.Transaction, logger *log.Entry) error {
+	if err := model.LockPending(transaction); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("locking pending transactions")
+		return err
+	}
+	var replaced *model.Transaction
+	if tx.Nonce != 0 {
+		old := &model.Transaction{}
+		found, err := old.GetPendingByNonce(transaction, tx.KeyID, tx.Nonce)
+		if err != nil {
+			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting pending transaction by nonce")
+			return err
+		}
+		if found && !bytes.Equal(old.Hash, tx.Hash) {
+			if old.Sent != 0 {
+				return ErrSent
+			}
+			if !higherFee(tx, old) {
+				return ErrUnderpriced
+			}
+			replaced = old
+		}
+	}
+
+	count, err := model.GetPendingCount(transaction, tx.KeyID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting count of pending transactions")
+		return err
+	}
+	if replaced != nil {
+		count--
+	}
+	if count >= *utils.MempoolKeyLimit {
+		return ErrKeyLimit
+	}
+
+	evicted, err := makeRoom(transaction, tx, replaced)
+	if err != nil {
+		return err
+	}
+	if replaced != nil {
+		if err = drop(transaction, replaced.Hash, fmt.Sprintf("replaced by %x", tx.Hash)); err != nil {
+			return err
+		}
+	}
+	for _, hash := range evicted {
+		if err = drop(transaction, hash, `evicted from mempool`); err != nil {
+			return err
+		}
+	}
+	if err = tx.Create(transaction); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating pending transaction")
+		return err
+	}
+	return nil
+}
+
+// makeRoom returns the transactions which must be evicted to put the transaction into the pool
+func makeRoom(transaction *model.DbTransaction, tx, replaced *model.Transaction) ([][]byte, error) {
+	_, size, err := model.GetPendingSize(transaction)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting size of pending transactions")
+		return nil, err
+	}
+	if replaced != nil {
+		size -= replaced.Size
+	}
+	need := size + tx.Size - *utils.MempoolMaxSize
+	if need <= 0 {
+		return nil, nil
+	}
+	candidates, err := model.GetEvictionCandidates(transaction, evictionCandidates)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting eviction candidates")
+		return nil, err
+	}
+	var skip []byte
+	if replaced != nil {
+		skip = replaced.Hash
+	}
+	return evictionList(candidates, tx, skip, need)
+}
+
+// evictionList returns the hashes of the candidates with the fee lower than the fee of tx
+// which free at least need bytes. The candidates are in the order from the lowest fee
+func evictionList(candidates []model.Transaction, tx *model.Transaction, skip []byte, need int64) ([][]byte, error) {
+	hashes := make([][]byte, 0)
+	for i := range candidates {
+		if need <= 0 {
+			break
+		}
+		if bytes.Equal(candidates[i].Hash, skip) {
+			continue
+		}
+		if !higherFee(tx, &candidates[i]) {
+			break
+		}
+		hashes = append(hashes, candidates[i].Hash)
+		need -= candidates[i].Size
+	}
+	if need > 0 {
+		return nil, ErrPoolFull
+	}
+	return hashes, nil
+}
+
+// drop removes the pending transaction and writes the reason into its status
+func drop(transaction *model.DbTransaction, hash []byte, reason string) error {
+	if _, err := model.DeleteTransactionByHash(transaction, hash); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("deleting pending transaction")
+		return err
+	}
+	status := &model.TransactionStatus{}
+	if err := status.SetError(transaction, reason, hash); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("setting transaction status error")
+		return err
+	}
+	return nil
+}
+
+// EvictExpired removes the pending transactions which can't get into the block because of their time
+func EvictExpired() error {
+	hashes, err := model.GetExpiredTransactions(time.Now().Unix() - consts.MAX_TX_BACK)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting expired transactions")
+		return err
+	}
+	for _, hash := range hashes {
+		if err = drop(nil, hash, ErrExpired.Error()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BlockTransactions returns the pending transactions for the new block in the order of their fee
+func BlockTransactions() ([]model.Transaction, error) {
+	trs, err := model.GetAllUnusedTransactions()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all unused transactions")
+		return nil, err
+	}
+	return selectForBlock(*trs, syspar.GetMaxTxCount(), syspar.GetMaxBlockUserTx(),
+		syspar.GetMaxBlockSize()-blockReserve), nil
+}
+
+// selectForBlock takes the transactions in their order while the block has room for them with their lengths.
+// The transactions of the key which has got maxUserTx transactions in the block are skipped
+func selectForBlock(trs []model.Transaction, maxCount, maxUserTx int, maxSize int64) []model.Transaction {
+	selected := make([]model.Transaction, 0)
+	userTx := make(map[int64]int)
+	var size int64
+	for _, tx := range trs {
+		if len(selected) >= maxCount {
+			break
+		}
+		txSize := int64(len(converter.EncodeLength(int64(len(tx.Data)))) + len(tx.Data))
+		if userTx[tx.KeyID] >= maxUserTx || size+txSize > maxSize {
+			continue
+		}
+		userTx[tx.KeyID]++
+		size += txSize
+		selected = append(selected, tx)
+	}
+	return selected
+}
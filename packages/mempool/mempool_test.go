@@ -0,0 +1,54 @@
+package mempool
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+)
+
+func pending(hash byte, keyID int64, payOver string, maxSum int64, size int) model.Transaction {
+	return model.Transaction{Hash: []byte{hash}, KeyID: keyID, PayOver: payOver, MaxSum: converter.Int64ToStr(maxSum),
+		Data: make([]byte, size), Size: int64(size)}
+}
+
+func TestHigherFee(t *testing.T) {
+	a, b := pending(1, 1, `2`, 10, 1), pending(2, 1, `1.5`, 100, 1)
+	if !higherFee(&a, &b) || higherFee(&b, &a) {
+		t.Error("payover must be compared first")
+	}
+	b.PayOver = `2`
+	if higherFee(&a, &b) || !higherFee(&b, &a) {
+		t.Error("max_sum must be compared for the equal payover")
+	}
+	b.MaxSum = `10`
+	if higherFee(&a, &b) {
+		t.Error("equal fee can't be higher")
+	}
+}
+
+func TestEvictionList(t *testing.T) {
+	candidates := []model.Transaction{pending(1, 1, `0`, 0, 10), pending(2, 2, `0`, 5, 10), pending(3, 3, `1`, 0, 10)}
+	tx := pending(4, 4, `0`, 10, 10)
+	hashes, err := evictionList(candidates, &tx, []byte{1}, 10)
+	if err != nil || len(hashes) != 1 || !bytes.Equal(hashes[0], []byte{2}) {
+		t.Errorf("wrong eviction %v %v", hashes, err)
+	}
+	if _, err = evictionList(candidates, &tx, nil, 30); err != ErrPoolFull {
+		t.Errorf("transaction with higher fee has been evicted: %v", err)
+	}
+}
+
+func TestSelectForBlock(t *testing.T) {
+	trs := []model.Transaction{pending(1, 1, `5`, 0, 10), pending(2, 1, `4`, 0, 10), pending(3, 2, `3`, 0, 100),
+		pending(4, 3, `2`, 0, 10), pending(5, 4, `1`, 0, 10)}
+	selected := selectForBlock(trs, 3, 1, 50)
+	var hashes []byte
+	for _, tx := range selected {
+		hashes = append(hashes, tx.Hash...)
+	}
+	if !bytes.Equal(hashes, []byte{1, 4, 5}) {
+		t.Errorf("wrong transactions %v", hashes)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package metrics collects the metrics of the node and writes them in the text exposition format of Prometheus
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefBuckets are the default buckets of the histograms of durations in seconds
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	getName() string
+	write(w io.Writer)
+}
+
+var (
+	mutex    sync.Mutex
+	registry = make(map[string]collector)
+)
+
+func register(c collector) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	registry[c.getName()] = c
+}
+
+// metric is the common part of the metrics, the series are identified by the values of the labels
+type metric struct {
+	mutex  sync.Mutex
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (m *metric) getName() string {
+	return m.name
+}
+
+func (m *metric) key(labelValues []string) string {
+	if len(labelValues) != len(m.labels) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "metric": m.name, "labels": labelValues}).Error("wrong number of label values")
+		labelValues = append(labelValues, make([]string, len(m.labels))...)[:len(m.labels)]
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+// series returns the name of the series with the labels and the extra label
+func (m *metric) series(suffix, key string, extra ...string) string {
+	pairs := make([]string, 0, len(m.labels)+1)
+	if len(m.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, fmt.Sprintf(`%s="%s"`, m.labels[i], escapeLabel(value)))
+		}
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[0], extra[1]))
+	}
+	if len(pairs) == 0 {
+		return m.name + suffix
+	}
+	return m.name + suffix + `{` + strings.Join(pairs, `,`) + `}`
+}
+
+func (m *metric) writeHeader(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, strings.Replace(m.help, "\n", ` `, -1), m.name, m.kind)
+}
+
+func escapeLabel(value string) string {
+	value = strings.Replace(value, `\`, `\\`, -1)
+	value = strings.Replace(value, `"`, `\"`, -1)
+	return strings.Replace(value, "\n", `\n`, -1)
+}
+
+func formatValue(value float64) string {
+	if math.IsInf(value, 1) {
+		return `+Inf`
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is the metric which only increases
+type Counter struct {
+	metric
+	values map[string]float64
+}
+
+// NewCounter registers the counter with the names of the labels
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{metric: metric{name: name, help: help, kind: `counter`, labels: labels},
+		values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Add adds the value to the series with the values of the labels
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mutex.Lock()
+	c.values[key] += value
+	c.mutex.Unlock()
+}
+
+// Inc increases the series with the values of the labels by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writeHeader(w)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s %s\n", c.series(``, key), formatValue(c.values[key]))
+	}
+}
+
+// Gauge is the metric which can go up and down
+type Gauge struct {
+	metric
+	values map[string]float64
+}
+
+// NewGauge registers the gauge with the names of the labels
+func NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{metric: metric{name: name, help: help, kind: `gauge`, labels: labels},
+		values: make(map[string]float64)}
+	register(g)
+	return g
+}
+
+// Set sets the value of the series with the values of the labels
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mutex.Lock()
+	g.values[key] = value
+	g.mutex.Unlock()
+}
+
+// Add adds the value to the series with the values of the labels
+func (g *Gauge) Add(value float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mutex.Lock()
+	g.values[key] += value
+	g.mutex.Unlock()
+}
+
+func (g *Gauge) write(w io.Writer) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.writeHeader(w)
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s %s\n", g.series(``, key), formatValue(g.values[key]))
+	}
+}
+
+// GaugeFunc is the gauge which value is got at the moment of collecting. The metric is skipped
+// if the function returns the error
+type GaugeFunc struct {
+	metric
+	value func() (float64, error)
+}
+
+// NewGaugeFunc registers the gauge which gets the value from the function
+func NewGaugeFunc(name, help string, value func() (float64, error)) *GaugeFunc {
+	g := &GaugeFunc{metric: metric{name: name, help: help, kind: `gauge`}, value: value}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w io.Writer) {
+	value, err := g.value()
+	if err != nil {
+		// the function logs the error itself, the metric is skipped
+		return
+	}
+	g.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatValue(value))
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram counts the observations in the buckets
+type Histogram struct {
+	metric
+	buckets []float64
+	values  map[string]*histogramSeries
+}
+
+// NewHistogram registers the histogram with the upper bounds of the buckets and the names of the labels
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{metric: metric{name: name, help: help, kind: `histogram`, labels: labels},
+		buckets: buckets, values: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe adds the value to the series with the values of the labels
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	series, ok := h.values[key]
+	if !ok {
+		series = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = series
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			series.counts[i]++
+		}
+	}
+	series.count++
+	series.sum += value
+}
+
+// ObserveSince adds the seconds since start to the series with the values of the labels
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.writeHeader(w)
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		series := h.values[key]
+		for i, bound := range h.buckets {
+			fmt.Fprintf(w, "%s %d\n", h.series(`_bucket`, key, `le`, formatValue(bound)), series.counts[i])
+		}
+		fmt.Fprintf(w, "%s %d\n", h.series(`_bucket`, key, `le`, `+Inf`), series.count)
+		fmt.Fprintf(w, "%s %s\n", h.series(`_sum`, key), formatValue(series.sum))
+		fmt.Fprintf(w, "%s %d\n", h.series(`_count`, key), series.count)
+	}
+}
+
+// Write writes all metrics in the text exposition format
+func Write(w io.Writer) {
+	mutex.Lock()
+	collectors := make([]collector, 0, len(registry))
+	for _, c := range registry {
+		collectors = append(collectors, c)
+	}
+	mutex.Unlock()
+	sort.Slice(collectors, func(i, j int) bool { return collectors[i].getName() < collectors[j].getName() })
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Handler serves the metrics for Prometheus
+func Handler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	Write(&buf)
+	w.Header().Set(`Content-Type`, `text/plain; version=0.0.4; charset=utf-8`)
+	w.Write(buf.Bytes())
+}
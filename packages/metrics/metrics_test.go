@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	requests := NewCounter(`test_requests_total`, `The number of requests`, `type`)
+	requests.Inc(`7`)
+	requests.Add(2, `7`)
+	requests.Inc(`1"x`)
+	depth := NewGauge(`test_queue`, `The depth of the queue`)
+	depth.Set(5)
+	depth.Add(-2)
+	NewGaugeFunc(`test_height`, `The height`, func() (float64, error) { return 42, nil })
+	NewGaugeFunc(`test_failed`, `The failed gauge`, func() (float64, error) { return 0, fmt.Errorf(`failed`) })
+	latency := NewHistogram(`test_latency_seconds`, `The latency`, []float64{0.1, 1}, `op`)
+	latency.Observe(0.05, `select`)
+	latency.Observe(0.5, `select`)
+	latency.Observe(3, `select`)
+
+	var buf bytes.Buffer
+	Write(&buf)
+	out := buf.String()
+	for _, line := range []string{
+		"# TYPE test_requests_total counter\n",
+		`test_requests_total{type="1\"x"} 1` + "\n",
+		`test_requests_total{type="7"} 3` + "\n",
+		"# TYPE test_queue gauge\ntest_queue 3\n",
+		"test_height 42\n",
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{op="select",le="0.1"} 1` + "\n",
+		`test_latency_seconds_bucket{op="select",le="1"} 2` + "\n",
+		`test_latency_seconds_bucket{op="select",le="+Inf"} 3` + "\n",
+		`test_latency_seconds_sum{op="select"} 3.55` + "\n",
+		`test_latency_seconds_count{op="select"} 3` + "\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("%q is not found in\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `test_failed`) {
+		t.Errorf("failed gauge has been written")
+	}
+	if strings.Index(out, `test_height`) > strings.Index(out, `test_queue`) {
+		t.Errorf("metrics are not sorted")
+	}
+}
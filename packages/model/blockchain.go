@@ -62,3 +62,10 @@ func (b *Block) GetBlocksFrom(startFromID int64, ordering string) ([]Block, erro
 func (b *Block) DeleteById(transaction *DbTransaction, id int64) error {
 	return GetDB(transaction).Where("id = ?", id).Delete(Block{}).Error
 }
+
+// GetBlocksRange returns no more than limit blocks from startID to endID in ascending order
+func GetBlocksRange(startID, endID int64, limit int) ([]Block, error) {
+	blockchain := new([]Block)
+	err := DBConn.Order("id asc").Limit(limit).Where("id >= ? AND id <= ?", startID, endID).Find(&blockchain).Error
+	return *blockchain, err
+}
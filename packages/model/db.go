@@ -42,6 +42,7 @@ func GormInit(user string, pass string, dbName string) error {
 		DBConn = nil
 		return err
 	}
+	registerMetrics(DBConn)
 	return nil
 }
 
@@ -130,7 +131,10 @@ func ExecSchema() error {
 		os.Remove(*utils.Dir + "/config.ini")
 		return err
 	}
-	return DBConn.Exec(string(schema)).Error
+	if err = DBConn.Exec(string(schema)).Error; err != nil {
+		return err
+	}
+	return Migrate()
 }
 
 func Update(transaction *DbTransaction, tblname, set, where string) error {
@@ -253,7 +257,35 @@ func GetColumnCount(tableName string) (int64, error) {
 	return count, nil
 }
 
+// SendTx writes the transaction into queue_tx
 func SendTx(txType int64, adminWallet int64, data []byte) ([]byte, error) {
+	return sendTx(nil, txType, adminWallet, data)
+}
+
+// SendTxBatch writes the transactions into queue_tx within one db transaction
+func SendTxBatch(txTypes []int64, adminWallet int64, data [][]byte) ([][]byte, error) {
+	dbTransaction, err := StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, 0, len(data))
+	for i, item := range data {
+		hash, err := sendTx(dbTransaction, txTypes[i], adminWallet, item)
+		if err != nil {
+			dbTransaction.Rollback()
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	if err = dbTransaction.Commit(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing batch of transactions")
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// sendTx creates the status of the transaction and puts it into queue_tx
+func sendTx(transaction *DbTransaction, txType int64, adminWallet int64, data []byte) ([]byte, error) {
 	hash, err := crypto.Hash(data)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("hashing data")
@@ -265,8 +297,7 @@ func SendTx(txType int64, adminWallet int64, data []byte) ([]byte, error) {
 		Type:     txType,
 		WalletID: adminWallet,
 	}
-	err = ts.Create()
-	if err != nil {
+	if err = GetDB(transaction).Create(ts).Error; err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("transaction status create")
 		return nil, err
 	}
@@ -274,8 +305,11 @@ func SendTx(txType int64, adminWallet int64, data []byte) ([]byte, error) {
 		Hash: hash,
 		Data: data,
 	}
-	err = qtx.Create()
-	return hash, err
+	if err = GetDB(transaction).Create(qtx).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("queue tx create")
+		return nil, err
+	}
+	return hash, nil
 }
 
 func AlterTableAddColumn(transaction *DbTransaction, tableName, columnName, columnType string) error {
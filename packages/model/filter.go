@@ -0,0 +1,256 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Filter is the structured condition for selecting rows of the table.
+// Each key is the name of the column and the value is either the value of the column
+// or the map of operators, for example
+// {"name": "John", "amount": {"$gte": 10, "$lt": 100}, "id": {"$in": [1, 2, 3]}, "title": {"$like": "%abc%"}}.
+// $ilike is the case-insensitive $like.
+// The conditions are joined by AND, $and and $or keys join the list of the nested filters, for example
+// {"$or": [{"name": "John"}, {"amount": {"$gt": 100}}]}
+type Filter map[string]interface{}
+
+var filterOperators = map[string]string{
+	`$eq`:    `=`,
+	`$neq`:   `<>`,
+	`$gt`:    `>`,
+	`$gte`:   `>=`,
+	`$lt`:    `<`,
+	`$lte`:   `<=`,
+	`$in`:    `IN`,
+	`$nin`:   `NOT IN`,
+	`$like`:  `LIKE`,
+	`$ilike`: `ILIKE`,
+}
+
+var filterGroups = map[string]string{
+	`$and`: ` AND `,
+	`$or`:  ` OR `,
+}
+
+// ParseFilter decodes the filter from JSON
+func ParseFilter(input string) (Filter, error) {
+	filter := make(Filter)
+	if len(strings.TrimSpace(input)) == 0 {
+		return filter, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&filter); err != nil {
+		return nil, fmt.Errorf(`filter must be JSON object`)
+	}
+	return filter, nil
+}
+
+// GetColumnTypes returns data types of the columns of the table
+func GetColumnTypes(tableName string) (map[string]string, error) {
+	rows, err := DBConn.Raw(`SELECT column_name, data_type FROM information_schema.columns WHERE table_name = ?`,
+		tableName).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]string)
+	for rows.Next() {
+		var name, dataType string
+		if err = rows.Scan(&name, &dataType); err != nil {
+			return nil, err
+		}
+		result[name] = dataType
+	}
+	return result, rows.Err()
+}
+
+func isTextColumn(dataType string) bool {
+	return dataType == `character varying` || dataType == `text` || dataType == `character`
+}
+
+func filterValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string, bool:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return fmt.Sprint(v), nil
+	case int64, int:
+		return fmt.Sprint(v), nil
+	}
+	return nil, fmt.Errorf(`unsupported value %v`, value)
+}
+
+// Where returns the parameterized where clause and its arguments for the columns of the table.
+// The names of the columns and the operators are checked so the clause is safe for SQL query.
+func (filter Filter) Where(columns map[string]string) (string, []interface{}, error) {
+	var (
+		conds []string
+		args  []interface{}
+	)
+	names := make([]string, 0, len(filter))
+	for name := range filter {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if join, ok := filterGroups[name]; ok {
+			list, err := filter.group(name)
+			if err != nil {
+				return ``, nil, err
+			}
+			parts := make([]string, 0, len(list))
+			for _, item := range list {
+				where, itemArgs, err := item.Where(columns)
+				if err != nil {
+					return ``, nil, err
+				}
+				if len(where) > 0 {
+					parts = append(parts, `(`+where+`)`)
+					args = append(args, itemArgs...)
+				}
+			}
+			if len(parts) > 0 {
+				conds = append(conds, `(`+strings.Join(parts, join)+`)`)
+			}
+			continue
+		}
+		dataType, ok := columns[name]
+		if !ok {
+			return ``, nil, fmt.Errorf(`unknown column %s`, name)
+		}
+		ops, ok := filter[name].(map[string]interface{})
+		if !ok {
+			ops = map[string]interface{}{`$eq`: filter[name]}
+		}
+		keys := make([]string, 0, len(ops))
+		for op := range ops {
+			keys = append(keys, op)
+		}
+		sort.Strings(keys)
+		for _, op := range keys {
+			sqlOp, ok := filterOperators[op]
+			if !ok {
+				return ``, nil, fmt.Errorf(`unknown operator %s`, op)
+			}
+			switch op {
+			case `$in`, `$nin`:
+				list, ok := ops[op].([]interface{})
+				if !ok || len(list) == 0 {
+					return ``, nil, fmt.Errorf(`%s of %s must be non-empty array`, op, name)
+				}
+				marks := make([]string, 0, len(list))
+				for _, item := range list {
+					val, err := filterValue(item)
+					if err != nil {
+						return ``, nil, err
+					}
+					marks = append(marks, `?`)
+					args = append(args, val)
+				}
+				conds = append(conds, fmt.Sprintf(`"%s" %s (%s)`, name, sqlOp, strings.Join(marks, `,`)))
+				continue
+			case `$like`, `$ilike`:
+				if !isTextColumn(dataType) {
+					return ``, nil, fmt.Errorf(`%s can be used only with text column %s`, op, name)
+				}
+			}
+			if ops[op] == nil && (op == `$eq` || op == `$neq`) {
+				if op == `$eq` {
+					conds = append(conds, fmt.Sprintf(`"%s" IS NULL`, name))
+				} else {
+					conds = append(conds, fmt.Sprintf(`"%s" IS NOT NULL`, name))
+				}
+				continue
+			}
+			val, err := filterValue(ops[op])
+			if err != nil {
+				return ``, nil, err
+			}
+			conds = append(conds, fmt.Sprintf(`"%s" %s ?`, name, sqlOp))
+			args = append(args, val)
+		}
+	}
+	return strings.Join(conds, ` AND `), args, nil
+}
+
+// group returns the nested filters of $and or $or key
+func (filter Filter) group(key string) ([]Filter, error) {
+	list, ok := filter[key].([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, fmt.Errorf(`%s must be non-empty array`, key)
+	}
+	result := make([]Filter, 0, len(list))
+	for _, item := range list {
+		sub, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(`items of %s must be objects`, key)
+		}
+		result = append(result, Filter(sub))
+	}
+	return result, nil
+}
+
+// Columns returns the names of the columns which are used in the filter and its nested filters
+func (filter Filter) Columns() []string {
+	var list []string
+	for name := range filter {
+		if _, ok := filterGroups[name]; !ok {
+			list = append(list, name)
+			continue
+		}
+		if items, err := filter.group(name); err == nil {
+			for _, item := range items {
+				list = append(list, item.Columns()...)
+			}
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// GetOrder returns the checked order clause. The order is the list of the columns with
+// optional asc or desc direction separated by commas, for example "name, amount desc".
+// The empty order returns the empty clause, the order without columns is wrong
+func GetOrder(columns map[string]string, order string) (string, error) {
+	var list []string
+	for _, item := range strings.Split(order, `,`) {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return ``, fmt.Errorf(`wrong order %s`, item)
+		}
+		if _, ok := columns[fields[0]]; !ok {
+			return ``, fmt.Errorf(`unknown column %s`, fields[0])
+		}
+		dir := `asc`
+		if len(fields) == 2 {
+			dir = strings.ToLower(fields[1])
+			if dir != `asc` && dir != `desc` {
+				return ``, fmt.Errorf(`wrong order direction %s`, fields[1])
+			}
+		}
+		list = append(list, fmt.Sprintf(`"%s" %s`, fields[0], dir))
+	}
+	if len(list) == 0 && len(strings.TrimSpace(order)) > 0 {
+		return ``, fmt.Errorf(`wrong order %s`, order)
+	}
+	return strings.Join(list, `, `), nil
+}
+
+// orderColumns returns the names of the columns of the order
+func orderColumns(order string) []string {
+	var list []string
+	for _, item := range strings.Split(order, `,`) {
+		if fields := strings.Fields(item); len(fields) > 0 {
+			list = append(list, fields[0])
+		}
+	}
+	return list
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/metrics"
+
+	"github.com/jinzhu/gorm"
+	log "github.com/sirupsen/logrus"
+)
+
+var queryTime = metrics.NewHistogram(`apla_db_query_seconds`,
+	`The time of the execution of the queries by the operation`, metrics.DefBuckets, `operation`)
+
+// queryOperations are the labels of the queries by the first keyword of the query
+var queryOperations = map[string]string{
+	`insert`: `create`, `update`: `update`, `delete`: `delete`, `select`: `select`, `with`: `select`,
+}
+
+// registerMetrics sets the logger which observes the duration of the queries. gorm traces all queries
+// including the queries of Exec and Raw which don't pass through the callbacks, so the detailed log mode
+// is enabled and the traced queries go to the metrics instead of the log
+func registerMetrics(db *gorm.DB) {
+	db.SetLogger(queryLogger{})
+	db.LogMode(true)
+}
+
+// queryLogger observes the traced queries and logs the errors of gorm
+type queryLogger struct{}
+
+// Print gets the messages of gorm. The traced query is "sql", file, duration, query, vars
+// and the error is "log", file, error
+func (queryLogger) Print(values ...interface{}) {
+	if len(values) >= 4 && values[0] == `sql` {
+		if duration, ok := values[2].(time.Duration); ok {
+			queryTime.Observe(duration.Seconds(), queryOperation(fmt.Sprint(values[3])))
+		}
+		return
+	}
+	if len(values) >= 2 && values[0] == `log` {
+		values = values[2:]
+	}
+	log.WithFields(log.Fields{"type": consts.DBError, "error": fmt.Sprint(values...)}).Error("executing query")
+}
+
+// queryOperation returns the label of the query, the queries which don't change or select the rows
+// are labelled as exec
+func queryOperation(query string) string {
+	if fields := strings.Fields(query); len(fields) > 0 {
+		if operation, ok := queryOperations[strings.ToLower(fields[0])]; ok {
+			return operation
+		}
+	}
+	return `exec`
+}
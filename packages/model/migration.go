@@ -0,0 +1,128 @@
+package model
+
+import (
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// migration is the update of the database structure. The statements must not fail
+// if they are applied to the database which has been created from the current schema
+type migration struct {
+	version int32
+	query   string
+}
+
+// migrations is the list of the updates of the existing databases, the versions must be increasing
+var migrations = []migration{
+	{1, `CREATE TABLE IF NOT EXISTS "votes" (
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"round" int NOT NULL DEFAULT '0',
+		"type" smallint NOT NULL DEFAULT '0',
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"hash" bytea NOT NULL DEFAULT '',
+		"signature" bytea NOT NULL DEFAULT ''
+		);
+		ALTER TABLE "votes" ADD COLUMN IF NOT EXISTS "round" int NOT NULL DEFAULT '0';
+		ALTER TABLE "votes" DROP CONSTRAINT IF EXISTS votes_pkey;
+		ALTER TABLE "votes" ADD CONSTRAINT votes_pkey PRIMARY KEY (block_id, round, type, key_id);
+		CREATE TABLE IF NOT EXISTS "finalized_blocks" (
+		"block_id" bigint NOT NULL DEFAULT '0' PRIMARY KEY,
+		"hash" bytea NOT NULL DEFAULT '',
+		"time" bigint NOT NULL DEFAULT '0'
+		);`},
+	{2, `CREATE TABLE IF NOT EXISTS "validators" (
+		"id" bigint NOT NULL DEFAULT '0' PRIMARY KEY,
+		"produced" bigint NOT NULL DEFAULT '0',
+		"missed" bigint NOT NULL DEFAULT '0',
+		"double_signed" bigint NOT NULL DEFAULT '0',
+		"suspended" bigint NOT NULL DEFAULT '0',
+		"node" text NOT NULL DEFAULT '',
+		"rb_id" bigint NOT NULL DEFAULT '0'
+		);
+		CREATE TABLE IF NOT EXISTS "validator_evidence" (
+		"id" bigint NOT NULL DEFAULT '0' PRIMARY KEY,
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"rb_id" bigint NOT NULL DEFAULT '0'
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS "validator_evidence_index_key" ON "validator_evidence" (key_id, block_id);
+		CREATE TABLE IF NOT EXISTS "double_signs" (
+		"key_id" bigint NOT NULL DEFAULT '0',
+		"block_id" bigint NOT NULL DEFAULT '0',
+		"for_sign1" text NOT NULL DEFAULT '',
+		"sign1" bytea NOT NULL DEFAULT '',
+		"for_sign2" text NOT NULL DEFAULT '',
+		"sign2" bytea NOT NULL DEFAULT '',
+		"time" bigint NOT NULL DEFAULT '0',
+		PRIMARY KEY (key_id, block_id)
+		);
+		INSERT INTO "system_parameters" ("name", "value", "conditions") VALUES
+		('validator_max_missed', '50', 'true'),
+		('validator_min_slots', '100', 'true'),
+		('validators_fork_height', '0', 'true')
+		ON CONFLICT DO NOTHING;`},
+	{3, `ALTER TABLE "transactions" ADD COLUMN IF NOT EXISTS "time" bigint NOT NULL DEFAULT '0',
+		ADD COLUMN IF NOT EXISTS "nonce" bigint NOT NULL DEFAULT '0',
+		ADD COLUMN IF NOT EXISTS "max_sum" decimal(30) NOT NULL DEFAULT '0',
+		ADD COLUMN IF NOT EXISTS "payover" decimal(30,18) NOT NULL DEFAULT '0',
+		ADD COLUMN IF NOT EXISTS "size" bigint NOT NULL DEFAULT '0';
+		CREATE INDEX IF NOT EXISTS "transactions_index_key" ON "transactions" (key_id, nonce);`},
+	{4, `INSERT INTO "system_parameters" ("name", "value", "conditions") VALUES
+		('nonce_fork_height', '0', 'true')
+		ON CONFLICT DO NOTHING;`},
+	{5, `INSERT INTO "system_parameters" ("name", "value", "conditions") VALUES
+		('snapshot_hash', '', 'false')
+		ON CONFLICT DO NOTHING;`},
+	{6, `CREATE TABLE IF NOT EXISTS "peers" (
+		"host" varchar(255) NOT NULL DEFAULT '' PRIMARY KEY,
+		"score" int NOT NULL DEFAULT '0',
+		"banned_until" bigint NOT NULL DEFAULT '0',
+		"ban_reason" text NOT NULL DEFAULT '',
+		"last_seen" bigint NOT NULL DEFAULT '0'
+		);`},
+	{7, `INSERT INTO "system_parameters" ("name", "value", "conditions") VALUES
+		('vm_cost_table', '', 'true'),
+		('tcp_auth_required', '0', 'true'),
+		('snapshot_period', '0', 'true')
+		ON CONFLICT (name) DO NOTHING;`},
+}
+
+// Migrate applies the migrations which haven't been applied to the database yet
+func Migrate() error {
+	if !DBConn.HasTable(&MigrationHistory{}) {
+		return nil
+	}
+	var current int32
+	row := DBConn.Table("migration_history").Select("coalesce(max(version), 0)").Row()
+	if err := row.Scan(&current); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting version of database")
+		return err
+	}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		transaction, err := StartTransaction()
+		if err != nil {
+			return err
+		}
+		if err = GetDB(transaction).Exec(m.query).Error; err == nil {
+			err = GetDB(transaction).Create(&MigrationHistory{ID: m.version, Version: m.version,
+				DateApplied: int32(time.Now().Unix())}).Error
+		}
+		if err != nil {
+			transaction.Rollback()
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "version": m.version}).Error("applying migration")
+			return err
+		}
+		if err = transaction.Commit(); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "version": m.version}).Error("committing migration")
+			return err
+		}
+		log.WithFields(log.Fields{"version": m.version}).Info("migration has been applied")
+	}
+	return nil
+}
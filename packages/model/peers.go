@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// Peer is the node of the address book
+type Peer struct {
+	Host        string `gorm:"primary_key;not null;size:255"`
+	Score       int64  `gorm:"not null"`
+	BannedUntil int64  `gorm:"not null"`
+	BanReason   string `gorm:"not null"`
+	LastSeen    int64  `gorm:"not null"`
+}
+
+func (Peer) TableName() string {
+	return "peers"
+}
+
+func (p *Peer) Get(host string) (bool, error) {
+	return isFound(DBConn.Where("host = ?", host).First(p))
+}
+
+// AddPeers adds the unknown hosts into the address book
+func AddPeers(hosts []string) error {
+	for _, host := range hosts {
+		err := DBConn.Exec(`INSERT INTO "peers" ("host") VALUES (?) ON CONFLICT DO NOTHING`, host).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPeers returns the peers which are not banned in the order of their scores
+func GetPeers(limit int) ([]Peer, error) {
+	peers := make([]Peer, 0)
+	err := DBConn.Where("banned_until <= ?", time.Now().Unix()).Order("score desc, last_seen desc").
+		Limit(limit).Find(&peers).Error
+	return peers, err
+}
+
+// UpdatePeerScore adds delta to the score of the peer, the score doesn't exceed maxScore.
+// The time of the last contact is updated if delta is positive
+func UpdatePeerScore(host string, delta, maxScore int64) error {
+	return DBConn.Exec(`UPDATE "peers" SET score = LEAST(score + ?, ?),
+		last_seen = CASE WHEN ? > 0 THEN ? ELSE last_seen END WHERE host = ?`,
+		delta, maxScore, delta, time.Now().Unix(), host).Error
+}
+
+// BanPeer bans the peer till the time and decreases its score by penalty
+func BanPeer(host string, till int64, reason string, penalty int64) error {
+	return DBConn.Exec(`INSERT INTO "peers" ("host", "score", "banned_until", "ban_reason") VALUES (?, ?, ?, ?)
+		ON CONFLICT (host) DO UPDATE SET score = peers.score - ?, banned_until = ?, ban_reason = ?`,
+		host, -penalty, till, reason, penalty, till, reason).Error
+}
+
+// UnbanExpiredPeers removes the bans which have expired
+func UnbanExpiredPeers() error {
+	return DBConn.Exec(`UPDATE "peers" SET banned_until = 0, ban_reason = '' WHERE banned_until > 0 AND banned_until <= ?`,
+		time.Now().Unix()).Error
+}
+
+// DeleteBadPeers removes the peers with the score less than minScore which are not banned
+func DeleteBadPeers(minScore int64) error {
+	return DBConn.Exec(`DELETE FROM "peers" WHERE score < ? AND banned_until <= ?`, minScore, time.Now().Unix()).Error
+}
@@ -0,0 +1,203 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/converter"
+)
+
+var (
+	// ErrTableNotFound is returned if the table of the query doesn't exist
+	ErrTableNotFound = errors.New(`table has not been found`)
+	// ErrReadAccess is returned if the read condition of the table is false
+	ErrReadAccess = errors.New(`access denied`)
+)
+
+// ColumnAccessError is returned if the column of the query can't be read because of its read condition
+type ColumnAccessError struct {
+	Column string
+}
+
+func (e *ColumnAccessError) Error() string {
+	return fmt.Sprintf(`access denied to column %s`, e.Column)
+}
+
+// ReadQuery is the query of the rows of the table which has been checked against the read permissions
+type ReadQuery struct {
+	Table   string
+	Columns []string
+	Where   string
+	Args    []interface{}
+	Order   string
+}
+
+// ReadPermissions are the read condition of the table and the read conditions of its columns.
+// They are specified by read and read_columns keys of the permissions of the table
+type ReadPermissions struct {
+	Table   string
+	Columns map[string]string
+}
+
+// GetReadPermissions returns the read permissions of the table of the ecosystem
+func GetReadPermissions(ecosystem int64, name string) (*ReadPermissions, error) {
+	table := &Table{}
+	table.SetTablePrefix(converter.Int64ToStr(ecosystem))
+	perm, err := table.GetPermissions(name, ``)
+	if err != nil {
+		return nil, err
+	}
+	read := &ReadPermissions{Table: perm[`read`], Columns: make(map[string]string)}
+	if len(perm[`read_columns`]) > 0 {
+		if err = json.Unmarshal([]byte(perm[`read_columns`]), &read.Columns); err != nil {
+			return nil, err
+		}
+	}
+	return read, nil
+}
+
+// Denied returns the columns which can't be read. ErrReadAccess is returned if the table can't be read.
+// eval evaluates the conditions, the condition which returns an error is false
+func (perm *ReadPermissions) Denied(eval func(string) (bool, error)) (map[string]bool, error) {
+	allowed := func(cond string) bool {
+		if len(cond) == 0 {
+			return true
+		}
+		ok, err := eval(cond)
+		return err == nil && ok
+	}
+	if !allowed(perm.Table) {
+		return nil, ErrReadAccess
+	}
+	denied := make(map[string]bool)
+	for column, cond := range perm.Columns {
+		if !allowed(cond) {
+			denied[column] = true
+		}
+	}
+	return denied, nil
+}
+
+// CheckRaw checks the read permissions for the query with the raw where condition.
+// Such query is allowed only if all columns of the table can be read
+func (perm *ReadPermissions) CheckRaw(eval func(string) (bool, error)) error {
+	denied, err := perm.Denied(eval)
+	if err != nil {
+		return err
+	}
+	columns := make([]string, 0, len(denied))
+	for column := range denied {
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	sort.Strings(columns)
+	return &ColumnAccessError{Column: columns[0]}
+}
+
+// NewReadQuery checks the selected columns, the filter and the order of the query to the table of the ecosystem.
+// eval evaluates the read conditions of the table and its columns, the condition which returns an error is false.
+// All readable columns are selected if columns is empty
+func NewReadQuery(ecosystem int64, name string, columns []string, filter Filter, order string,
+	eval func(string) (bool, error)) (*ReadQuery, error) {
+
+	tableName := fmt.Sprintf(`%d_%s`, ecosystem, name)
+	types, err := GetColumnTypes(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(types) == 0 {
+		return nil, ErrTableNotFound
+	}
+	perm, err := GetReadPermissions(ecosystem, name)
+	if err != nil {
+		return nil, err
+	}
+	denied, err := perm.Denied(eval)
+	if err != nil {
+		return nil, err
+	}
+	return BuildReadQuery(tableName, types, denied, columns, filter, order)
+}
+
+// BuildReadQuery returns the query to the table with the types of the columns. The denied columns
+// can't be selected or used in the filter and the order
+func BuildReadQuery(tableName string, types map[string]string, denied map[string]bool, columns []string,
+	filter Filter, order string) (*ReadQuery, error) {
+
+	var err error
+	check := func(list []string) error {
+		for _, column := range list {
+			if _, ok := types[column]; !ok {
+				return fmt.Errorf(`unknown column %s`, column)
+			}
+			if denied[column] {
+				return &ColumnAccessError{Column: column}
+			}
+		}
+		return nil
+	}
+
+	query := &ReadQuery{Table: tableName}
+	for _, column := range columns {
+		if column = strings.TrimSpace(column); len(column) > 0 && column != `*` {
+			query.Columns = append(query.Columns, column)
+		}
+	}
+	if len(query.Columns) == 0 {
+		for column := range types {
+			if !denied[column] {
+				query.Columns = append(query.Columns, column)
+			}
+		}
+		sort.Strings(query.Columns)
+	}
+	if err = check(query.Columns); err != nil {
+		return nil, err
+	}
+	if err = check(filter.Columns()); err != nil {
+		return nil, err
+	}
+	if err = check(orderColumns(order)); err != nil {
+		return nil, err
+	}
+	if query.Where, query.Args, err = filter.Where(types); err != nil {
+		return nil, err
+	}
+	if query.Order, err = GetOrder(types, order); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// From returns FROM and WHERE clauses of the query
+func (q *ReadQuery) From() string {
+	from := fmt.Sprintf(` from "%s"`, q.Table)
+	if len(q.Where) > 0 {
+		from += ` where ` + q.Where
+	}
+	return from
+}
+
+// Select returns the SQL query of the rows, offset and limit are added if they are positive
+func (q *ReadQuery) Select(offset, limit int64) string {
+	columns := make([]string, len(q.Columns))
+	for i, column := range q.Columns {
+		columns[i] = `"` + column + `"`
+	}
+	query := `select ` + strings.Join(columns, `,`) + q.From()
+	if len(q.Order) > 0 {
+		query += ` order by ` + q.Order
+	}
+	if limit > 0 {
+		query += fmt.Sprintf(` limit %d`, limit)
+	}
+	if offset > 0 {
+		query += fmt.Sprintf(` offset %d`, offset)
+	}
+	return query
+}
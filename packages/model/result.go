@@ -180,11 +180,11 @@ func GetAllTransaction(transaction *DbTransaction, query string, countRows int,
 }
 
 func GetAll(query string, countRows int, args ...interface{}) ([]map[string]string, error) {
-	return GetAllTransaction(nil, query, countRows, args)
+	return GetAllTx(nil, query, countRows, args...)
 }
 
 func GetAllTx(transaction *DbTransaction, query string, countRows int, args ...interface{}) ([]map[string]string, error) {
-	return GetAllTransaction(transaction, query, countRows, args)
+	return GetAllTransaction(transaction, query, countRows, args...)
 }
 
 func GetOneRowTransaction(transaction *DbTransaction, query string, args ...interface{}) *OneRow {
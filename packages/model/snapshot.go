@@ -0,0 +1,757 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotVersion is the version of the format of the snapshot
+const SnapshotVersion = 2
+
+// SnapshotHashParam is the name of the system parameter with the committed hash of the last snapshot.
+// The parameter isn't a part of the hashed state
+const SnapshotHashParam = `snapshot_hash`
+
+var (
+	// ErrSnapshotFormat is returned if the snapshot is malformed
+	ErrSnapshotFormat = errors.New(`wrong format of snapshot`)
+	// ErrSnapshotHash is returned if the hash of the restored snapshot doesn't match the expected one
+	ErrSnapshotHash = errors.New(`wrong hash of snapshot`)
+)
+
+var (
+	snapshotIdent      = regexp.MustCompile(`^[a-z0-9_]{1,63}$`)
+	snapshotSizedType  = regexp.MustCompile(`^(character varying|character|numeric)\(\d{1,6}(,\d{1,6})?\)$`)
+	snapshotDefault    = regexp.MustCompile(`^(-?\d{1,30}(\.\d{1,30})?|true|false|'[^'\\]*'::([a-z ]+))$`)
+	snapshotConstraint = regexp.MustCompile(`^(PRIMARY KEY|UNIQUE) \(([^()]+)\)$`)
+	snapshotIndex      = regexp.MustCompile(`^CREATE (UNIQUE )?INDEX (\S+) ON (\S+) USING btree \(([^()]+)\)$`)
+)
+
+// snapshotTypes are the types of the columns which can be restored from the snapshot
+var snapshotTypes = map[string]bool{
+	`bigint`:                      true,
+	`boolean`:                     true,
+	`bpchar`:                      true,
+	`bytea`:                       true,
+	`character varying`:           true,
+	`double precision`:            true,
+	`integer`:                     true,
+	`jsonb`:                       true,
+	`numeric`:                     true,
+	`smallint`:                    true,
+	`text`:                        true,
+	`timestamp without time zone`: true,
+}
+
+// snapshotLocalTables are the tables with the data of the node, they are not a part of the state
+var snapshotLocalTables = map[string]bool{
+	`block_chain`:         true,
+	`config`:              true,
+	`confirmations`:       true,
+	`double_signs`:        true,
+	`finalized_blocks`:    true,
+	`info_block`:          true,
+	`install`:             true,
+	`main_lock`:           true,
+	`migration_history`:   true,
+	`my_node_keys`:        true,
+	`peers`:               true,
+	`queue_blocks`:        true,
+	`queue_tx`:            true,
+	`rollback`:            true,
+	`rollback_tx`:         true,
+	`stop_daemons`:        true,
+	`transactions`:        true,
+	`transactions_status`: true,
+	`votes`:               true,
+}
+
+// SnapshotColumn is the column of the table in the snapshot
+type SnapshotColumn struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	NotNull bool   `json:"not_null"`
+	Default string `json:"default"`
+}
+
+// SnapshotIndex is the constraint or the index of the table in the snapshot
+type SnapshotIndex struct {
+	Name string `json:"name"`
+	Def  string `json:"def"`
+}
+
+// SnapshotTable is the description of the table in the snapshot, it is followed by Rows lines of the data
+type SnapshotTable struct {
+	Name        string           `json:"name"`
+	Columns     []SnapshotColumn `json:"columns"`
+	Constraints []SnapshotIndex  `json:"constraints"`
+	Indexes     []SnapshotIndex  `json:"indexes"`
+	Rows        int64            `json:"rows"`
+}
+
+type snapshotHeader struct {
+	Snapshot int    `json:"snapshot"`
+	BlockID  int64  `json:"block_id"`
+	Hash     []byte `json:"hash"`
+}
+
+type snapshotLine struct {
+	Table *SnapshotTable `json:"table,omitempty"`
+	Block *Block         `json:"block,omitempty"`
+}
+
+// IsSnapshotTable returns true if the table is a part of the state of the blockchain
+func IsSnapshotTable(name string) bool {
+	return !snapshotLocalTables[name]
+}
+
+// FormatSnapshotHash returns the value of snapshot_hash parameter and snapshotHash flag in the format block_id,hash
+func FormatSnapshotHash(blockID int64, hash []byte) string {
+	return fmt.Sprintf(`%d,%x`, blockID, hash)
+}
+
+// ParseSnapshotHash returns the block and the hash of the value in the format block_id,hash
+func ParseSnapshotHash(value string) (int64, []byte, error) {
+	var (
+		blockID int64
+		hash    []byte
+	)
+	if _, err := fmt.Sscanf(value, `%d,%x`, &blockID, &hash); err != nil {
+		return 0, nil, err
+	}
+	return blockID, hash, nil
+}
+
+// snapshotWriter writes the lines of the snapshot and calculates the hash of the state
+type snapshotWriter struct {
+	w    *bufio.Writer
+	hash hash.Hash
+}
+
+func (sw *snapshotWriter) write(line []byte, hashed bool) error {
+	line = append(line, '\n')
+	if hashed && sw.hash != nil {
+		sw.hash.Write(line)
+	}
+	_, err := sw.w.Write(line)
+	return err
+}
+
+func (sw *snapshotWriter) writeJSON(v interface{}, hashed bool) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling snapshot line")
+		return err
+	}
+	return sw.write(data, hashed)
+}
+
+// GetSnapshotTables returns the sorted list of the tables of the state
+func GetSnapshotTables(transaction *DbTransaction) ([]string, error) {
+	rows, err := GetDB(transaction).Raw(`SELECT tablename FROM pg_tables WHERE schemaname = current_schema()`).Rows()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting list of tables")
+		return nil, err
+	}
+	defer rows.Close()
+	tables := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("scanning table name")
+			return nil, err
+		}
+		if IsSnapshotTable(name) {
+			tables = append(tables, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("retrieving list of tables")
+		return nil, err
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+func getSnapshotTable(transaction *DbTransaction, name string) (*SnapshotTable, error) {
+	table := &SnapshotTable{Name: name}
+	rows, err := GetDB(transaction).Raw(`SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull,
+		coalesce(pg_get_expr(d.adbin, d.adrelid), '') FROM pg_attribute a
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE a.attrelid = quote_ident(?)::regclass AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`, name).Rows()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting columns of table")
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var column SnapshotColumn
+		if err := rows.Scan(&column.Name, &column.Type, &column.NotNull, &column.Default); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("scanning column of table")
+			return nil, err
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	if table.Constraints, err = getSnapshotIndexes(transaction, `SELECT conname, pg_get_constraintdef(oid)
+		FROM pg_constraint WHERE conrelid = quote_ident(?)::regclass ORDER BY conname`, name); err != nil {
+		return nil, err
+	}
+	if table.Indexes, err = getSnapshotIndexes(transaction, `SELECT indexname, indexdef FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename = ? AND indexname NOT IN
+		(SELECT conname FROM pg_constraint WHERE conrelid = quote_ident(tablename)::regclass) ORDER BY indexname`, name); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func getSnapshotIndexes(transaction *DbTransaction, query, name string) ([]SnapshotIndex, error) {
+	rows, err := GetDB(transaction).Raw(query, name).Rows()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting indexes of table")
+		return nil, err
+	}
+	defer rows.Close()
+	indexes := make([]SnapshotIndex, 0)
+	for rows.Next() {
+		var index SnapshotIndex
+		if err := rows.Scan(&index.Name, &index.Def); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("scanning index of table")
+			return nil, err
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+// StartSnapshotTransaction starts the read only transaction which sees the state at the moment of the call
+// and returns the last block of this state. The blocks must not be played during the call,
+// after that the state of the transaction doesn't change until it is finished
+func StartSnapshotTransaction() (*DbTransaction, int64, error) {
+	transaction, err := StartTransaction()
+	if err != nil {
+		return nil, 0, err
+	}
+	var blockID int64
+	err = GetDB(transaction).Exec(`SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY`).Error
+	if err == nil {
+		// the snapshot of the transaction is taken by the first query
+		err = GetDB(transaction).Raw(`SELECT block_id FROM "info_block"`).Row().Scan(&blockID)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("starting snapshot transaction")
+		transaction.Rollback()
+		return nil, 0, err
+	}
+	return transaction, blockID, nil
+}
+
+// snapshotRows returns the condition of the rows of the table which are a part of the state
+func snapshotRows(name string) string {
+	if name == `system_parameters` {
+		return ` WHERE name <> '` + SnapshotHashParam + `'`
+	}
+	return ``
+}
+
+// writeSnapshotState writes the header with the block and the hashed state of the blockchain
+func writeSnapshotState(sw *snapshotWriter, transaction *DbTransaction, blockID int64, blockHash []byte) error {
+	if err := sw.writeJSON(snapshotHeader{Snapshot: SnapshotVersion, BlockID: blockID, Hash: blockHash}, true); err != nil {
+		return err
+	}
+	tables, err := GetSnapshotTables(transaction)
+	if err != nil {
+		return err
+	}
+	for _, name := range tables {
+		table, err := getSnapshotTable(transaction, name)
+		if err != nil {
+			return err
+		}
+		if err = GetDB(transaction).Raw(`SELECT count(*) FROM "` + name + `"` + snapshotRows(name)).Row().Scan(&table.Rows); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("getting count of rows")
+			return err
+		}
+		if err = sw.writeJSON(snapshotLine{Table: table}, true); err != nil {
+			return err
+		}
+		rows, err := GetDB(transaction).Raw(`SELECT (to_jsonb(t) - 'rb_id')::text FROM "` + name + `" AS t` +
+			snapshotRows(name) + ` ORDER BY 1 COLLATE "C"`).Rows()
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("selecting rows of table")
+			return err
+		}
+		for rows.Next() {
+			var row []byte
+			if err = rows.Scan(&row); err != nil {
+				log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("scanning row of table")
+				break
+			}
+			if err = sw.write(row, true); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SnapshotHash returns the hash of the state of the blockchain after the block with blockID and blockHash.
+// The state is read in the transaction of the block, the hash is committed into snapshot_hash parameter
+func SnapshotHash(transaction *DbTransaction, blockID int64, blockHash []byte) ([]byte, error) {
+	sw := &snapshotWriter{w: bufio.NewWriter(ioutil.Discard), hash: sha256.New()}
+	if err := writeSnapshotState(sw, transaction, blockID, blockHash); err != nil {
+		return nil, err
+	}
+	return sw.hash.Sum(nil), nil
+}
+
+// WriteSnapshot writes the state of the blockchain after blockID block followed by this block and returns
+// the hash of the state. The rows are written in the order of their JSON representation without rb_id
+// so the hash is the same on all nodes. The hash of the block is a part of the hashed data
+func WriteSnapshot(transaction *DbTransaction, blockID int64, w io.Writer) ([]byte, error) {
+	block := &Block{}
+	if err := GetDB(transaction).Where("id = ?", blockID).First(block).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": blockID}).Error("getting block of snapshot")
+		return nil, err
+	}
+	sw := &snapshotWriter{w: bufio.NewWriter(w), hash: sha256.New()}
+	if err := writeSnapshotState(sw, transaction, blockID, block.Hash); err != nil {
+		return nil, err
+	}
+	// the block is checked by its hash in the header
+	if err := sw.writeJSON(snapshotLine{Block: block}, false); err != nil {
+		return nil, err
+	}
+	if err := sw.w.Flush(); err != nil {
+		return nil, err
+	}
+	return sw.hash.Sum(nil), nil
+}
+
+// snapshotDDL contains the queries which create the table of the snapshot
+type snapshotDDL struct {
+	create  []string
+	indexes []string
+}
+
+func unquoteIdent(name string) (string, error) {
+	if len(name) > 1 && name[0] == '"' && name[len(name)-1] == '"' {
+		name = name[1 : len(name)-1]
+	}
+	if !snapshotIdent.MatchString(name) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "name": name}).Error("wrong name in snapshot")
+		return ``, ErrSnapshotFormat
+	}
+	return name, nil
+}
+
+// snapshotColumnList checks the list of the columns of the constraint or the index
+func snapshotColumnList(list string, columns map[string]bool) ([]string, error) {
+	names := strings.Split(list, `, `)
+	for i, name := range names {
+		column, err := unquoteIdent(name)
+		if err != nil {
+			return nil, err
+		}
+		if !columns[column] {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "column": column}).Error("unknown column in snapshot")
+			return nil, ErrSnapshotFormat
+		}
+		names[i] = `"` + column + `"`
+	}
+	return names, nil
+}
+
+func isSnapshotType(name string) bool {
+	return snapshotTypes[name] || snapshotSizedType.MatchString(name)
+}
+
+// parseSnapshotTable checks the names, the types and the defaults of the table by the whitelists
+// and makes the queries which create the table. The definitions from the snapshot are never executed as is
+func parseSnapshotTable(table *SnapshotTable) (*snapshotDDL, error) {
+	if _, err := unquoteIdent(table.Name); err != nil || !IsSnapshotTable(table.Name) || table.Rows < 0 {
+		return nil, ErrSnapshotFormat
+	}
+	ddl := &snapshotDDL{}
+	columns := make(map[string]bool)
+	defs := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		if _, err := unquoteIdent(column.Name); err != nil || columns[column.Name] {
+			return nil, ErrSnapshotFormat
+		}
+		columns[column.Name] = true
+		if !isSnapshotType(column.Type) {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "column_type": column.Type}).Error("wrong type of column in snapshot")
+			return nil, ErrSnapshotFormat
+		}
+		def := `"` + column.Name + `" ` + column.Type
+		if column.NotNull {
+			def += ` NOT NULL`
+		}
+		if len(column.Default) > 0 {
+			match := snapshotDefault.FindStringSubmatch(column.Default)
+			if match == nil || (len(match[3]) > 0 && !snapshotTypes[match[3]]) {
+				log.WithFields(log.Fields{"type": consts.InvalidObject, "default": column.Default}).Error("wrong default of column in snapshot")
+				return nil, ErrSnapshotFormat
+			}
+			def += ` DEFAULT ` + column.Default
+		}
+		defs = append(defs, def)
+	}
+	ddl.create = []string{`DROP TABLE IF EXISTS "` + table.Name + `"`,
+		`CREATE TABLE "` + table.Name + `" (` + strings.Join(defs, `,`) + `)`}
+	for _, constraint := range table.Constraints {
+		match := snapshotConstraint.FindStringSubmatch(constraint.Def)
+		if match == nil {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "constraint": constraint.Def}).Error("wrong constraint in snapshot")
+			return nil, ErrSnapshotFormat
+		}
+		name, err := unquoteIdent(constraint.Name)
+		if err != nil {
+			return nil, err
+		}
+		list, err := snapshotColumnList(match[2], columns)
+		if err != nil {
+			return nil, err
+		}
+		ddl.create = append(ddl.create, `ALTER TABLE ONLY "`+table.Name+`" ADD CONSTRAINT "`+name+`" `+
+			match[1]+` (`+strings.Join(list, `, `)+`)`)
+	}
+	for _, index := range table.Indexes {
+		match := snapshotIndex.FindStringSubmatch(index.Def)
+		if match == nil {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "index": index.Def}).Error("wrong index in snapshot")
+			return nil, ErrSnapshotFormat
+		}
+		name, err := unquoteIdent(match[2])
+		if err != nil {
+			return nil, err
+		}
+		tableName := match[3]
+		if i := strings.LastIndexByte(tableName, '.'); i >= 0 {
+			tableName = tableName[i+1:]
+		}
+		if tableName, err = unquoteIdent(tableName); err != nil {
+			return nil, err
+		}
+		if name != index.Name || tableName != table.Name {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "index": index.Def}).Error("index of another table in snapshot")
+			return nil, ErrSnapshotFormat
+		}
+		list, err := snapshotColumnList(match[4], columns)
+		if err != nil {
+			return nil, err
+		}
+		ddl.indexes = append(ddl.indexes, `CREATE `+match[1]+`INDEX "`+name+`" ON "`+table.Name+`" (`+
+			strings.Join(list, `, `)+`)`)
+	}
+	return ddl, nil
+}
+
+// readSnapshot reads and validates the snapshot, onTable is called for every table and onRow for every row
+// of the table if they are not nil. It returns the last block of the snapshot and the hash of the state
+func readSnapshot(r io.Reader, onTable func(*SnapshotTable, *snapshotDDL) error,
+	onRow func(*SnapshotTable, []byte) error) (*Block, []byte, error) {
+	var (
+		header snapshotHeader
+		block  *Block
+	)
+	reader := bufio.NewReader(r)
+	sum := sha256.New()
+	readLine := func() ([]byte, error) {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil, err
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return line, nil
+	}
+	line, err := readLine()
+	if err != nil {
+		return nil, nil, ErrSnapshotFormat
+	}
+	sum.Write(line)
+	if err = json.Unmarshal(line, &header); err != nil || header.Snapshot != SnapshotVersion {
+		log.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("parsing header of snapshot")
+		return nil, nil, ErrSnapshotFormat
+	}
+	tables := make(map[string]bool)
+	for {
+		if line, err = readLine(); err == io.EOF {
+			break
+		} else if err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading snapshot")
+			return nil, nil, err
+		}
+		var item snapshotLine
+		if err = json.Unmarshal(line, &item); err != nil {
+			log.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("parsing line of snapshot")
+			return nil, nil, ErrSnapshotFormat
+		}
+		if item.Block != nil && block == nil {
+			block = item.Block
+			continue
+		}
+		table := item.Table
+		if table == nil || block != nil || tables[table.Name] {
+			return nil, nil, ErrSnapshotFormat
+		}
+		tables[table.Name] = true
+		ddl, err := parseSnapshotTable(table)
+		if err != nil {
+			return nil, nil, err
+		}
+		sum.Write(line)
+		if onTable != nil {
+			if err = onTable(table, ddl); err != nil {
+				return nil, nil, err
+			}
+		}
+		for i := int64(0); i < table.Rows; i++ {
+			if line, err = readLine(); err != nil {
+				return nil, nil, ErrSnapshotFormat
+			}
+			sum.Write(line)
+			if onRow != nil {
+				if err = onRow(table, line); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+	if block == nil || block.ID != header.BlockID || !bytes.Equal(block.Hash, header.Hash) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "block_id": header.BlockID}).Error("block of snapshot is missing")
+		return nil, nil, ErrSnapshotFormat
+	}
+	return block, sum.Sum(nil), nil
+}
+
+func checkSnapshotHash(hash, expected []byte) error {
+	if !bytes.Equal(hash, expected) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "hash": fmt.Sprintf(`%x`, hash),
+			"expected": fmt.Sprintf(`%x`, expected)}).Error("checking hash of snapshot")
+		return ErrSnapshotHash
+	}
+	return nil
+}
+
+// RestoreSnapshot replaces the state of the blockchain with the snapshot and returns its last block.
+// The whole snapshot is read and its hash is compared with expected which must be received from a trusted
+// source before anything is changed. The database is changed only during the second pass
+func RestoreSnapshot(r io.ReadSeeker, expected []byte) (block *Block, err error) {
+	_, hash, err := readSnapshot(r, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkSnapshotHash(hash, expected); err != nil {
+		return nil, err
+	}
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("seeking snapshot")
+		return nil, err
+	}
+
+	transaction, err := StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			transaction.Rollback()
+		}
+	}()
+	tables, err := GetSnapshotTables(transaction)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range tables {
+		if err = DropTable(transaction, name); err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": name}).Error("dropping table")
+			return nil, err
+		}
+	}
+	var insert string
+	indexes := make([]string, 0)
+	createTable := func(table *SnapshotTable, ddl *snapshotDDL) error {
+		for _, query := range ddl.create {
+			if err := GetDB(transaction).Exec(query).Error; err != nil {
+				log.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": query}).Error("creating table of snapshot")
+				return err
+			}
+		}
+		columns := make([]string, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			if column.Name != `rb_id` {
+				columns = append(columns, `"`+column.Name+`"`)
+			}
+		}
+		insert = `INSERT INTO "` + table.Name + `" (` + strings.Join(columns, `,`) + `) SELECT ` +
+			strings.Join(columns, `,`) + ` FROM jsonb_populate_record(NULL::"` + table.Name + `", ?::jsonb)`
+		indexes = append(indexes, ddl.indexes...)
+		return nil
+	}
+	insertRow := func(table *SnapshotTable, row []byte) error {
+		if err := GetDB(transaction).Exec(insert, string(row)).Error; err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "table": table.Name}).Error("inserting row of snapshot")
+			return err
+		}
+		return nil
+	}
+	if block, hash, err = readSnapshot(r, createTable, insertRow); err != nil {
+		return nil, err
+	}
+	// the snapshot could be changed after the first pass
+	if err = checkSnapshotHash(hash, expected); err != nil {
+		return nil, err
+	}
+	for _, query := range indexes {
+		if err = GetDB(transaction).Exec(query).Error; err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": query}).Error("creating index of snapshot")
+			return nil, err
+		}
+	}
+	if err = restoreSnapshotBlock(transaction, block); err != nil {
+		return nil, err
+	}
+	// the committed hash of the restored state is the value of the parameter after the block
+	if err = GetDB(transaction).Exec(`INSERT INTO "system_parameters" ("name", "value", "conditions") VALUES (?, ?, 'false')`,
+		SnapshotHashParam, FormatSnapshotHash(block.ID, hash)).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("restoring snapshot hash parameter")
+		return nil, err
+	}
+	if err = transaction.Commit(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing snapshot")
+		return nil, err
+	}
+	return block, nil
+}
+
+func restoreSnapshotBlock(transaction *DbTransaction, block *Block) error {
+	queries := []string{`DELETE FROM "block_chain"`, `DELETE FROM "info_block"`, `DELETE FROM "rollback"`,
+		`DELETE FROM "rollback_tx"`}
+	for _, query := range queries {
+		if err := GetDB(transaction).Exec(query).Error; err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": query}).Error("cleaning tables for snapshot")
+			return err
+		}
+	}
+	if err := block.Create(transaction); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating block of snapshot")
+		return err
+	}
+	ib := &InfoBlock{
+		Hash:         block.Hash,
+		BlockID:      block.ID,
+		Time:         block.Time,
+		EcosystemID:  block.EcosystemID,
+		KeyID:        block.KeyID,
+		NodePosition: converter.Int64ToStr(block.NodePosition),
+		Sent:         1,
+	}
+	if len(block.Data) > 2 {
+		ib.CurrentVersion = converter.Int64ToStr(converter.BinToDec(block.Data[:2]))
+	}
+	err := ib.Create(transaction)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating info block of snapshot")
+	}
+	return err
+}
+
+// CommitSnapshotHash saves the hash of the snapshot of blockID block into snapshot_hash parameter.
+// The previous value is kept in the rollback table so it is restored by RollbackSnapshotHash.
+func CommitSnapshotHash(transaction *DbTransaction, blockID int64, hash []byte) error {
+	par := &SystemParameter{}
+	if err := GetDB(transaction).Where("name = ?", SnapshotHashParam).First(par).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting snapshot hash parameter")
+		return err
+	}
+	data, err := json.Marshal(map[string]string{`value`: par.Value, `prev_rb_id`: converter.Int64ToStr(par.RbID)})
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling rollback of snapshot hash")
+		return err
+	}
+	rollback := &Rollback{Data: string(data), BlockID: blockID}
+	if err = rollback.Create(transaction); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating rollback of snapshot hash")
+		return err
+	}
+	err = GetDB(transaction).Exec(`UPDATE "system_parameters" SET value = ?, rb_id = ? WHERE name = ?`,
+		FormatSnapshotHash(blockID, hash), rollback.RbID, SnapshotHashParam).Error
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating snapshot hash parameter")
+	}
+	return err
+}
+
+// RollbackSnapshotHash restores snapshot_hash parameter if it has been changed by blockID block
+func RollbackSnapshotHash(transaction *DbTransaction, blockID int64) error {
+	par := &SystemParameter{}
+	if err := GetDB(transaction).Where("name = ?", SnapshotHashParam).First(par).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting snapshot hash parameter")
+		return err
+	}
+	rollback := &Rollback{}
+	found, err := isFound(GetDB(transaction).Where("rb_id = ?", par.RbID).First(rollback))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting rollback of snapshot hash")
+		return err
+	}
+	if !found || rollback.BlockID != blockID {
+		return nil
+	}
+	var prev map[string]string
+	if err = json.Unmarshal([]byte(rollback.Data), &prev); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling rollback of snapshot hash")
+		return err
+	}
+	err = GetDB(transaction).Exec(`UPDATE "system_parameters" SET value = ?, rb_id = ? WHERE name = ?`,
+		prev[`value`], converter.StrToInt64(prev[`prev_rb_id`]), SnapshotHashParam).Error
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("restoring snapshot hash parameter")
+		return err
+	}
+	if err = GetDB(transaction).Delete(rollback).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("deleting rollback of snapshot hash")
+	}
+	return err
+}
+
+// GetSnapshotHash returns the block and the hash of the last committed snapshot, blockID is zero if
+// the snapshot hasn't been committed
+func GetSnapshotHash(transaction *DbTransaction) (int64, []byte, error) {
+	par := &SystemParameter{}
+	found, err := isFound(GetDB(transaction).Where("name = ?", SnapshotHashParam).First(par))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting snapshot hash parameter")
+		return 0, nil, err
+	}
+	if !found || len(par.Value) == 0 {
+		return 0, nil, nil
+	}
+	return ParseSnapshotHash(par.Value)
+}
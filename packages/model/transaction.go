@@ -1,16 +1,27 @@
 package model
 
 type Transaction struct {
-	Hash      []byte `gorm:"private_key;not null"`
-	Data      []byte `gorm:"not null"`
-	Used      int8   `gorm:"not null"`
-	HighRate  int8   `gorm:"not null"`
-	Type      int8   `gorm:"not null"`
-	KeyID  int64  `gorm:"not null"`
-	Counter   int8   `gorm:"not null"`
-	Sent      int8   `gorm:"not null"`
-	Verified  int8   `gorm:"not null;default:1"`
-}
+	Hash     []byte `gorm:"private_key;not null"`
+	Data     []byte `gorm:"not null"`
+	Used     int8   `gorm:"not null"`
+	HighRate int8   `gorm:"not null"`
+	Type     int8   `gorm:"not null"`
+	KeyID    int64  `gorm:"not null"`
+	Counter  int8   `gorm:"not null"`
+	Sent     int8   `gorm:"not null"`
+	Verified int8   `gorm:"not null;default:1"`
+	Time     int64  `gorm:"not null"`
+	Nonce    int64  `gorm:"not null"`
+	MaxSum   string `gorm:"not null"`
+	PayOver  string `gorm:"column:payover;not null"`
+	Size     int64  `gorm:"not null"`
+}
+
+// pendingOrder is the order of the pending transactions by priority, the transactions with the higher fee go first
+const pendingOrder = "payover desc, max_sum desc, time, hash"
+
+// pendingLockKey is the key of the advisory lock which serializes the changes of the mempool
+const pendingLockKey = 0x6d656d706f6f6c
 
 func GetAllTransactions(limit int) (*[]Transaction, error) {
 	transactions := new([]Transaction)
@@ -20,9 +31,10 @@ func GetAllTransactions(limit int) (*[]Transaction, error) {
 	return transactions, nil
 }
 
+// GetAllUnusedTransactions returns the pending transactions in the order of their priority
 func GetAllUnusedTransactions() (*[]Transaction, error) {
 	transactions := new([]Transaction)
-	if err := DBConn.Where("used = ?", "0").Find(&transactions).Error; err != nil {
+	if err := DBConn.Where("used = ?", "0").Order(pendingOrder).Find(&transactions).Error; err != nil {
 		return nil, err
 	}
 	return transactions, nil
@@ -57,8 +69,8 @@ func DeleteLoopedTransactions() (int64, error) {
 	return query.RowsAffected, query.Error
 }
 
-func DeleteTransactionByHash(hash []byte) (int64, error) {
-	query := DBConn.Exec("DELETE FROM transactions WHERE hash = ?", hash)
+func DeleteTransactionByHash(transaction *DbTransaction, hash []byte) (int64, error) {
+	query := GetDB(transaction).Exec("DELETE FROM transactions WHERE hash = ?", hash)
 	return query.RowsAffected, query.Error
 }
 
@@ -104,6 +116,57 @@ func (t *Transaction) GetVerified(transactionHash []byte) (bool, error) {
 	return isFound(DBConn.Where("hash = ? AND verified = 1", transactionHash).First(t))
 }
 
-func (t *Transaction) Create() error {
-	return DBConn.Create(t).Error
+func (t *Transaction) Create(transaction *DbTransaction) error {
+	return GetDB(transaction).Create(t).Error
+}
+
+// LockPending locks the pending transactions for the changes of the mempool until the end of the transaction
+func LockPending(transaction *DbTransaction) error {
+	return GetDB(transaction).Exec("SELECT pg_advisory_xact_lock(?)", pendingLockKey).Error
+}
+
+// GetPendingByNonce returns the pending transaction of the key with the nonce
+func (t *Transaction) GetPendingByNonce(transaction *DbTransaction, keyID, nonce int64) (bool, error) {
+	return isFound(GetDB(transaction).Where("key_id = ? AND nonce = ? AND used = 0", keyID, nonce).First(t))
+}
+
+// GetPendingTransactions returns the pending transactions in the order of their priority.
+// The transactions of all keys are returned if keyID is zero
+func GetPendingTransactions(keyID int64, limit int) ([]Transaction, error) {
+	transactions := make([]Transaction, 0)
+	query := DBConn.Where("used = 0")
+	if keyID != 0 {
+		query = query.Where("key_id = ?", keyID)
+	}
+	err := query.Order(pendingOrder).Limit(limit).Find(&transactions).Error
+	return transactions, err
+}
+
+// GetPendingCount returns the number of the pending transactions of the key
+func GetPendingCount(transaction *DbTransaction, keyID int64) (int64, error) {
+	var count int64
+	err := GetDB(transaction).Table("transactions").Where("key_id = ? AND used = 0", keyID).Count(&count).Error
+	return count, err
+}
+
+// GetPendingSize returns the number and the total size of the pending transactions
+func GetPendingSize(transaction *DbTransaction) (count int64, size int64, err error) {
+	row := GetDB(transaction).Raw("SELECT count(*), COALESCE(sum(size), 0) FROM transactions WHERE used = 0").Row()
+	err = row.Scan(&count, &size)
+	return
+}
+
+// GetExpiredTransactions returns the hashes of the pending transactions with the time before the specified time
+func GetExpiredTransactions(before int64) ([][]byte, error) {
+	hashes := make([][]byte, 0)
+	err := DBConn.Table("transactions").Where("used = 0 AND time < ?", before).Pluck("hash", &hashes).Error
+	return hashes, err
+}
+
+// GetEvictionCandidates returns the pending transactions in the reverse order of their priority
+func GetEvictionCandidates(transaction *DbTransaction, limit int) ([]Transaction, error) {
+	transactions := make([]Transaction, 0)
+	err := GetDB(transaction).Where("used = 0").Order("payover, max_sum, time desc, hash desc").Limit(limit).
+		Find(&transactions).Error
+	return transactions, err
 }
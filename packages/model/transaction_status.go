@@ -30,6 +30,6 @@ func (ts *TransactionStatus) UpdateBlockMsg(transaction *DbTransaction, newBlock
 		map[string]interface{}{"block_id": newBlockID, "error": msg}).Error
 }
 
-func (ts *TransactionStatus) SetError(errorText string, transactionHash []byte) error {
-	return DBConn.Model(&TransactionStatus{}).Where("hash = ?", transactionHash).Update("error", errorText).Error
+func (ts *TransactionStatus) SetError(transaction *DbTransaction, errorText string, transactionHash []byte) error {
+	return GetDB(transaction).Model(&TransactionStatus{}).Where("hash = ?", transactionHash).Update("error", errorText).Error
 }
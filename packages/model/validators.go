@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+// Validator is the statistics of the full node which generates the blocks.
+// ID is the key of the full node
+type Validator struct {
+	ID           int64  `gorm:"primary_key;not null" json:"key_id"`
+	Produced     int64  `gorm:"not null" json:"produced"`
+	Missed       int64  `gorm:"not null" json:"missed"`
+	DoubleSigned int64  `gorm:"not null" json:"double_signed"`
+	Suspended    int64  `gorm:"not null" json:"suspended"`
+	Node         string `gorm:"not null" json:"-"`
+	RbID         int64  `gorm:"not null" json:"-"`
+}
+
+func (Validator) TableName() string {
+	return "validators"
+}
+
+func (v *Validator) Get(transaction *DbTransaction, keyID int64) (bool, error) {
+	return isFound(GetDB(transaction).Where("id = ?", keyID).First(v))
+}
+
+// GetValidators returns the statistics of all full nodes
+func GetValidators() ([]Validator, error) {
+	validators := make([]Validator, 0)
+	err := DBConn.Order("id").Find(&validators).Error
+	return validators, err
+}
+
+// UpdateValidatorSlots adds delta to the produced slots of the producer and to the missed slots of the skipped nodes.
+// The statistics which become empty after the rollback are removed
+func UpdateValidatorSlots(transaction *DbTransaction, producer int64, skipped []int64, delta int64) error {
+	update := func(keyID, produced, missed int64) error {
+		return GetDB(transaction).Exec(`INSERT INTO "validators" ("id", "produced", "missed") VALUES (?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET produced = validators.produced + EXCLUDED.produced,
+			missed = validators.missed + EXCLUDED.missed`, keyID, produced, missed).Error
+	}
+	if err := update(producer, delta, 0); err != nil {
+		return err
+	}
+	for _, keyID := range skipped {
+		if err := update(keyID, 0, delta); err != nil {
+			return err
+		}
+	}
+	if delta > 0 {
+		return nil
+	}
+	return GetDB(transaction).Exec(`DELETE FROM "validators" WHERE id IN (?) AND produced = 0 AND missed = 0
+		AND double_signed = 0 AND suspended = 0 AND rb_id = 0`, append(skipped, producer)).Error
+}
+
+// IsValidatorEvidence returns true if double signing of the block by the node has been registered
+func IsValidatorEvidence(transaction *DbTransaction, keyID, blockID int64) (bool, error) {
+	var count int64
+	err := GetDB(transaction).Table("validator_evidence").Where("key_id = ? AND block_id = ?", keyID, blockID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// DoubleSign is the evidence that the full node has signed two different blocks with the same block id.
+// It is found by the node and can be sent in ReportDoubleSign contract
+type DoubleSign struct {
+	KeyID    int64  `gorm:"primary_key;not null" json:"key_id"`
+	BlockID  int64  `gorm:"primary_key;not null" json:"block_id"`
+	ForSign1 string `gorm:"not null" json:"for_sign1"`
+	Sign1    []byte `gorm:"not null" json:"sign1"`
+	ForSign2 string `gorm:"not null" json:"for_sign2"`
+	Sign2    []byte `gorm:"not null" json:"sign2"`
+	Time     int64  `gorm:"not null" json:"time"`
+}
+
+func (DoubleSign) TableName() string {
+	return "double_signs"
+}
+
+// Create saves the evidence, the next evidences for the same block are ignored
+func (ds *DoubleSign) Create() error {
+	ds.Time = time.Now().Unix()
+	return DBConn.Exec(`INSERT INTO "double_signs" ("key_id", "block_id", "for_sign1", "sign1", "for_sign2", "sign2", "time")
+		VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+		ds.KeyID, ds.BlockID, ds.ForSign1, ds.Sign1, ds.ForSign2, ds.Sign2, ds.Time).Error
+}
+
+// GetDoubleSigns returns the evidences of the double signing which have been found by the node
+func GetDoubleSigns() ([]DoubleSign, error) {
+	signs := make([]DoubleSign, 0)
+	err := DBConn.Order("block_id, key_id").Find(&signs).Error
+	return signs, err
+}
@@ -0,0 +1,100 @@
+package model
+
+import "time"
+
+// Vote is the signed vote of the full node for the block in the round of voting
+type Vote struct {
+	BlockID   int64  `gorm:"primary_key;not null" json:"block_id"`
+	Round     int32  `gorm:"primary_key;not null" json:"round"`
+	Type      int8   `gorm:"primary_key;not null" json:"type"`
+	KeyID     int64  `gorm:"primary_key;not null" json:"key_id"`
+	Hash      []byte `gorm:"not null" json:"hash"`
+	Signature []byte `gorm:"not null" json:"signature"`
+}
+
+func (Vote) TableName() string {
+	return "votes"
+}
+
+// Create saves the vote, the next votes of the node of the same type for the block in the round are ignored
+func (v *Vote) Create() error {
+	return DBConn.Exec(`INSERT INTO "votes" ("block_id", "round", "type", "key_id", "hash", "signature")
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`, v.BlockID, v.Round, v.Type, v.KeyID, v.Hash, v.Signature).Error
+}
+
+func (v *Vote) Get(blockID int64, round int32, voteType int8, keyID int64) (bool, error) {
+	return isFound(DBConn.Where("block_id = ? AND round = ? AND type = ? AND key_id = ?",
+		blockID, round, voteType, keyID).First(v))
+}
+
+// GetLast returns the vote of the type of the node for the block in the last round
+func (v *Vote) GetLast(blockID int64, voteType int8, keyID int64) (bool, error) {
+	return isFound(DBConn.Where("block_id = ? AND type = ? AND key_id = ?", blockID, voteType, keyID).
+		Order("round desc").First(v))
+}
+
+// GetVotes returns the votes for the blocks starting with fromID
+func GetVotes(fromID int64, limit int) ([]Vote, error) {
+	votes := make([]Vote, 0)
+	err := DBConn.Where("block_id >= ?", fromID).Order("block_id, round desc, type, key_id").Limit(limit).Find(&votes).Error
+	return votes, err
+}
+
+// GetVoteKeys returns the nodes which have voted for the hash of the block in the round
+func GetVoteKeys(blockID int64, round int32, voteType int8, hash []byte) ([]int64, error) {
+	keys := make([]int64, 0)
+	err := DBConn.Model(&Vote{}).Where("block_id = ? AND round = ? AND type = ? AND hash = ?",
+		blockID, round, voteType, hash).Pluck("key_id", &keys).Error
+	return keys, err
+}
+
+// GetRoundKeys returns the nodes which have voted for the block in every round
+func GetRoundKeys(blockID int64) (map[int32][]int64, error) {
+	votes := make([]Vote, 0)
+	if err := DBConn.Select("DISTINCT round, key_id").Where("block_id = ?", blockID).Find(&votes).Error; err != nil {
+		return nil, err
+	}
+	rounds := make(map[int32][]int64)
+	for _, vote := range votes {
+		rounds[vote.Round] = append(rounds[vote.Round], vote.KeyID)
+	}
+	return rounds, nil
+}
+
+// DeleteVotes removes the votes for the blocks before blockID
+func DeleteVotes(blockID int64) error {
+	return DBConn.Exec(`DELETE FROM "votes" WHERE block_id < ?`, blockID).Error
+}
+
+// FinalizedBlock is the block which has got the commit votes of more than 2/3 of the full nodes.
+// The finalized block and all previous blocks can't be rolled back
+type FinalizedBlock struct {
+	BlockID int64  `gorm:"primary_key;not null"`
+	Hash    []byte `gorm:"not null"`
+	Time    int64  `gorm:"not null"`
+}
+
+func (FinalizedBlock) TableName() string {
+	return "finalized_blocks"
+}
+
+// Create saves the finalized block
+func (fb *FinalizedBlock) Create() error {
+	fb.Time = time.Now().Unix()
+	return DBConn.Exec(`INSERT INTO "finalized_blocks" ("block_id", "hash", "time") VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING`, fb.BlockID, fb.Hash, fb.Time).Error
+}
+
+// GetLast returns the last finalized block
+func (fb *FinalizedBlock) GetLast(transaction *DbTransaction) (bool, error) {
+	return isFound(GetDB(transaction).Order("block_id desc").First(fb))
+}
+
+// GetFinalizedBlockID returns the identifier of the last finalized block
+func GetFinalizedBlockID(transaction *DbTransaction) (int64, error) {
+	fb := &FinalizedBlock{}
+	if _, err := fb.GetLast(transaction); err != nil {
+		return 0, err
+	}
+	return fb.BlockID, nil
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/crypto"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
 	"github.com/AplaProject/go-apla/packages/smart"
 	"github.com/AplaProject/go-apla/packages/templatev2"
 	"github.com/AplaProject/go-apla/packages/utils"
@@ -200,6 +201,9 @@ type Parser struct {
 	DbTransaction    *model.DbTransaction
 
 	AllPkeys map[string]string
+
+	simulation *SimulateResult  // it is not nil when the contract is simulated
+	debugger   *script.Debugger // it is not nil when the simulated contract is traced or debugged
 }
 
 func (p Parser) GetLogger() *log.Entry {
@@ -383,7 +387,7 @@ func (p *Parser) BlockError(err error) {
 	}
 	p.DeleteQueueTx(p.TxHash)
 	ts := &model.TransactionStatus{}
-	ts.SetError(errText, p.TxHash)
+	ts.SetError(nil, errText, p.TxHash)
 }
 
 // AccessRights checks the access right by executing the condition value
@@ -61,6 +61,10 @@ func GetBlocks(blockID int64, host string, rollbackBlocks string) error {
 			log.WithFields(log.Fields{"type": consts.BlockIsFirst}).Error("block id is smaller than 2")
 			return utils.ErrInfo(errors.New("block_id < 2"))
 		}
+		// the blocks starting with blockID are replaced
+		if err := checkNotFinalized(nil, blockID); err != nil {
+			return err
+		}
 		// if the limit of blocks received from the node was exaggerated
 		if count > int64(rollback) {
 			log.WithFields(log.Fields{"count": count, "max_count": int64(rollback)}).Error("limit of received from the node was exaggerated")
@@ -75,7 +79,7 @@ func GetBlocks(blockID int64, host string, rollbackBlocks string) error {
 
 		block, err := ProcessBlockWherePrevFromBlockchainTable(binaryBlock)
 		if err != nil {
-			return utils.ErrInfo(err)
+			return err
 		}
 
 		if badBlocks[block.Header.BlockID] == string(converter.BinToHex(block.Header.Sign)) {
@@ -107,6 +111,9 @@ func GetBlocks(blockID int64, host string, rollbackBlocks string) error {
 		// check the signature
 		_, okSignErr := utils.CheckSign([][]byte{nodePublicKey}, forSign, block.Header.Sign, true)
 		if okSignErr == nil {
+			if err := block.checkDoubleSign(forSign); err != nil {
+				return utils.ErrInfo(err)
+			}
 			break
 		}
 	}
@@ -163,13 +170,17 @@ func GetBlocks(blockID int64, host string, rollbackBlocks string) error {
 
 		if err := block.CheckBlock(); err != nil {
 			dbTransaction.Rollback()
-			return utils.ErrInfo(err)
+			return err
 		}
 
 		if err := block.playBlock(dbTransaction); err != nil {
 			dbTransaction.Rollback()
 			return utils.ErrInfo(err)
 		}
+		if err := block.commitSnapshotHash(dbTransaction); err != nil {
+			dbTransaction.Rollback()
+			return err
+		}
 		prevBlocks[block.Header.BlockID] = block
 
 		// for last block we should update block info
@@ -199,6 +210,12 @@ func GetBlocks(blockID int64, host string, rollbackBlocks string) error {
 		}
 	}
 
-	err = dbTransaction.Commit()
-	return err
+	if err = dbTransaction.Commit(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing db transaction")
+		return err
+	}
+	for i := len(blocks) - 1; i >= 0; i-- {
+		notifyBlock(blocks[i])
+	}
+	return nil
 }
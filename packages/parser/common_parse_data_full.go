@@ -19,6 +19,7 @@ package parser
 import (
 	"bytes"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -45,6 +46,23 @@ type Block struct {
 	MrklRoot   []byte
 	BinData    []byte
 	Parsers    []*Parser
+	txNotices  []TxNotice
+}
+
+// InvalidBlockError is returned if the block breaks the rules of the blockchain.
+// Unlike the other errors it proves that the sender of the block is faulty
+type InvalidBlockError struct {
+	Err error
+}
+
+func (e *InvalidBlockError) Error() string {
+	return e.Err.Error()
+}
+
+// IsInvalidBlock returns true if the error proves that the block is invalid
+func IsInvalidBlock(err error) bool {
+	_, ok := err.(*InvalidBlockError)
+	return ok
 }
 
 func (b Block) GetLogger() *log.Entry {
@@ -94,12 +112,21 @@ func (block *Block) PlayBlockSafe() error {
 		return err
 	}
 
+	if err := block.commitSnapshotHash(dbTransaction); err != nil {
+		dbTransaction.Rollback()
+		return err
+	}
+
 	if err := InsertIntoBlockchain(dbTransaction, block); err != nil {
 		dbTransaction.Rollback()
 		return err
 	}
 
-	dbTransaction.Commit()
+	if err := dbTransaction.Commit(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("committing db transaction")
+		return err
+	}
+	notifyBlock(block)
 	return nil
 }
 
@@ -130,18 +157,18 @@ func ProcessBlockWherePrevFromMemory(data []byte) (*Block, error) {
 func ProcessBlockWherePrevFromBlockchainTable(data []byte) (*Block, error) {
 	if int64(len(data)) > syspar.GetMaxBlockSize() {
 		log.WithFields(log.Fields{"size": len(data), "max_size": syspar.GetMaxBlockSize(), "type": consts.ParameterExceeded}).Error("binary block size exceeds max block size")
-		return nil, utils.ErrInfo(fmt.Errorf(`len(binaryBlock) > variables.Int64["max_block_size"]`))
+		return nil, &InvalidBlockError{utils.ErrInfo(fmt.Errorf(`len(binaryBlock) > variables.Int64["max_block_size"]`))}
 	}
 
 	buf := bytes.NewBuffer(data)
 	if buf.Len() == 0 {
 		log.WithFields(log.Fields{"type": consts.EmptyObject}).Error("buffer is empty")
-		return nil, fmt.Errorf("empty buffer")
+		return nil, &InvalidBlockError{fmt.Errorf("empty buffer")}
 	}
 
 	block, err := parseBlock(buf)
 	if err != nil {
-		return nil, err
+		return nil, &InvalidBlockError{err}
 	}
 	block.BinData = data
 
@@ -346,6 +373,20 @@ func IsContractTransaction(txType int) bool {
 	return txType > 127
 }
 
+// isNonceFork returns true if the nonce of the transactions is activated for the block of the parser
+// or for the next block if the transaction is parsed out of the block
+func (p *Parser) isNonceFork() (bool, error) {
+	if p.BlockData != nil {
+		return syspar.IsNonceFork(p.BlockData.BlockID), nil
+	}
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return false, err
+	}
+	return syspar.IsNonceFork(infoBlock.BlockID + 1), nil
+}
+
 func parseContractTransaction(p *Parser, buf *bytes.Buffer) error {
 	smartTx := tx.SmartContract{}
 	if err := msgpack.Unmarshal(buf.Bytes(), &smartTx); err != nil {
@@ -363,7 +404,15 @@ func parseContractTransaction(p *Parser, buf *bytes.Buffer) error {
 		log.WithFields(log.Fields{"contract_type": smartTx.Type, "type": consts.NotFound}).Error("unknown contract")
 		return fmt.Errorf(`unknown contract %d`, smartTx.Type)
 	}
-	forsign := smartTx.ForSign()
+	nonceFork, err := p.isNonceFork()
+	if err != nil {
+		return err
+	}
+	if smartTx.Nonce != 0 && !nonceFork {
+		log.WithFields(log.Fields{"tx_hash": p.TxHash, "type": consts.InvalidObject}).Error("nonce is not activated")
+		return fmt.Errorf(`nonce is not activated`)
+	}
+	forsign := smartTx.ForSign(nonceFork)
 
 	p.TxContract = contract
 	p.TxHeader = &smartTx.Header
@@ -402,6 +451,22 @@ func parseContractTransaction(p *Parser, buf *bytes.Buffer) error {
 					return err
 				}
 				v = s
+			case `map[string]interface {}`:
+				var s string
+				if err := converter.BinUnmarshal(&input, &s); err != nil {
+					log.WithFields(log.Fields{"error": err, "type": consts.UnmarshallingError}).Error("bin unmarshalling map")
+					return err
+				}
+				imap := make(map[string]interface{})
+				if len(s) > 0 {
+					if err := json.Unmarshal([]byte(s), &imap); err != nil {
+						log.WithFields(log.Fields{"error": err, "type": consts.JSONUnmarshallError}).Error("unmarshalling map field")
+						return err
+					}
+				}
+				isforv = true
+				forv = s
+				v = imap
 			case `[]uint8`:
 				var b []byte
 				if err := converter.BinUnmarshal(&input, &b); err != nil {
@@ -542,6 +607,15 @@ func checkTransaction(p *Parser, checkTime int64, checkForDupTr bool) error {
 }
 
 func CheckTransaction(data []byte) (*tx.Header, error) {
+	p, err := parseAndCheckTransaction(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.TxHeader, nil
+}
+
+// parseAndCheckTransaction returns the parser of the transaction which has been checked
+func parseAndCheckTransaction(data []byte) (*Parser, error) {
 	trBuff := bytes.NewBuffer(data)
 	p, err := ParseTransaction(trBuff)
 	if err != nil {
@@ -553,7 +627,7 @@ func CheckTransaction(data []byte) (*tx.Header, error) {
 		return nil, err
 	}
 
-	return p.TxHeader, nil
+	return p, nil
 }
 
 func (block *Block) readPreviousBlockFromMemory() error {
@@ -608,11 +682,16 @@ func playTransaction(p *Parser) (string, error) {
 }
 
 func (block *Block) playBlock(dbTransaction *model.DbTransaction) error {
+	defer blockPlayTime.ObserveSince(time.Now())
 	logger := block.GetLogger()
 	if _, err := model.DeleteUsedTransactions(dbTransaction); err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("delete used transactions")
 		return err
 	}
+	block.txNotices = make([]TxNotice, 0, len(block.Parsers))
+	if err := block.updateValidatorSlots(dbTransaction, 1); err != nil {
+		return err
+	}
 
 	for _, p := range block.Parsers {
 		p.DbTransaction = dbTransaction
@@ -622,6 +701,7 @@ func (block *Block) playBlock(dbTransaction *model.DbTransaction) error {
 			// skip this transaction
 			model.MarkTransactionUsed(nil, p.TxHash)
 			p.processBadTransaction(p.TxHash, err.Error())
+			block.addTxNotice(p.TxHash, ``, err.Error())
 			continue
 		}
 
@@ -639,6 +719,7 @@ func (block *Block) playBlock(dbTransaction *model.DbTransaction) error {
 		if err := InsertInLogTx(p.DbTransaction, p.TxFullData, p.TxTime); err != nil {
 			return utils.ErrInfo(err)
 		}
+		block.addTxNotice(p.TxHash, msg, ``)
 	}
 	return nil
 }
@@ -648,7 +729,7 @@ func (block *Block) CheckBlock() error {
 	// exclude blocks from future
 	if block.Header.Time > time.Now().Unix() {
 		logger.WithFields(log.Fields{"type": consts.ParameterExceeded}).Error("block time is larger than now")
-		return utils.ErrInfo(fmt.Errorf("incorrect block time - block.Header.Time > time.Now().Unix()"))
+		return &InvalidBlockError{utils.ErrInfo(fmt.Errorf("incorrect block time - block.Header.Time > time.Now().Unix()"))}
 	}
 	if block.PrevHeader == nil || block.PrevHeader.BlockID != block.Header.BlockID-1 {
 		if err := block.readPreviousBlockFromBlockchainTable(); err != nil {
@@ -660,7 +741,7 @@ func (block *Block) CheckBlock() error {
 	if block.PrevHeader != nil {
 		if block.Header.BlockID != block.PrevHeader.BlockID+1 {
 			logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("block id is larger then previous more than on 1")
-			return utils.ErrInfo(fmt.Errorf("incorrect block_id %d != %d +1", block.Header.BlockID, block.PrevHeader.BlockID))
+			return &InvalidBlockError{utils.ErrInfo(fmt.Errorf("incorrect block_id %d != %d +1", block.Header.BlockID, block.PrevHeader.BlockID))}
 		}
 		// check time interval between blocks
 		sleepTime, err := syspar.GetSleepTimeByPosition(block.Header.NodePosition, block.PrevHeader.NodePosition)
@@ -674,7 +755,7 @@ func (block *Block) CheckBlock() error {
 			errTime = 0
 		}
 		if block.PrevHeader.Time+sleepTime-block.Header.Time > errTime {
-			return utils.ErrInfo(fmt.Errorf("incorrect block time %d + %d - %d > %d", block.PrevHeader.Time, sleepTime, block.Header.Time, errTime))
+			return &InvalidBlockError{utils.ErrInfo(fmt.Errorf("incorrect block time %d + %d - %d > %d", block.PrevHeader.Time, sleepTime, block.Header.Time, errTime))}
 		}
 	}
 
@@ -686,14 +767,14 @@ func (block *Block) CheckBlock() error {
 		// check for duplicate transactions
 		if _, ok := txHashes[hexHash]; ok {
 			logger.WithFields(log.Fields{"tx_hash": hexHash, "type": consts.DuplicateObject}).Error("duplicate transaction")
-			return utils.ErrInfo(fmt.Errorf("duplicate transaction %s", hexHash))
+			return &InvalidBlockError{utils.ErrInfo(fmt.Errorf("duplicate transaction %s", hexHash))}
 		}
 		txHashes[hexHash] = struct{}{}
 
 		// check for max transaction per user in one block
 		txCounter[p.TxKeyID]++
 		if txCounter[p.TxKeyID] > syspar.GetMaxBlockUserTx() {
-			return utils.ErrInfo(fmt.Errorf("max_block_user_transactions"))
+			return &InvalidBlockError{utils.ErrInfo(fmt.Errorf("max_block_user_transactions"))}
 		}
 
 		if err := checkTransaction(p, block.Header.Time, false); err != nil {
@@ -708,7 +789,7 @@ func (block *Block) CheckBlock() error {
 	}
 	if !result {
 		logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("incorrect signature")
-		return fmt.Errorf("incorrect signature / p.PrevBlock.BlockId: %d", block.PrevHeader.BlockID)
+		return &InvalidBlockError{fmt.Errorf("incorrect signature / p.PrevBlock.BlockId: %d", block.PrevHeader.BlockID)}
 	}
 	return nil
 }
@@ -18,6 +18,7 @@ package parser
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -94,7 +95,27 @@ func RollbackTxFromBlock(data []byte) error {
 	return err
 }
 
+// ErrFinalized is returned on the attempt to roll back the finalized block
+var ErrFinalized = errors.New("finalized block can't be rolled back")
+
+// checkNotFinalized returns ErrFinalized if the block has been finalized by the votes of the full nodes
+func checkNotFinalized(transaction *model.DbTransaction, blockID int64) error {
+	finalizedID, err := model.GetFinalizedBlockID(transaction)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting finalized block")
+		return err
+	}
+	if blockID <= finalizedID {
+		log.WithFields(log.Fields{"type": consts.BlockError, "block_id": blockID, "finalized_id": finalizedID}).Error("rolling back finalized block")
+		return ErrFinalized
+	}
+	return nil
+}
+
 func doBlockRollback(transaction *model.DbTransaction, block *Block) error {
+	if err := checkNotFinalized(transaction, block.Header.BlockID); err != nil {
+		return err
+	}
 	// rollback transactions in reverse order
 	logger := block.GetLogger()
 	for i := len(block.Parsers) - 1; i >= 0; i-- {
@@ -155,5 +176,12 @@ func doBlockRollback(transaction *model.DbTransaction, block *Block) error {
 		}
 	}
 
+	if err := model.RollbackSnapshotHash(transaction, block.Header.BlockID); err != nil {
+		return err
+	}
+	if err := block.updateValidatorSlots(transaction, -1); err != nil {
+		return err
+	}
+	rollbackBlocks.Inc()
 	return nil
 }
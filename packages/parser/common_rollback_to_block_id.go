@@ -30,6 +30,9 @@ import (
 // RollbackToBlockID rollbacks blocks till blockID
 func (p *Parser) RollbackToBlockID(blockID int64) error {
 	logger := p.GetLogger()
+	if err := checkNotFinalized(nil, blockID+1); err != nil {
+		return err
+	}
 	_, err := model.MarkVerifiedAndNotUsedTransactionsUnverified()
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("marking verified and not used transactions unverified")
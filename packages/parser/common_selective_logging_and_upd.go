@@ -180,6 +180,7 @@ func (p *Parser) selectiveLoggingAndUpd(fields []string, ivalues []interface{},
 			return 0, tableID, err
 		}
 		tableID = logData[p.AllPkeys[table]]
+		p.addChange(RowUpdate, table, tableID, fields, values)
 	} else {
 		isID := false
 		addSQLIns0 := ""
@@ -240,6 +241,8 @@ func (p *Parser) selectiveLoggingAndUpd(fields []string, ivalues []interface{},
 		err = model.GetDB(p.DbTransaction).Exec(insertQuery).Error
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.DBError, "error": err, "query": insertQuery}).Error("executing insert query")
+		} else {
+			p.addChange(RowInsert, table, tableID, append(fields, whereFields...), append(values, whereValues...))
 		}
 	}
 	if err != nil {
@@ -20,6 +20,7 @@ import (
 	"errors"
 
 	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/mempool"
 	"github.com/AplaProject/go-apla/packages/model"
 	"github.com/AplaProject/go-apla/packages/utils"
 
@@ -32,11 +33,12 @@ func (p *Parser) TxParser(hash, binaryTx []byte, myTx bool) error {
 	logger := p.GetLogger()
 	txType, keyID := GetTxTypeAndUserID(binaryTx)
 
-	header, err := CheckTransaction(binaryTx)
+	txParser, err := parseAndCheckTransaction(binaryTx)
 	if err != nil {
 		p.processBadTransaction(hash, err.Error())
 		return err
 	}
+	header := txParser.TxHeader
 
 	if !( /*txType > 127 ||*/ consts.IsStruct(int(txType))) {
 		if header == nil {
@@ -60,7 +62,7 @@ func (p *Parser) TxParser(hash, binaryTx []byte, myTx bool) error {
 	}
 	counter := tx.Counter
 	counter++
-	_, err = model.DeleteTransactionByHash(hash)
+	_, err = model.DeleteTransactionByHash(nil, hash)
 	if err != nil {
 		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("deleting transaction by hash")
 		return utils.ErrInfo(err)
@@ -74,10 +76,18 @@ func (p *Parser) TxParser(hash, binaryTx []byte, myTx bool) error {
 		KeyID:    keyID,
 		Counter:  counter,
 		Verified: 1,
+		Time:     txParser.TxTime,
 	}
-	err = newTx.Create()
-	if err != nil {
-		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("creating new transaction")
+	if txParser.TxSmart != nil {
+		newTx.Nonce = txParser.TxSmart.Nonce
+		newTx.MaxSum = txParser.TxSmart.MaxSum
+		newTx.PayOver = txParser.TxSmart.PayOver
+	}
+	if err = mempool.Add(newTx); err != nil {
+		if err == mempool.ErrExpired || err == mempool.ErrKeyLimit || err == mempool.ErrPoolFull ||
+			err == mempool.ErrUnderpriced || err == mempool.ErrSent {
+			p.processBadTransaction(hash, err.Error())
+		}
 		return utils.ErrInfo(err)
 	}
 
@@ -111,7 +121,7 @@ func (p *Parser) processBadTransaction(hash []byte, errText string) error {
 	// -----
 	if qtx.FromGate == 0 {
 		m := &model.TransactionStatus{}
-		err = m.SetError(errText, hash)
+		err = m.SetError(nil, errText, hash)
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("setting transaction status error")
 			return utils.ErrInfo(err)
@@ -0,0 +1,54 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"github.com/AplaProject/go-apla/packages/metrics"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+)
+
+// otherContracts is the label of the contracts which are not labelled by their names
+const otherContracts = `other`
+
+var (
+	blockPlayTime = metrics.NewHistogram(`apla_block_play_seconds`,
+		`The time of applying the block to the state`, metrics.DefBuckets)
+	rollbackBlocks = metrics.NewCounter(`apla_rollback_blocks_total`,
+		`The number of the blocks which have been rolled back`)
+	// the contract metrics are labelled by the names of the system and the active contracts only,
+	// the names of other contracts are set by the users and are not limited
+	contractCalls = metrics.NewCounter(`apla_contract_calls_total`,
+		`The number of the executions of the contracts in the blocks`, `contract`)
+	contractFuel = metrics.NewCounter(`apla_contract_fuel_total`,
+		`The fuel which has been spent by the contracts in the blocks`, `contract`)
+	contractErrors = metrics.NewCounter(`apla_contract_errors_total`,
+		`The number of the failed executions of the contracts in the blocks`, `contract`)
+)
+
+// contractLabel returns the label of the contract for the metrics. The contracts of the first ecosystem
+// and the activated contracts are labelled by their names, all other contracts are labelled as other
+func contractLabel(contract *smart.Contract) string {
+	if contract == nil || contract.Block == nil {
+		return otherContracts
+	}
+	info, ok := contract.Block.Info.(*script.ContractInfo)
+	if !ok || info.Owner == nil || (info.Owner.StateID != 1 && !info.Owner.Active) {
+		return otherContracts
+	}
+	return info.Name
+}
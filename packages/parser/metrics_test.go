@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+)
+
+func TestContractLabel(t *testing.T) {
+	contract := func(name string, owner *script.OwnerInfo) *smart.Contract {
+		return &smart.Contract{Block: &script.Block{Info: &script.ContractInfo{Name: name, Owner: owner}}}
+	}
+	cases := []struct {
+		contract *smart.Contract
+		label    string
+	}{
+		{contract(`@1MoneyTransfer`, &script.OwnerInfo{StateID: 1}), `@1MoneyTransfer`},
+		{contract(`@2Active`, &script.OwnerInfo{StateID: 2, Active: true}), `@2Active`},
+		{contract(`@2Custom`, &script.OwnerInfo{StateID: 2}), otherContracts},
+		{contract(`@2NoOwner`, nil), otherContracts},
+		{nil, otherContracts},
+	}
+	for i, c := range cases {
+		if label := contractLabel(c.contract); label != c.label {
+			t.Errorf("%d: label %s instead of %s", i, label, c.label)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"sync"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TxNotice contains the status of the transaction which has been processed in the block
+type TxNotice struct {
+	Hash   []byte
+	Result string
+	Error  string
+}
+
+// TableNotice contains the information about the changed row of the table
+type TableNotice struct {
+	Table  string
+	ID     string
+	TxHash []byte
+}
+
+// BlockNotice contains the information about the applied block
+type BlockNotice struct {
+	BlockID     int64
+	Hash        []byte
+	Time        int64
+	EcosystemID int64
+	KeyID       int64
+	Txs         []TxNotice
+	Tables      []TableNotice
+}
+
+var (
+	blockHandlers     []func(*BlockNotice)
+	blockHandlersLock sync.RWMutex
+)
+
+// AddBlockHandler registers the function which will be called after each applied block
+func AddBlockHandler(handler func(*BlockNotice)) {
+	blockHandlersLock.Lock()
+	defer blockHandlersLock.Unlock()
+	blockHandlers = append(blockHandlers, handler)
+}
+
+func (block *Block) addTxNotice(hash []byte, result, errText string) {
+	block.txNotices = append(block.txNotices, TxNotice{Hash: hash, Result: result, Error: errText})
+}
+
+// notifyBlock sends the notice about the committed block to all registered handlers
+func notifyBlock(block *Block) {
+	blockHandlersLock.RLock()
+	defer blockHandlersLock.RUnlock()
+	if len(blockHandlers) == 0 {
+		return
+	}
+	notice := &BlockNotice{
+		BlockID:     block.Header.BlockID,
+		Hash:        block.Header.Hash,
+		Time:        block.Header.Time,
+		EcosystemID: block.Header.EcosystemID,
+		KeyID:       block.Header.KeyID,
+		Txs:         block.txNotices,
+	}
+	rollbackTx := &model.RollbackTx{}
+	changes, err := rollbackTx.GetBlockRollbackTransactions(nil, block.Header.BlockID)
+	if err != nil {
+		block.GetLogger().WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting rollback transactions of the block")
+	}
+	for _, item := range changes {
+		notice.Tables = append(notice.Tables, TableNotice{Table: item.NameTable, ID: item.TableID, TxHash: item.TxHash})
+	}
+	for _, handler := range blockHandlers {
+		handler(notice)
+	}
+}
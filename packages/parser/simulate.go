@@ -0,0 +1,128 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RowInsert is the action of the inserted row
+	RowInsert = `insert`
+	// RowUpdate is the action of the updated row
+	RowUpdate = `update`
+)
+
+// RowChange contains the row which has been inserted or updated by the contract
+type RowChange struct {
+	Action string
+	Table  string
+	ID     string
+	Values map[string]string
+}
+
+// SimulateResult contains the result of the simulated call of the contract
+type SimulateResult struct {
+	Fuel    decimal.Decimal
+	Price   decimal.Decimal
+	Result  string
+	Error   string
+	Warning string
+	Info    string
+	Changes []RowChange
+}
+
+// addChange appends the changed row to the result of the simulation
+func (p *Parser) addChange(action, table, id string, fields, values []string) {
+	if p.simulation == nil {
+		return
+	}
+	change := RowChange{Action: action, Table: table, ID: id, Values: make(map[string]string)}
+	for i, field := range fields {
+		if i < len(values) {
+			change.Values[strings.TrimLeft(strings.TrimPrefix(field, `timestamp `), `+-`)] = values[i]
+		}
+	}
+	if len(change.ID) == 0 {
+		change.ID = change.Values[`id`]
+	}
+	p.simulation.Changes = append(p.simulation.Changes, change)
+}
+
+// Simulate runs the contract of the parsed transaction against the current state.
+// The signature is not checked and the fuel is not paid, but the price and the balance are checked. All changes are made inside
+// the database transaction which is always rolled back. The contracts in VM, the languages and the system parameters
+// in memory are not changed by the simulation. If debugger is not nil then
+// it traces the execution of the contract and pauses it on breakpoints.
+func (p *Parser) Simulate(debugger *script.Debugger) (*SimulateResult, error) {
+	logger := p.GetLogger()
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return nil, err
+	}
+	allKeys, err := getAllTables()
+	if err != nil {
+		return nil, err
+	}
+	dbTransaction, err := model.StartTransaction()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTransaction.Rollback()
+
+	p.DbTransaction = dbTransaction
+	p.BlockData = &utils.BlockData{BlockID: infoBlock.BlockID + 1, Time: time.Now().Unix(),
+		EcosystemID: infoBlock.EcosystemID, KeyID: infoBlock.KeyID}
+	p.AllPkeys = allKeys
+	p.simulation = &SimulateResult{Changes: make([]RowChange, 0)}
+	p.debugger = debugger
+	defer func() {
+		p.simulation = nil
+		p.debugger = nil
+	}()
+
+	result := p.simulation
+	if err = p.CallContract(smart.CallInit | smart.CallCondition | smart.CallAction); err != nil {
+		errText := err.Error()
+		switch {
+		case strings.HasPrefix(errText, `!`):
+			result.Warning = errText[1:]
+		case strings.HasPrefix(errText, `*`):
+			result.Info = errText[1:]
+		default:
+			result.Error = errText
+		}
+	} else if p.TxContract.Extend != nil {
+		if ret, ok := (*p.TxContract.Extend)[`result`]; ok {
+			result.Result = converter.InterfaceToStr(ret)
+		}
+	}
+	result.Fuel = p.TxUsedCost
+	return result, nil
+}
@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -45,28 +46,33 @@ import (
 
 var (
 	funcCallsDB = map[string]struct{}{
-		"DBInsert":       struct{}{},
-		"DBUpdate":       struct{}{},
-		"DBUpdateExt":    struct{}{},
-		"DBGetList":      struct{}{},
-		"DBGetTable":     struct{}{},
-		"DBSelect":       struct{}{},
-		"DBInt":          struct{}{},
-		"DBRowExt":       struct{}{},
-		"DBRow":          struct{}{},
-		"DBStringExt":    struct{}{},
-		"DBIntExt":       struct{}{},
-		"DBFreeRequest":  struct{}{},
-		"DBStringWhere":  struct{}{},
-		"DBIntWhere":     struct{}{},
-		"DBAmount":       struct{}{},
-		"UpdateContract": struct{}{},
-		"UpdateParam":    struct{}{},
-		"UpdateMenu":     struct{}{},
-		"UpdatePage":     struct{}{},
-		"DBInsertReport": struct{}{},
-		"UpdateSysParam": struct{}{},
-		"FindEcosystem":  struct{}{},
+		"DBInsert":           struct{}{},
+		"DBUpdate":           struct{}{},
+		"DBUpdateExt":        struct{}{},
+		"DBGetList":          struct{}{},
+		"DBGetTable":         struct{}{},
+		"DBSelect":           struct{}{},
+		"DBInt":              struct{}{},
+		"DBRowExt":           struct{}{},
+		"DBRow":              struct{}{},
+		"DBStringExt":        struct{}{},
+		"DBIntExt":           struct{}{},
+		"DBFreeRequest":      struct{}{},
+		"DBStringWhere":      struct{}{},
+		"DBIntWhere":         struct{}{},
+		"DBAmount":           struct{}{},
+		"UpdateContract":     struct{}{},
+		"UpdateParam":        struct{}{},
+		"UpdateMenu":         struct{}{},
+		"UpdatePage":         struct{}{},
+		"DBInsertReport":     struct{}{},
+		"UpdateSysParam":     struct{}{},
+		"FindEcosystem":      struct{}{},
+		"AddFullNode":        struct{}{},
+		"RemoveFullNode":     struct{}{},
+		"SuspendFullNode":    struct{}{},
+		"ResumeFullNode":     struct{}{},
+		"DoubleSignEvidence": struct{}{},
 	}
 	extendCost = map[string]int64{
 		"AddressToId":       10,
@@ -106,10 +112,13 @@ var (
 		"RollbackColumn":    50,
 		"PermColumn":        50,
 		"JSONToMap":         50,
+		"ValidatorStats":    10,
+		"ValidatorFaulty":   10,
+		"IsValidator":       10,
 	}
 )
 
-//SignRes contains the data of the signature
+// SignRes contains the data of the signature
 type SignRes struct {
 	Param string `json:"name"`
 	Text  string `json:"text"`
@@ -189,6 +198,14 @@ func init() {
 		"Eval":               Eval,
 		"Activate":           ActivateContract,
 		"JSONToMap":          JSONToMap,
+		"AddFullNode":        AddFullNode,
+		"RemoveFullNode":     RemoveFullNode,
+		"SuspendFullNode":    SuspendFullNode,
+		"ResumeFullNode":     ResumeFullNode,
+		"ValidatorStats":     ValidatorStats,
+		"ValidatorFaulty":    ValidatorFaulty,
+		"IsValidator":        IsValidator,
+		"DoubleSignEvidence": DoubleSignEvidence,
 		"check_signature":    CheckSignature, // system function
 	}, AutoPars: map[string]string{
 		`*parser.Parser`: `parser`,
@@ -288,27 +305,29 @@ func (p *Parser) CallContract(flags int) (err error) {
 		if len(wallet.PublicKey) > 0 {
 			public = wallet.PublicKey
 		}
-		if p.TxSmart.Type == 258 { // UpdFullNodes
-			node := syspar.GetNode(p.TxSmart.KeyID)
-			if node == nil {
-				logger.WithFields(log.Fields{"user_id": p.TxSmart.KeyID, "type": consts.NotFound}).Error("unknown node id")
-				return fmt.Errorf("unknown node id")
+		if p.simulation == nil {
+			if p.TxSmart.Type == 258 { // UpdFullNodes
+				node := syspar.GetNode(p.TxSmart.KeyID)
+				if node == nil {
+					logger.WithFields(log.Fields{"user_id": p.TxSmart.KeyID, "type": consts.NotFound}).Error("unknown node id")
+					return fmt.Errorf("unknown node id")
+				}
+				public = node.Public
+			}
+			if len(public) == 0 {
+				logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("empty public key")
+				return fmt.Errorf("empty public key")
+			}
+			p.PublicKeys = append(p.PublicKeys, public)
+			CheckSignResult, err := utils.CheckSign(p.PublicKeys, p.TxData[`forsign`].(string), p.TxSmart.BinSignatures, false)
+			if err != nil {
+				logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("checking tx data sign")
+				return err
+			}
+			if !CheckSignResult {
+				logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("incorrect sign")
+				return fmt.Errorf("incorrect sign")
 			}
-			public = node.Public
-		}
-		if len(public) == 0 {
-			logger.WithFields(log.Fields{"type": consts.EmptyObject}).Error("empty public key")
-			return fmt.Errorf("empty public key")
-		}
-		p.PublicKeys = append(p.PublicKeys, public)
-		CheckSignResult, err := utils.CheckSign(p.PublicKeys, p.TxData[`forsign`].(string), p.TxSmart.BinSignatures, false)
-		if err != nil {
-			logger.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("checking tx data sign")
-			return err
-		}
-		if !CheckSignResult {
-			logger.WithFields(log.Fields{"type": consts.InvalidObject}).Error("incorrect sign")
-			return fmt.Errorf("incorrect sign")
 		}
 		if p.TxSmart.EcosystemID > 0 {
 			if p.TxSmart.TokenEcosystem == 0 {
@@ -357,7 +376,7 @@ func (p *Parser) CallContract(flags int) (err error) {
 			}
 			if cprice := p.TxContract.GetFunc(`price`); cprice != nil {
 				var ret []interface{}
-				if ret, err = smart.Run(cprice, nil, p.TxContract.Extend); err != nil {
+				if ret, err = smart.RunDebug(cprice, nil, p.TxContract.Extend, p.debugger); err != nil {
 					return err
 				} else if len(ret) == 1 {
 					if _, ok := ret[0].(int64); !ok {
@@ -390,7 +409,7 @@ func (p *Parser) CallContract(flags int) (err error) {
 				continue
 			}
 			p.TxContract.Called = 1 << i
-			_, err = smart.Run(cfunc, nil, p.TxContract.Extend)
+			_, err = smart.RunDebug(cfunc, nil, p.TxContract.Extend, p.debugger)
 			if err != nil {
 				before -= price
 				break
@@ -399,8 +418,20 @@ func (p *Parser) CallContract(flags int) (err error) {
 	}
 	p.TxUsedCost = decimal.New(before-(*p.TxContract.Extend)[`txcost`].(int64), 0)
 	p.TxContract.TxPrice = price
+	if (flags&smart.CallAction) != 0 && flags&smart.CallRollback == 0 && p.simulation == nil {
+		label := contractLabel(p.TxContract)
+		contractCalls.Inc(label)
+		contractFuel.Add(float64(p.TxUsedCost.IntPart()), label)
+		if err != nil {
+			contractErrors.Inc(label)
+		}
+	}
 	if (flags&smart.CallAction) != 0 && p.TxSmart.EcosystemID > 0 {
 		apl := p.TxUsedCost.Mul(fuelRate)
+		if p.simulation != nil {
+			p.simulation.Price = apl
+			return
+		}
 		wltAmount, err := decimal.NewFromString(payWallet.Amount)
 		if err != nil {
 			logger.WithFields(log.Fields{"type": consts.ConvertionError, "error": err, "value": payWallet.Amount}).Error("converting pay wallet amount from string to decimal")
@@ -524,10 +555,13 @@ func DBUpdateExt(p *Parser, tblname string, column string, value interface{}, pa
 }
 
 // DBString returns the value of the field of the record with the specified id
-func DBString(tblname string, name string, id int64) (int64, string, error) {
+func DBString(p *Parser, tblname string, name string, id int64) (int64, string, error) {
 	if err := checkReport(tblname); err != nil {
 		return 0, ``, err
 	}
+	if err := p.checkColumnsRead(tblname, name, `id`); err != nil {
+		return 0, ``, err
+	}
 	cost, err := model.GetQueryTotalCost(`select `+converter.EscapeName(name)+` from `+converter.EscapeName(tblname)+` where id=?`, id)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting query total cost")
@@ -571,6 +605,9 @@ func DBInt(p *Parser, tblname string, name string, id int64) (int64, int64, erro
 	if err := checkReport(tblname); err != nil {
 		return 0, 0, err
 	}
+	if err := p.checkColumnsRead(tblname, name, `id`); err != nil {
+		return 0, 0, err
+	}
 	cost, err := model.GetQueryTotalCost(`select `+converter.EscapeName(name)+` from `+converter.EscapeName(tblname)+` where id=?`, id)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting query total cost")
@@ -603,6 +640,9 @@ func DBStringExt(p *Parser, tblname string, name string, id interface{}, idname
 	if err := checkReport(tblname); err != nil {
 		return 0, ``, err
 	}
+	if err := p.checkColumnsRead(tblname, name, idname); err != nil {
+		return 0, ``, err
+	}
 
 	isBytea := getBytea(tblname)
 	if isBytea[idname] {
@@ -664,10 +704,13 @@ func DBFreeRequest(p *Parser, tblname string /*name string,*/, id interface{}, i
 }
 
 // DBStringWhere returns the column value based on the 'where' condition and 'params' values for this condition
-func DBStringWhere(tblname string, name string, where string, params ...interface{}) (int64, string, error) {
+func DBStringWhere(p *Parser, tblname string, name string, where string, params ...interface{}) (int64, string, error) {
 	if err := checkReport(tblname); err != nil {
 		return 0, ``, err
 	}
+	if err := p.checkRawRead(tblname); err != nil {
+		return 0, ``, err
+	}
 
 	selectQuery := `select ` + converter.EscapeName(name) + ` from ` + converter.EscapeName(tblname) + ` where ` + strings.Replace(converter.Escape(where), `$`, `?`, -1)
 	qcost, err := model.GetQueryTotalCost(selectQuery, params...)
@@ -684,9 +727,9 @@ func DBStringWhere(tblname string, name string, where string, params ...interfac
 }
 
 // DBIntWhere returns the column value based on the 'where' condition and 'params' values for this condition
-func DBIntWhere(tblname string, name string, where string, params ...interface{}) (cost int64, ret int64, err error) {
+func DBIntWhere(p *Parser, tblname string, name string, where string, params ...interface{}) (cost int64, ret int64, err error) {
 	var val string
-	cost, val, err = DBStringWhere(tblname, name, where, params...)
+	cost, val, err = DBStringWhere(p, tblname, name, where, params...)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -854,8 +897,6 @@ func SysParamInt(name string) int64 {
 	return syspar.SysInt64(name)
 }
 
-
-
 // SysFuel returns the fuel rate
 func SysFuel(state int64) string {
 	return syspar.GetFuelRate(state)
@@ -936,12 +977,21 @@ func CheckSignature(i *map[string]interface{}, name string) error {
 	return nil
 }
 
-// Len returns the length of the slice
-func Len(in []interface{}) int64 {
-	if in == nil {
-		return 0
+// Len returns the length of the array, the map or the string
+func Len(in interface{}) int64 {
+	switch v := in.(type) {
+	case []interface{}:
+		return int64(len(v))
+	case map[string]interface{}:
+		return int64(len(v))
+	case []map[string]string:
+		return int64(len(v))
+	case map[string]string:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
 	}
-	return int64(len(in))
+	return 0
 }
 
 // LangRes returns the language resource
@@ -957,22 +1007,127 @@ func checkWhere(tblname string, where string, order string) (string, string, err
 	return strings.Replace(converter.Escape(where), `$`, `?`, -1), order, nil
 }
 
-// DBGetList returns a list of column values with the specified 'offset', 'limit', 'where'
-func DBGetList(tblname string, name string, offset, limit int64, order string,
+// isFilter returns true if the where condition is the structured filter
+func isFilter(where string) bool {
+	return strings.HasPrefix(strings.TrimSpace(where), `{`)
+}
+
+// splitTableName returns the ecosystem and the name of the table with the prefix of the ecosystem.
+// The ecosystem is zero for the system tables which haven't got the numeric prefix
+func splitTableName(tblname string) (int64, string) {
+	off := strings.IndexByte(tblname, '_')
+	if off <= 0 {
+		return 0, tblname
+	}
+	ecosystem, err := strconv.ParseInt(tblname[:off], 10, 64)
+	if err != nil || ecosystem <= 0 {
+		return 0, tblname
+	}
+	return ecosystem, tblname[off+1:]
+}
+
+// readQuery checks the columns, the filter and the order of the query against the read permissions of the table.
+// The system tables haven't got the read permissions so only the columns of the query are checked
+func (p *Parser) readQuery(tblname, columns string, filter model.Filter, order string) (*model.ReadQuery, error) {
+	ecosystem, name := splitTableName(tblname)
+	if ecosystem == 0 {
+		types, err := model.GetColumnTypes(name)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting column types")
+			return nil, err
+		}
+		if len(types) == 0 {
+			return nil, model.ErrTableNotFound
+		}
+		return model.BuildReadQuery(name, types, nil, strings.Split(columns, `,`), filter, order)
+	}
+	return model.NewReadQuery(ecosystem, name, strings.Split(columns, `,`), filter, order, p.EvalIf)
+}
+
+// checkRawRead checks the read permissions of the table for the query with the raw where condition.
+// Such query is allowed only if all columns of the table can be read
+func (p *Parser) checkRawRead(tblname string) error {
+	ecosystem, name := splitTableName(tblname)
+	if ecosystem == 0 {
+		// the system tables haven't got the read permissions
+		return nil
+	}
+	perm, err := model.GetReadPermissions(ecosystem, name)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting read permissions")
+		return err
+	}
+	if err = perm.CheckRaw(p.EvalIf); err != nil {
+		if _, ok := err.(*model.ColumnAccessError); ok {
+			return fmt.Errorf(`%s, use the structured filter`, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkColumnsRead checks the read permissions of the selected columns and the key column of the query
+// to the row of the table. All columns or the columns with the expressions require the read access to the whole table
+func (p *Parser) checkColumnsRead(tblname, columns, key string) error {
+	for _, column := range strings.Split(columns, `,`) {
+		if !isColumnName(strings.TrimSpace(column)) {
+			return p.checkRawRead(tblname)
+		}
+	}
+	_, err := p.readQuery(tblname, columns+`,`+key, nil, ``)
+	return err
+}
+
+// isColumnName returns true if the string is the plain name of the column
+func isColumnName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, ch := range name {
+		if !(ch >= '0' && ch <= '9') && ch != '_' && !(ch >= 'a' && ch <= 'z') && !(ch >= 'A' && ch <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// DBGetList returns a list of column values with the specified 'offset', 'limit', 'where'.
+// The where condition is either the structured filter or the raw condition with $ parameters
+func DBGetList(p *Parser, tblname string, name string, offset, limit int64, order string,
 	where string, params ...interface{}) (int64, []interface{}, error) {
 
 	if err := checkReport(tblname); err != nil {
 		return 0, nil, err
 	}
-
-	if len(order) > 0 {
-		order = ` order by ` + converter.EscapeName(order)
-	}
 	if limit <= 0 {
 		limit = -1
 	}
-	list, err := model.GetAll(`select `+converter.Escape(name)+` from `+converter.EscapeName(tblname)+` where `+
-		strings.Replace(converter.Escape(where), `$`, `?`, -1)+order+fmt.Sprintf(` offset %d `, offset), int(limit), params...)
+	var (
+		list []map[string]string
+		err  error
+	)
+	if isFilter(where) {
+		var (
+			filter model.Filter
+			query  *model.ReadQuery
+		)
+		if filter, err = model.ParseFilter(where); err != nil {
+			return 0, nil, err
+		}
+		if query, err = p.readQuery(tblname, name, filter, order); err != nil {
+			return 0, nil, err
+		}
+		list, err = model.GetAll(query.Select(offset, limit), int(limit), query.Args...)
+	} else {
+		if err = p.checkRawRead(tblname); err != nil {
+			return 0, nil, err
+		}
+		if len(order) > 0 {
+			order = ` order by ` + converter.EscapeName(order)
+		}
+		list, err = model.GetAll(`select `+converter.Escape(name)+` from `+converter.EscapeName(tblname)+` where `+
+			strings.Replace(converter.Escape(where), `$`, `?`, -1)+order+fmt.Sprintf(` offset %d `, offset), int(limit), params...)
+	}
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("get all")
 	}
@@ -983,33 +1138,53 @@ func DBGetList(tblname string, name string, offset, limit int64, order string,
 	return 0, result, err
 }
 
-// DBGetTable returns an array of values of the specified columns when there is selection of data 'offset', 'limit', 'where'
-func DBGetTable(tblname string, columns string, offset, limit int64, order string,
+// DBGetTable returns an array of values of the specified columns when there is selection of data 'offset', 'limit', 'where'.
+// The where condition is either the structured filter or the raw condition with $ parameters
+func DBGetTable(p *Parser, tblname string, columns string, offset, limit int64, order string,
 	where string, params ...interface{}) (int64, []interface{}, error) {
-	var err error
+	var (
+		list []map[string]string
+		err  error
+	)
 	if err = checkReport(tblname); err != nil {
 		return 0, nil, err
 	}
-
-	where, order, err = checkWhere(tblname, where, order)
 	if limit <= 0 {
 		limit = -1
 	}
-	cols := strings.Split(converter.Escape(columns), `,`)
-	list, err := model.GetAll(`select `+strings.Join(cols, `,`)+` from `+converter.EscapeName(tblname)+` where `+
-		where+order+fmt.Sprintf(` offset %d `, offset), int(limit), params...)
+	if isFilter(where) {
+		var (
+			filter model.Filter
+			query  *model.ReadQuery
+		)
+		if filter, err = model.ParseFilter(where); err != nil {
+			return 0, nil, err
+		}
+		if query, err = p.readQuery(tblname, columns, filter, order); err != nil {
+			return 0, nil, err
+		}
+		list, err = model.GetAll(query.Select(offset, limit), int(limit), query.Args...)
+	} else {
+		if err = p.checkRawRead(tblname); err != nil {
+			return 0, nil, err
+		}
+		where, order, err = checkWhere(tblname, where, order)
+		cols := strings.Split(converter.Escape(columns), `,`)
+		list, err = model.GetAll(`select `+strings.Join(cols, `,`)+` from `+converter.EscapeName(tblname)+` where `+
+			where+order+fmt.Sprintf(` offset %d `, offset), int(limit), params...)
+	}
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("get all")
+	}
 	result := make([]interface{}, len(list))
 	for i := 0; i < len(list); i++ {
-		//result[i] = make(map[string]interface{})
 		result[i] = reflect.ValueOf(list[i]).Interface()
-		/*		for _, key := range cols {
-				result[i][key] = reflect.ValueOf(list[i][key]).Interface()
-			}*/
 	}
 	return 0, result, err
 }
 
-// DBSelect returns an array of values of the specified columns when there is selection of data 'offset', 'limit', 'where'
+// DBSelect returns an array of values of the specified columns when there is selection of data 'offset', 'limit', 'where'.
+// The where condition is either the structured filter or the raw condition with $ parameters
 func DBSelect(p *Parser, tblname string, columns string, id int64, order string, offset, limit, ecosystem int64,
 	where string, params []interface{}) (int64, []interface{}, error) {
 
@@ -1026,7 +1201,6 @@ func DBSelect(p *Parser, tblname string, columns string, id int64, order string,
 	if len(order) == 0 {
 		order = `id`
 	}
-	where = strings.Replace(converter.Escape(where), `$`, `?`, -1)
 	if id != 0 {
 		where = fmt.Sprintf(`id='%d'`, id)
 		limit = 1
@@ -1043,8 +1217,25 @@ func DBSelect(p *Parser, tblname string, columns string, id int64, order string,
 	if tblname[0] < '1' || tblname[0] > '9' || !strings.Contains(tblname, `_`) {
 		tblname = fmt.Sprintf(`%d_%s`, ecosystem, tblname)
 	}
-	rows, err = model.DBConn.Table(tblname).Select(columns).Where(where, params...).Order(order).
-		Offset(offset).Limit(limit).Rows()
+	if isFilter(where) {
+		var (
+			filter model.Filter
+			query  *model.ReadQuery
+		)
+		if filter, err = model.ParseFilter(where); err != nil {
+			return 0, nil, err
+		}
+		if query, err = p.readQuery(tblname, columns, filter, order); err != nil {
+			return 0, nil, err
+		}
+		rows, err = model.DBConn.Raw(query.Select(offset, limit), query.Args...).Rows()
+	} else {
+		if err = p.checkRawRead(tblname); err != nil {
+			return 0, nil, err
+		}
+		rows, err = model.DBConn.Table(tblname).Select(columns).Where(strings.Replace(converter.Escape(where), `$`, `?`, -1),
+			params...).Order(order).Offset(offset).Limit(limit).Rows()
+	}
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("selecting rows from table")
 		return 0, nil, err
@@ -1088,6 +1279,9 @@ func DBRowExt(p *Parser, tblname string, columns string, id interface{}, idname
 	if err := checkReport(tblname); err != nil {
 		return 0, nil, err
 	}
+	if err := p.checkColumnsRead(tblname, columns, idname); err != nil {
+		return 0, nil, err
+	}
 
 	isBytea := getBytea(tblname)
 	if isBytea[idname] {
@@ -1119,6 +1313,9 @@ func DBRow(p *Parser, tblname string, columns string, id int64) (int64, map[stri
 	if err := checkReport(tblname); err != nil {
 		return 0, nil, err
 	}
+	if err := p.checkColumnsRead(tblname, columns, `id`); err != nil {
+		return 0, nil, err
+	}
 
 	query := `select ` + converter.Sanitize(columns, ` ,()*`) + ` from ` + converter.EscapeName(tblname) + ` where id=?`
 	cost, err := model.GetQueryTotalCost(query, id)
@@ -1163,6 +1360,12 @@ func UpdateSysParam(p *Parser, name, value, conditions string) (int64, error) {
 		fields = append(fields, "value")
 		values = append(values, value)
 	}
+	if name == syspar.VMCostTable && len(value) > 0 {
+		if _, err := script.ParseCostTable(value); err != nil {
+			log.WithFields(log.Fields{"type": consts.ParseError, "error": err}).Error("parsing cost table")
+			return 0, err
+		}
+	}
 	if len(conditions) > 0 {
 		if err := smart.CompileEval(conditions, 0); err != nil {
 			log.WithFields(log.Fields{"error": err, "conditions": conditions, "state_id": 0, "type": consts.EvalError}).Error("compiling eval")
@@ -1179,6 +1382,10 @@ func UpdateSysParam(p *Parser, name, value, conditions string) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if p.simulation != nil {
+		// the cache of the parameters is not changed by the simulation which is rolled back
+		return 0, nil
+	}
 	err = syspar.SysUpdate()
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating syspar")
@@ -1243,6 +1450,9 @@ func FindEcosystem(p *Parser, country string) (int64, int64, error) {
 
 // UpdateLang updates language resource
 func UpdateLang(p *Parser, name, trans string) {
+	if p.simulation != nil {
+		return
+	}
 	language.UpdateLang(int(p.TxEcosystemID), name, trans)
 }
 
@@ -1298,7 +1508,10 @@ func FlushContract(p *Parser, iroot interface{}, id int64, active bool) error {
 			root.Children[i].Info.(*script.ContractInfo).Owner.Active = active
 		}
 	}
-
+	if p.simulation != nil {
+		// the simulated contract is compiled but VM is not changed
+		return nil
+	}
 	smart.FlushBlock(root)
 	return nil
 }
@@ -1327,6 +1540,9 @@ func ActivateContract(p *Parser, tblid int64, state int64) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("ActivateContract can be only called from @1ActivateContract")
 		return fmt.Errorf(`ActivateContract can be only called from @1ActivateContract`)
 	}
+	if p.simulation != nil {
+		return nil
+	}
 	smart.ActivateContract(tblid, state, true)
 	return nil
 }
@@ -1349,9 +1565,10 @@ func CreateEcosystem(p *Parser, wallet int64, name string) (int64, error) {
 		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("executing ecosystem schema")
 		return 0, err
 	}
-	err = smart.LoadContract(p.DbTransaction, id)
-	if err != nil {
-		return 0, err
+	if p.simulation == nil {
+		if err = smart.LoadContract(p.DbTransaction, id); err != nil {
+			return 0, err
+		}
 	}
 	return converter.StrToInt64(id), err
 }
@@ -1395,6 +1612,39 @@ func RollbackEcosystem(p *Parser) error {
 	return ssToDel.Delete(p.DbTransaction)
 }
 
+// tablePermissions are the conditions of the table. The read condition of the table and
+// the read conditions of its columns are optional
+type tablePermissions struct {
+	Insert      string            `json:"insert"`
+	Update      string            `json:"update"`
+	NewColumn   string            `json:"new_column"`
+	Read        string            `json:"read,omitempty"`
+	ReadColumns map[string]string `json:"read_columns,omitempty"`
+}
+
+func parseTablePermissions(permissions string) (*tablePermissions, error) {
+	perm := &tablePermissions{}
+	decoder := json.NewDecoder(strings.NewReader(permissions))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(perm); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling permissions from json")
+		return nil, fmt.Errorf(`Permissions must contain "insert", "new_column", "update" and optional "read", "read_columns"`)
+	}
+	return perm, nil
+}
+
+// conditions returns the conditions which must be not empty
+func (perm *tablePermissions) conditions() map[string]string {
+	conds := map[string]string{`insert`: perm.Insert, `update`: perm.Update, `new_column`: perm.NewColumn}
+	if len(perm.Read) > 0 {
+		conds[`read`] = perm.Read
+	}
+	for column, cond := range perm.ReadColumns {
+		conds[`read_columns.`+column] = cond
+	}
+	return conds
+}
+
 func TableConditions(p *Parser, name, columns, permissions string) (err error) {
 	isEdit := len(columns) == 0
 
@@ -1427,22 +1677,23 @@ func TableConditions(p *Parser, name, columns, permissions string) (err error) {
 		return fmt.Errorf(`table %s exists`, name)
 	}
 
-	var perm map[string]string
-	err = json.Unmarshal([]byte(permissions), &perm)
+	perm, err := parseTablePermissions(permissions)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling permissions from json")
 		return
 	}
-	if len(perm) != 3 {
-		log.WithFields(log.Fields{"size": len(perm), "type": consts.InvalidObject}).Error("permissions must contain insert, new_column, and update")
-		return fmt.Errorf(`Permissions must contain "insert", "new_column", "update"`)
+	conds := perm.conditions()
+	names := make([]string, 0, len(conds))
+	for v := range conds {
+		names = append(names, v)
 	}
-	for _, v := range []string{`insert`, `update`, `new_column`} {
-		if len(perm[v]) == 0 {
+	sort.Strings(names)
+	for _, v := range names {
+		cond := conds[v]
+		if len(cond) == 0 {
 			log.WithFields(log.Fields{"condition_type": v, "type": consts.EmptyObject}).Error("condition is empty")
 			return fmt.Errorf(`%v condition is empty`, v)
 		}
-		if err = smart.CompileEval(perm[v], uint32(p.TxSmart.EcosystemID)); err != nil {
+		if err = smart.CompileEval(cond, uint32(p.TxSmart.EcosystemID)); err != nil {
 			log.WithFields(log.Fields{"type": consts.EvalError, "error": err}).Error("compile evaluating permissions")
 			return err
 		}
@@ -1585,17 +1836,11 @@ func CreateTable(p *Parser, name string, columns, permissions string) error {
 			return err
 		}
 	}
-	var perm map[string]string
-	permlist := make(map[string]string)
-	err = json.Unmarshal([]byte(permissions), &perm)
+	perm, err := parseTablePermissions(permissions)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling permissions")
 		return err
 	}
-	for _, v := range []string{`insert`, `update`, `new_column`} {
-		permlist[v] = perm[v]
-	}
-	permout, err := json.Marshal(permlist)
+	permout, err := json.Marshal(perm)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("unmarshalling permissions")
 		return err
@@ -1635,17 +1880,11 @@ func PermTable(p *Parser, name, permissions string) error {
 		log.WithFields(log.Fields{"type": consts.IncorrectCallingContract}).Error("EditTable can be only called from @1EditTable")
 		return fmt.Errorf(`EditTable can be only called from @1EditTable`)
 	}
-	var perm map[string]string
-	permlist := make(map[string]string)
-	err := json.Unmarshal([]byte(permissions), &perm)
+	perm, err := parseTablePermissions(permissions)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling table permissions to json")
 		return err
 	}
-	for _, v := range []string{`insert`, `update`, `new_column`} {
-		permlist[v] = perm[v]
-	}
-	permout, err := json.Marshal(permlist)
+	permout, err := json.Marshal(perm)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling permission list to json")
 		return err
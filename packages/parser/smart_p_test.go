@@ -0,0 +1,36 @@
+package parser
+
+import "testing"
+
+func TestSplitTableName(t *testing.T) {
+	cases := []struct {
+		table     string
+		ecosystem int64
+		name      string
+	}{
+		{`1_keys`, 1, `keys`},
+		{`25_my_table`, 25, `my_table`},
+		{`system_parameters`, 0, `system_parameters`},
+		{`info_block`, 0, `info_block`},
+		{`_keys`, 0, `_keys`},
+		{`-1_keys`, 0, `-1_keys`},
+	}
+	for _, c := range cases {
+		if ecosystem, name := splitTableName(c.table); ecosystem != c.ecosystem || name != c.name {
+			t.Errorf("%s: %d %s instead of %d %s", c.table, ecosystem, name, c.ecosystem, c.name)
+		}
+	}
+}
+
+func TestIsColumnName(t *testing.T) {
+	for _, name := range []string{`id`, `amount`, `key_id`, `Value2`} {
+		if !isColumnName(name) {
+			t.Errorf("%s is not column name", name)
+		}
+	}
+	for _, name := range []string{``, `*`, `count(id)`, `id as x`, `"id"`} {
+		if isColumnName(name) {
+			t.Errorf("%s is column name", name)
+		}
+	}
+}
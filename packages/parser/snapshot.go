@@ -0,0 +1,151 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	snapshotExt = `.snapshot`
+	// snapshotsKept is the number of the last snapshot files which are kept on the disk
+	snapshotsKept = 2
+)
+
+// SnapshotFile is the snapshot of the state on the disk
+type SnapshotFile struct {
+	BlockID int64
+	Hash    []byte
+	Name    string
+}
+
+// SnapshotDir returns the directory of the snapshot files
+func SnapshotDir() string {
+	return filepath.Join(*utils.Dir, `snapshots`)
+}
+
+// SnapshotFileName returns the name of the snapshot file of the block with the hash of the state
+func SnapshotFileName(blockID int64, hash []byte) string {
+	return filepath.Join(SnapshotDir(), fmt.Sprintf(`%d_%x`, blockID, hash)+snapshotExt)
+}
+
+// Snapshots returns the list of the snapshot files sorted by the blocks
+func Snapshots() []SnapshotFile {
+	files, err := filepath.Glob(filepath.Join(SnapshotDir(), `*`+snapshotExt))
+	if err != nil {
+		return nil
+	}
+	list := make([]SnapshotFile, 0, len(files))
+	for _, name := range files {
+		item := SnapshotFile{Name: name}
+		if _, err := fmt.Sscanf(filepath.Base(name), `%d_%x`, &item.BlockID, &item.Hash); err == nil {
+			list = append(list, item)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BlockID < list[j].BlockID })
+	return list
+}
+
+// LastSnapshot returns the last snapshot file or nil if there are no snapshots
+func LastSnapshot() *SnapshotFile {
+	list := Snapshots()
+	if len(list) == 0 {
+		return nil
+	}
+	return &list[len(list)-1]
+}
+
+// MakeSnapshot writes the snapshot of the state of the transaction after blockID block into the snapshot directory.
+// The hash of the state is a part of the name of the file, it must match the hash committed by the block
+func MakeSnapshot(transaction *model.DbTransaction, blockID int64, logger *log.Entry) error {
+	if err := os.MkdirAll(SnapshotDir(), 0775); err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("creating snapshot directory")
+		return err
+	}
+	fileName := filepath.Join(SnapshotDir(), converter.Int64ToStr(blockID)+`.tmp`)
+	file, err := os.Create(fileName)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("creating snapshot file")
+		return err
+	}
+	hash, err := model.WriteSnapshot(transaction, blockID, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = checkCommittedSnapshot(transaction, blockID, hash, logger)
+	}
+	if err == nil {
+		err = os.Rename(fileName, SnapshotFileName(blockID, hash))
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing snapshot file")
+		os.Remove(fileName)
+		return err
+	}
+	logger.WithFields(log.Fields{"block_id": blockID, "hash": converter.BinToHex(hash)}).Info("snapshot of state has been made")
+	removeOldSnapshots(logger)
+	return nil
+}
+
+// checkCommittedSnapshot compares the hash of the snapshot with the hash which has been committed by the block
+func checkCommittedSnapshot(transaction *model.DbTransaction, blockID int64, hash []byte, logger *log.Entry) error {
+	committedID, committed, err := model.GetSnapshotHash(transaction)
+	if err != nil {
+		return err
+	}
+	if committedID != blockID || !bytes.Equal(committed, hash) {
+		logger.WithFields(log.Fields{"type": consts.InvalidObject, "block_id": blockID, "hash": converter.BinToHex(hash),
+			"committed": model.FormatSnapshotHash(committedID, committed)}).Error("snapshot doesn't match committed hash")
+		return model.ErrSnapshotHash
+	}
+	return nil
+}
+
+// commitSnapshotHash saves the hash of the state after the snapshot block into snapshot_hash parameter,
+// so the snapshots which are downloaded by the new nodes are checked with the hash from the blockchain
+func (block *Block) commitSnapshotHash(transaction *model.DbTransaction) error {
+	if !syspar.IsSnapshotBlock(block.Header.BlockID) {
+		return nil
+	}
+	hash, err := model.SnapshotHash(transaction, block.Header.BlockID, block.Header.Hash)
+	if err != nil {
+		return err
+	}
+	return model.CommitSnapshotHash(transaction, block.Header.BlockID, hash)
+}
+
+func removeOldSnapshots(logger *log.Entry) {
+	list := Snapshots()
+	for i := 0; i < len(list)-snapshotsKept; i++ {
+		if err := os.Remove(list[i].Name); err != nil {
+			logger.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("removing old snapshot")
+		}
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrValidatorNotFound is returned if the key isn't in the list of the full nodes
+	ErrValidatorNotFound = errors.New("full node has not been found")
+	// ErrEvidence is returned if the blocks are not the evidence of the double signing
+	ErrEvidence = errors.New("incorrect evidence of double signing")
+)
+
+// blockForSign returns the data of the block header which is signed by the full node
+func blockForSign(header *utils.BlockData, prevHash, mrklRoot []byte) string {
+	return fmt.Sprintf("0,%d,%x,%d,%d,%d,%d,%s", header.BlockID, prevHash, header.Time, header.EcosystemID,
+		header.KeyID, header.NodePosition, mrklRoot)
+}
+
+// signedHeader is the part of the signed data of the block header which identifies the slot of the full node
+type signedHeader struct {
+	BlockID  int64
+	PrevHash string
+	Time     int64
+	KeyID    int64
+}
+
+// parseBlockForSign returns the block id, the previous hash, the time and the key of the node
+// from the signed data of the block header
+func parseBlockForSign(forSign string) (*signedHeader, error) {
+	fields := strings.Split(forSign, ",")
+	if len(fields) != 8 || fields[0] != "0" {
+		return nil, ErrEvidence
+	}
+	return &signedHeader{BlockID: converter.StrToInt64(fields[1]), PrevHash: fields[2],
+		Time: converter.StrToInt64(fields[3]), KeyID: converter.StrToInt64(fields[5])}, nil
+}
+
+// isDoubleSign returns true if the different block headers have been signed by the full node in the same slot
+// on the same previous block. The slot lasts gap seconds. The full node which signs the block again
+// after the rollback of the fork uses the other previous block or the next slot
+func isDoubleSign(forSign1, forSign2 string, gap int64) bool {
+	if forSign1 == forSign2 {
+		return false
+	}
+	header1, err := parseBlockForSign(forSign1)
+	if err != nil {
+		return false
+	}
+	header2, err := parseBlockForSign(forSign2)
+	if err != nil {
+		return false
+	}
+	diff := header1.Time - header2.Time
+	if diff < 0 {
+		diff = -diff
+	}
+	return header1.BlockID == header2.BlockID && header1.KeyID == header2.KeyID &&
+		header1.PrevHash == header2.PrevHash && diff < gap
+}
+
+// getFullNodes returns the list of the full nodes from the database
+func getFullNodes(transaction *model.DbTransaction) ([][]string, error) {
+	par := &model.SystemParameter{}
+	if err := model.GetDB(transaction).Where("name = ?", syspar.FullNodes).First(par).Error; err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting full nodes")
+		return nil, err
+	}
+	nodes := make([][]string, 0)
+	if len(par.Value) == 0 {
+		return nodes, nil
+	}
+	if err := json.Unmarshal([]byte(par.Value), &nodes); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling full nodes from json")
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// nodeKeyByPosition returns the key of the full node with the position or 0
+func nodeKeyByPosition(nodes [][]string, position int64) int64 {
+	if position < 0 || position >= int64(len(nodes)) || len(nodes[position]) < 3 {
+		return 0
+	}
+	return converter.StrToInt64(nodes[position][1])
+}
+
+// skippedNodes returns the keys of the full nodes which have missed their slots between the blocks
+func skippedNodes(nodes [][]string, prevPosition, position int64) []int64 {
+	count := int64(len(nodes))
+	skipped := make([]int64, 0)
+	if prevPosition < 0 || prevPosition >= count || position < 0 || position >= count {
+		return skipped
+	}
+	for pos := (prevPosition + 1) % count; pos != position; pos = (pos + 1) % count {
+		if keyID := nodeKeyByPosition(nodes, pos); keyID != 0 {
+			skipped = append(skipped, keyID)
+		}
+	}
+	return skipped
+}
+
+// updateValidatorSlots adds delta to the produced slot of the generator of the block
+// and to the missed slots of the full nodes which have been skipped before it
+func (block *Block) updateValidatorSlots(transaction *model.DbTransaction, delta int64) error {
+	if block.Header.BlockID == 1 || !syspar.IsValidatorsFork(block.Header.BlockID) {
+		return nil
+	}
+	if block.PrevHeader == nil || block.PrevHeader.BlockID != block.Header.BlockID-1 {
+		if err := block.readPreviousBlockFromBlockchainTable(); err != nil {
+			return err
+		}
+	}
+	nodes, err := getFullNodes(transaction)
+	if err != nil {
+		return err
+	}
+	producer := nodeKeyByPosition(nodes, block.Header.NodePosition)
+	if producer == 0 {
+		producer = block.Header.KeyID
+	}
+	skipped := skippedNodes(nodes, block.PrevHeader.NodePosition, block.Header.NodePosition)
+	if err = model.UpdateValidatorSlots(transaction, producer, skipped, delta); err != nil {
+		block.GetLogger().WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("updating validator slots")
+	}
+	return err
+}
+
+// checkDoubleSign saves the evidence if the generator of the block has signed our block with the same id
+// in the same slot
+func (block *Block) checkDoubleSign(forSign string) error {
+	if !syspar.IsValidatorsFork(block.Header.BlockID) {
+		return nil
+	}
+	logger := block.GetLogger()
+	my := &model.Block{}
+	found, err := my.Get(block.Header.BlockID)
+	if err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block by id")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	myBlock, err := ProcessBlockWherePrevFromBlockchainTable(my.Data)
+	if err != nil {
+		return err
+	}
+	if myBlock.Header.KeyID != block.Header.KeyID || myBlock.Header.NodePosition != block.Header.NodePosition {
+		return nil
+	}
+	myForSign := blockForSign(&myBlock.Header, myBlock.PrevHeader.Hash, myBlock.MrklRoot)
+	if !isDoubleSign(myForSign, forSign, syspar.GetGapsBetweenBlocks()) {
+		return nil
+	}
+	logger.WithFields(log.Fields{"type": consts.BlockError, "key_id": block.Header.KeyID}).Warning("full node has signed two different blocks")
+	ds := &model.DoubleSign{KeyID: block.Header.KeyID, BlockID: block.Header.BlockID, ForSign1: myForSign,
+		Sign1: myBlock.Header.Sign, ForSign2: forSign, Sign2: block.Header.Sign}
+	if err = ds.Create(); err != nil {
+		logger.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("saving double sign evidence")
+	}
+	return err
+}
+
+// setFullNodes writes the list of the full nodes to full_nodes system parameter
+func setFullNodes(p *Parser, nodes [][]string) (int64, error) {
+	if len(nodes) == 0 {
+		log.WithFields(log.Fields{"type": consts.EmptyObject}).Error("removing the last full node")
+		return 0, fmt.Errorf(`the last full node can't be removed`)
+	}
+	data, err := json.Marshal(nodes)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling full nodes to json")
+		return 0, err
+	}
+	return UpdateSysParam(p, syspar.FullNodes, string(data), ``)
+}
+
+// findFullNode returns the position of the full node in the list or -1
+func findFullNode(nodes [][]string, keyID int64) int {
+	for i := range nodes {
+		if nodeKeyByPosition(nodes, int64(i)) == keyID {
+			return i
+		}
+	}
+	return -1
+}
+
+// setValidatorStatus saves the block of the suspension of the full node and its data for the resumption
+func setValidatorStatus(p *Parser, keyID, suspended int64, node string) (int64, error) {
+	cost, _, err := p.selectiveLoggingAndUpd([]string{`suspended`, `node`}, []interface{}{suspended, node},
+		`validators`, []string{`id`}, []string{converter.Int64ToStr(keyID)}, true)
+	return cost, err
+}
+
+// AddFullNode adds the full node to the list of the full nodes
+func AddFullNode(p *Parser, host string, keyID int64, pub string) (int64, error) {
+	if pkey, err := hex.DecodeString(pub); err != nil || len(pkey) != consts.PubkeySizeLength {
+		log.WithFields(log.Fields{"type": consts.ConvertionError, "value": pub}).Error("decoding public key of full node")
+		return 0, fmt.Errorf(`incorrect public key of the full node`)
+	}
+	if len(host) == 0 || keyID == 0 {
+		log.WithFields(log.Fields{"type": consts.EmptyObject}).Error("empty host or key of full node")
+		return 0, fmt.Errorf(`empty host or key of the full node`)
+	}
+	nodes, err := getFullNodes(p.DbTransaction)
+	if err != nil {
+		return 0, err
+	}
+	if findFullNode(nodes, keyID) >= 0 {
+		log.WithFields(log.Fields{"type": consts.DuplicateObject, "key_id": keyID}).Error("full node already exists")
+		return 0, fmt.Errorf(`full node %d already exists`, keyID)
+	}
+	cost, err := setFullNodes(p, append(nodes, []string{host, converter.Int64ToStr(keyID), pub}))
+	if err != nil {
+		return 0, err
+	}
+	validator := &model.Validator{}
+	if _, err = validator.Get(p.DbTransaction, keyID); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validator")
+		return 0, err
+	}
+	if validator.Suspended == 0 {
+		return cost, nil
+	}
+	vcost, err := setValidatorStatus(p, keyID, 0, ``)
+	return cost + vcost, err
+}
+
+// RemoveFullNode removes the full node from the list of the full nodes
+func RemoveFullNode(p *Parser, keyID int64) (int64, error) {
+	nodes, err := getFullNodes(p.DbTransaction)
+	if err != nil {
+		return 0, err
+	}
+	i := findFullNode(nodes, keyID)
+	if i < 0 {
+		log.WithFields(log.Fields{"type": consts.NotFound, "key_id": keyID}).Error("removing unknown full node")
+		return 0, ErrValidatorNotFound
+	}
+	return setFullNodes(p, append(nodes[:i], nodes[i+1:]...))
+}
+
+// SuspendFullNode removes the full node from the list of the full nodes and keeps its data for ResumeFullNode
+func SuspendFullNode(p *Parser, keyID int64) (int64, error) {
+	nodes, err := getFullNodes(p.DbTransaction)
+	if err != nil {
+		return 0, err
+	}
+	i := findFullNode(nodes, keyID)
+	if i < 0 {
+		log.WithFields(log.Fields{"type": consts.NotFound, "key_id": keyID}).Error("suspending unknown full node")
+		return 0, ErrValidatorNotFound
+	}
+	node, err := json.Marshal(nodes[i])
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling full node to json")
+		return 0, err
+	}
+	cost, err := setFullNodes(p, append(nodes[:i], nodes[i+1:]...))
+	if err != nil {
+		return 0, err
+	}
+	vcost, err := setValidatorStatus(p, keyID, p.BlockData.BlockID, string(node))
+	return cost + vcost, err
+}
+
+// ResumeFullNode returns the suspended full node to the list of the full nodes
+func ResumeFullNode(p *Parser, keyID int64) (int64, error) {
+	validator := &model.Validator{}
+	if _, err := validator.Get(p.DbTransaction, keyID); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validator")
+		return 0, err
+	}
+	if validator.Suspended == 0 {
+		log.WithFields(log.Fields{"type": consts.NotFound, "key_id": keyID}).Error("resuming full node which is not suspended")
+		return 0, fmt.Errorf(`full node %d is not suspended`, keyID)
+	}
+	var node []string
+	if err := json.Unmarshal([]byte(validator.Node), &node); err != nil || len(node) < 3 {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling suspended full node")
+		return 0, fmt.Errorf(`incorrect data of the suspended full node`)
+	}
+	return AddFullNode(p, node[0], keyID, node[2])
+}
+
+// ValidatorStats returns the produced and missed slots and the number of double signed blocks of the full node
+func ValidatorStats(p *Parser, keyID int64) (map[string]interface{}, error) {
+	validator := &model.Validator{}
+	if _, err := validator.Get(p.DbTransaction, keyID); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validator")
+		return nil, err
+	}
+	return map[string]interface{}{`produced`: validator.Produced, `missed`: validator.Missed,
+		`double_signed`: validator.DoubleSigned, `suspended`: validator.Suspended}, nil
+}
+
+// IsValidator returns true if the key belongs to one of the full nodes
+func IsValidator(keyID int64) bool {
+	return syspar.GetNode(keyID) != nil
+}
+
+// ValidatorFaulty returns true if the full node has double signed a block or has missed too many slots
+func ValidatorFaulty(p *Parser, keyID int64) (bool, error) {
+	validator := &model.Validator{}
+	if _, err := validator.Get(p.DbTransaction, keyID); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validator")
+		return false, err
+	}
+	return syspar.IsValidatorFaulty(validator.Produced, validator.Missed, validator.DoubleSigned), nil
+}
+
+// validatorPublicKey returns the public key of the full node or of the suspended full node
+func validatorPublicKey(p *Parser, keyID int64) ([]byte, error) {
+	if node := syspar.GetNode(keyID); node != nil {
+		return node.Public, nil
+	}
+	validator := &model.Validator{}
+	if _, err := validator.Get(p.DbTransaction, keyID); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validator")
+		return nil, err
+	}
+	var node []string
+	if err := json.Unmarshal([]byte(validator.Node), &node); err != nil || len(node) < 3 {
+		return nil, ErrValidatorNotFound
+	}
+	return hex.DecodeString(node[2])
+}
+
+// DoubleSignEvidence registers two different signed block headers with the same block id of the full node
+func DoubleSignEvidence(p *Parser, forSign1, sign1, forSign2, sign2 string) (int64, error) {
+	header, err := parseBlockForSign(forSign1)
+	if err != nil {
+		return 0, err
+	}
+	blockID, keyID := header.BlockID, header.KeyID
+	if !syspar.IsValidatorsFork(blockID) || !isDoubleSign(forSign1, forSign2, syspar.GetGapsBetweenBlocks()) {
+		log.WithFields(log.Fields{"type": consts.InvalidObject, "block_id": blockID, "key_id": keyID}).Error("blocks are not double signed")
+		return 0, ErrEvidence
+	}
+	pub, err := validatorPublicKey(p, keyID)
+	if err != nil {
+		return 0, err
+	}
+	for _, item := range [][]string{{forSign1, sign1}, {forSign2, sign2}} {
+		sign, err := hex.DecodeString(item[1])
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.ConvertionError, "error": err}).Error("decoding signature of block")
+			return 0, err
+		}
+		if ok, err := utils.CheckSign([][]byte{pub}, item[0], sign, true); !ok || err != nil {
+			log.WithFields(log.Fields{"type": consts.InvalidObject, "error": err, "key_id": keyID}).Error("checking signature of block")
+			return 0, ErrEvidence
+		}
+	}
+	found, err := model.IsValidatorEvidence(p.DbTransaction, keyID, blockID)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting validator evidence")
+		return 0, err
+	}
+	if found {
+		log.WithFields(log.Fields{"type": consts.DuplicateObject, "block_id": blockID, "key_id": keyID}).Error("evidence has already been registered")
+		return 0, fmt.Errorf(`evidence has already been registered`)
+	}
+	cost, _, err := p.selectiveLoggingAndUpd([]string{`key_id`, `block_id`}, []interface{}{keyID, blockID},
+		`validator_evidence`, nil, nil, true)
+	if err != nil {
+		return 0, err
+	}
+	vcost, _, err := p.selectiveLoggingAndUpd([]string{`+double_signed`}, []interface{}{1},
+		`validators`, []string{`id`}, []string{converter.Int64ToStr(keyID)}, true)
+	return cost + vcost, err
+}
@@ -0,0 +1,60 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSkippedNodes(t *testing.T) {
+	nodes := [][]string{{`host0`, `10`, `00`}, {`host1`, `11`, `01`}, {`host2`, `12`, `02`}, {`host3`, `13`, `03`}}
+	cases := []struct {
+		prev, pos int64
+		skipped   []int64
+	}{
+		{0, 1, []int64{}},
+		{0, 3, []int64{11, 12}},
+		{2, 1, []int64{13, 10}},
+		{1, 1, []int64{12, 13, 10}},
+		{1, 7, []int64{}},
+	}
+	for _, c := range cases {
+		if skipped := skippedNodes(nodes, c.prev, c.pos); !reflect.DeepEqual(skipped, c.skipped) {
+			t.Errorf("%d -> %d: skipped %v instead of %v", c.prev, c.pos, skipped, c.skipped)
+		}
+	}
+	if skipped := skippedNodes(nodes[:1], 0, 0); len(skipped) != 0 {
+		t.Errorf("single node has skipped %v", skipped)
+	}
+}
+
+func TestParseBlockForSign(t *testing.T) {
+	header, err := parseBlockForSign(`0,25,abcdef,1500000000,1,-7,2,d41d8c`)
+	if err != nil || header.BlockID != 25 || header.KeyID != -7 || header.PrevHash != `abcdef` ||
+		header.Time != 1500000000 {
+		t.Errorf("wrong header %+v: %v", header, err)
+	}
+	if _, err = parseBlockForSign(`1,25,abcdef`); err != ErrEvidence {
+		t.Errorf("wrong error %v", err)
+	}
+}
+
+func TestIsDoubleSign(t *testing.T) {
+	forSign := `0,25,abcdef,1500000000,1,-7,2,d41d8c`
+	cases := []struct {
+		forSign string
+		double  bool
+	}{
+		{`0,25,abcdef,1500000001,1,-7,2,0cc175`, true},
+		{`0,25,abcdef,1500000000,1,-7,2,d41d8c`, false},
+		{`0,25,012345,1500000000,1,-7,2,0cc175`, false},
+		{`0,25,abcdef,1500000030,1,-7,2,0cc175`, false},
+		{`0,25,abcdef,1500000000,1,-8,2,0cc175`, false},
+		{`0,26,abcdef,1500000000,1,-7,2,0cc175`, false},
+		{`0,25,abcdef`, false},
+	}
+	for _, c := range cases {
+		if double := isDoubleSign(forSign, c.forSign, 2); double != c.double {
+			t.Errorf("%s: double sign %v", c.forSign, double)
+		}
+	}
+}
@@ -39,6 +39,9 @@ const (
 	cmdSetIndex              // set index []
 	cmdFuncName              // set func name Func(...).Name(...)
 	cmdError                 // error command
+	cmdArrayInit             // create array from the values in stack
+	cmdMapInit               // create map from the pairs of keys and values in stack
+	cmdFor                   // run block for every item of array or map
 )
 
 // the commands for operations in expressions are listed below
@@ -64,3 +67,12 @@ const (
 	cmdSys          = 0xff
 	cmdUnary uint16 = 50
 )
+
+// cmdNames contains the names of the commands for the trace of the execution
+var cmdNames = map[uint16]string{cmdPush: `push`, cmdVar: `var`, cmdExtend: `extend`, cmdCallExtend: `callextend`,
+	cmdPushStr: `pushstr`, cmdCall: `call`, cmdCallVari: `callvari`, cmdReturn: `return`, cmdIf: `if`,
+	cmdElse: `else`, cmdAssignVar: `assignvar`, cmdAssign: `assign`, cmdLabel: `label`, cmdContinue: `continue`,
+	cmdWhile: `while`, cmdBreak: `break`, cmdIndex: `index`, cmdSetIndex: `setindex`, cmdFuncName: `funcname`,
+	cmdError: `error`, cmdArrayInit: `array`, cmdMapInit: `map`, cmdFor: `for`, cmdNot: `not`, cmdSign: `sign`,
+	cmdAdd: `add`, cmdSub: `sub`, cmdMul: `mul`, cmdDiv: `div`, cmdAnd: `and`, cmdOr: `or`, cmdEqual: `equal`,
+	cmdNotEq: `noteq`, cmdLess: `less`, cmdNotLess: `notless`, cmdGreat: `great`, cmdNotGreat: `notgreat`}
@@ -89,6 +89,8 @@ const (
 	stateConstsAssign
 	stateConstsValue
 	stateFields
+	stateFor
+	stateForIn
 	stateEval
 
 	// The list of state flags
@@ -115,6 +117,7 @@ const (
 	errVarType               // must be type
 	errAssign                // must be '='
 	errStrNum                // must be number or string
+	errMustIn                // must be 'in'
 )
 
 const (
@@ -145,6 +148,8 @@ const (
 	cfContinue
 	cfBreak
 	cfCmdError
+	cfForVar
+	cfFor
 
 //	cfEval
 )
@@ -182,6 +187,8 @@ var (
 		fContinue,
 		fBreak,
 		fCmdError,
+		fForVar,
+		fFor,
 	}
 
 	// 'states' describes a finite machine with states on the base of which a bytecode will be generated
@@ -201,6 +208,7 @@ var (
 			lexKeyword | (keyBreak << 8):    {stateBody, cfBreak},
 			lexKeyword | (keyIf << 8):       {stateEval | statePush | stateToBlock | stateMustEval, cfIf},
 			lexKeyword | (keyWhile << 8):    {stateEval | statePush | stateToBlock | stateLabel | stateMustEval, cfWhile},
+			lexKeyword | (keyFor << 8):      {stateFor | statePush, 0},
 			lexKeyword | (keyElse << 8):     {stateBlock | statePush, cfElse},
 			lexKeyword | (keyVar << 8):      {stateVar, 0},
 			lexKeyword | (keyTX << 8):       {stateTX, cfTX},
@@ -332,6 +340,14 @@ var (
 			isRCurly:   {stateToBody, 0},
 			0:          {errMustRCurly, cfError},
 		},
+		{ // stateFor
+			lexIdent: {stateForIn, cfForVar},
+			0:        {errMustName, cfError},
+		},
+		{ // stateForIn
+			lexKeyword | (keyIn << 8): {stateEval | stateToBlock | stateMustEval, cfFor},
+			0:                         {errMustIn, cfError},
+		},
 	}
 )
 
@@ -346,15 +362,37 @@ func fError(buf *[]*Block, state int, lexem *Lexem) error {
 		`must be type`,             // errVarType
 		`must be '='`,              // errAssign
 		`must be number or string`, // errStrNum
+		`must be 'in'`,             // errMustIn
 	}
 	fmt.Printf("%s %x %v [Ln:%d Col:%d]\r\n", errors[state], lexem.Type, lexem.Value, lexem.Line, lexem.Column)
 	logger := lexem.GetLogger()
 	if lexem.Type == lexNewLine {
 		logger.WithFields(log.Fields{"error": errors[state], "lex_value": lexem.Value, "type": consts.ParseError}).Error("unexpected new line")
-		return fmt.Errorf(`%s (unexpected new line) [Ln:%d]`, errors[state], lexem.Line-1)
+		return &CompileError{fmt.Sprintf(`%s (unexpected new line) [Ln:%d]`, errors[state], lexem.Line-1),
+			lexem.Line - 1, 0}
 	}
 	logger.WithFields(log.Fields{"error": errors[state], "lex_value": lexem.Value, "type": consts.ParseError}).Error("parsing error")
-	return fmt.Errorf(`%s %x %v [Ln:%d Col:%d]`, errors[state], lexem.Type, lexem.Value, lexem.Line, lexem.Column)
+	return &CompileError{fmt.Sprintf(`%s %x %v [Ln:%d Col:%d]`, errors[state], lexem.Type, lexem.Value, lexem.Line, lexem.Column),
+		lexem.Line, lexem.Column}
+}
+
+// CompileError is the error of the compilation with the position in the source code
+type CompileError struct {
+	Message string
+	Line    uint32
+	Column  uint32
+}
+
+func (e *CompileError) Error() string {
+	return e.Message
+}
+
+// compileError adds the position of the lexeme to the error of the compilation
+func compileError(err error, lexem *Lexem) error {
+	if _, ok := err.(*CompileError); ok {
+		return err
+	}
+	return &CompileError{err.Error(), lexem.Line, lexem.Column}
 }
 
 func fFuncResult(buf *[]*Block, state int, lexem *Lexem) error {
@@ -504,6 +542,29 @@ func fWhile(buf *[]*Block, state int, lexem *Lexem) error {
 	return nil
 }
 
+// fForVar declares the variable of the for loop in the block of the loop
+func fForVar(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	if block.Objects == nil {
+		block.Objects = make(map[string]*ObjInfo)
+	}
+	block.Objects[lexem.Value.(string)] = &ObjInfo{Type: ObjVar, Value: len(block.Vars)}
+	block.Vars = append(block.Vars, reflect.TypeOf([]interface{}{}).Elem())
+	return nil
+}
+
+// fFor moves the compiled expression of the collection from the block of the loop to the parent block.
+// The block of the loop starts with the assignment of the current item to the variable of the loop.
+func fFor(buf *[]*Block, state int, lexem *Lexem) error {
+	block := (*buf)[len(*buf)-1]
+	parent := (*buf)[len(*buf)-2]
+	parent.Code = append(parent.Code, block.Code...)
+	parent.Code = append(parent.Code, &ByteCode{cmdFor, block})
+	ivar := &VarInfo{&ObjInfo{Type: ObjVar, Value: 0}, block}
+	block.Code = ByteCodes{&ByteCode{cmdAssignVar, []*VarInfo{ivar}}, &ByteCode{cmdAssign, 0}}
+	return nil
+}
+
 func fContinue(buf *[]*Block, state int, lexem *Lexem) error {
 	(*(*buf)[len(*buf)-1]).Code = append((*(*buf)[len(*buf)-1]).Code, &ByteCode{cmdContinue, 0})
 	return nil
@@ -662,6 +723,16 @@ func fNameBlock(buf *[]*Block, state int, lexem *Lexem) error {
 	return nil
 }
 
+// setLines assigns the line of the source code to the new commands of the block
+func setLines(block *Block, line uint32) {
+	if len(block.Lines) > len(block.Code) {
+		block.Lines = block.Lines[:len(block.Code)]
+	}
+	for len(block.Lines) < len(block.Code) {
+		block.Lines = append(block.Lines, line)
+	}
+}
+
 // CompileBlock compile the source code into the Block structure with a byte-code
 func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 	root := &Block{Info: owner.StateID, Owner: owner}
@@ -744,6 +815,7 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 					(*prev).Code = (*prev).Code[:len((*prev).Code)-1]
 					prev = blockstack[len(blockstack)-1]
 					(*prev).Code = append((*prev).Code, &ByteCode{cmdContinue, 0})
+					setLines(prev, lexem.Line)
 				}
 			}
 			blockstack = blockstack[:len(blockstack)-1]
@@ -757,9 +829,12 @@ func (vm *VM) CompileBlock(input []rune, owner *OwnerInfo) (*Block, error) {
 		//fmt.Println(`LEX`, curState, lexem, stack)
 		if newState.Func > 0 {
 			if err := funcs[newState.Func](&blockstack, nextState, lexem); err != nil {
-				return nil, err
+				return nil, compileError(err, lexem)
 			}
 		}
+		for k := len(blockstack) - 1; k >= 0 && k >= len(blockstack)-2; k-- {
+			setLines(blockstack[k], lexem.Line)
+		}
 		curState = nextState
 	}
 	if len(stack) > 0 {
@@ -838,9 +913,73 @@ func (vm *VM) findObj(name string, block *[]*Block) (ret *ObjInfo, owner *Block)
 	return
 }
 
+// isLiteral returns true if the curly bracket starts the literal of map but not the block
+func isLiteral(lexems *Lexems, i int) bool {
+	if i == 0 {
+		return false
+	}
+	switch (*lexems)[i-1].Type & 0xff {
+	case lexOper, lexKeyword:
+		return true
+	}
+	switch (*lexems)[i-1].Type {
+	case isEq, isLPar, isComma, isLBrack, isColon, isLCurly:
+		return true
+	}
+	return false
+}
+
+// nextLexem returns the index of the next lexeme skipping new lines
+func nextLexem(lexems *Lexems, i int) int {
+	for i++; i < len(*lexems) && (*lexems)[i].Type == lexNewLine; i++ {
+	}
+	return i
+}
+
+// literalCount returns the initial count of the items of the literal which starts at i position
+func literalCount(lexems *Lexems, i int, closing uint32) int {
+	if next := nextLexem(lexems, i); next < len(*lexems) && (*lexems)[next].Type == closing {
+		return 0
+	}
+	return 1
+}
+
+// closeLiteral checks that the literal is not closed right after the comma or the colon
+func closeLiteral(lexems *Lexems, i int) error {
+	for i--; i > 0 && (*lexems)[i].Type == lexNewLine; i-- {
+	}
+	if (*lexems)[i].Type == isComma || (*lexems)[i].Type == isColon {
+		logger := (*lexems)[i].GetLogger()
+		logger.WithFields(log.Fields{"type": consts.ParseError}).Error("there is not value after separator")
+		return fmt.Errorf(`there is not value after separator`)
+	}
+	return nil
+}
+
+// literalCmd returns the command of the innermost literal of array or map which is not closed yet
+func literalCmd(buffer ByteCodes) uint16 {
+	for k := len(buffer) - 1; k > 0; k-- {
+		if buffer[k].Cmd == cmdSys && buffer[k].Value.(uint16) == 0xff {
+			if buffer[k-1].Cmd == cmdArrayInit || buffer[k-1].Cmd == cmdMapInit {
+				return buffer[k-1].Cmd
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
 // This function is responsible for the compilation of expressions
-func (vm *VM) compileEval(lexems *Lexems, ind *int, block *[]*Block) error {
+func (vm *VM) compileEval(lexems *Lexems, ind *int, block *[]*Block) (err error) {
 	i := *ind
+	defer func() {
+		if err != nil {
+			if i >= len(*lexems) {
+				i = len(*lexems) - 1
+			}
+			err = compileError(err, (*lexems)[i])
+		}
+	}()
 	curBlock := (*block)[len(*block)-1]
 
 	buffer := make(ByteCodes, 0, 20)
@@ -856,9 +995,51 @@ main:
 		logger := lexem.GetLogger()
 		//fmt.Println(i, parcount, lexem)
 		switch lexem.Type {
-		case isRCurly, isLCurly:
-			i--
-			break main
+		case isLCurly:
+			if !isLiteral(lexems, i) {
+				i--
+				break main
+			}
+			parcount = append(parcount, literalCount(lexems, i, isRCurly))
+			buffer = append(buffer, &ByteCode{cmdMapInit, uint16(0)}, &ByteCode{cmdSys, uint16(0xff)})
+		case isRCurly:
+			if literalCmd(buffer) != cmdMapInit {
+				i--
+				break main
+			}
+			if err := closeLiteral(lexems, i); err != nil {
+				return err
+			}
+			for {
+				prev := buffer[len(buffer)-1]
+				buffer = buffer[:len(buffer)-1]
+				if prev.Cmd == cmdSys && prev.Value.(uint16) == 0xff {
+					break
+				}
+				bytecode = append(bytecode, prev)
+			}
+			count := parcount[len(parcount)-1]
+			parcount = parcount[:len(parcount)-1]
+			if count%2 != 0 {
+				logger.WithFields(log.Fields{"type": consts.ParseError}).Error("map must contain pairs of keys and values")
+				return fmt.Errorf(`map must contain pairs of keys and values`)
+			}
+			buffer = buffer[:len(buffer)-1]
+			bytecode = append(bytecode, &ByteCode{cmdMapInit, count})
+		case isColon:
+			if literalCmd(buffer) != cmdMapInit || parcount[len(parcount)-1]%2 == 0 {
+				logger.WithFields(log.Fields{"type": consts.ParseError}).Error("unexpected colon")
+				return fmt.Errorf(`unexpected colon`)
+			}
+			parcount[len(parcount)-1]++
+			for len(buffer) > 0 {
+				prev := buffer[len(buffer)-1]
+				if prev.Cmd == cmdSys && prev.Value.(uint16) == 0xff {
+					break
+				}
+				bytecode = append(bytecode, prev)
+				buffer = buffer[:len(buffer)-1]
+			}
 		case lexNewLine:
 			if i > 0 && ((*lexems)[i-1].Type == isComma || (*lexems)[i-1].Type == lexOper) {
 				continue main
@@ -872,8 +1053,16 @@ main:
 		case isLPar:
 			buffer = append(buffer, &ByteCode{cmdSys, uint16(0xff)})
 		case isLBrack:
+			if len(buffer) == 0 || buffer[len(buffer)-1].Cmd != cmdIndex {
+				parcount = append(parcount, literalCount(lexems, i, isRBrack))
+				buffer = append(buffer, &ByteCode{cmdArrayInit, uint16(0)})
+			}
 			buffer = append(buffer, &ByteCode{cmdSys, uint16(0xff)})
 		case isComma:
+			if literalCmd(buffer) == cmdMapInit && parcount[len(parcount)-1]%2 != 0 {
+				logger.WithFields(log.Fields{"type": consts.ParseError}).Error("there is not value of the key")
+				return fmt.Errorf(`there is not value of the key`)
+			}
 			if len(parcount) > 0 {
 				parcount[len(parcount)-1]++
 			}
@@ -956,6 +1145,15 @@ main:
 					bytecode = append(bytecode, prev)
 				}
 			}
+			if len(buffer) > 0 && buffer[len(buffer)-1].Cmd == cmdArrayInit {
+				if err := closeLiteral(lexems, i); err != nil {
+					return err
+				}
+				buffer = buffer[:len(buffer)-1]
+				bytecode = append(bytecode, &ByteCode{cmdArrayInit, parcount[len(parcount)-1]})
+				parcount = parcount[:len(parcount)-1]
+				continue
+			}
 			if len(buffer) > 0 {
 				if prev := buffer[len(buffer)-1]; prev.Cmd == cmdIndex {
 					buffer = buffer[:len(buffer)-1]
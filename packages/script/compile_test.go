@@ -280,6 +280,50 @@ func TestVMCompile(t *testing.T) {
 			100).Limit(10) + DBFind( "table").Where("request")
 		return out
 	}`, `names`, `mytable   0 0=keys name,value  0 0=keys qqmy  0 199=table name id=? 10 0=table  request 0 0=`},
+		{`func literals string {
+		var list array
+		var m map
+		var out string
+		list = [1, 2 + 3, "str", [10, 20]]
+		m = {"a": 1, "b": list[1] * 2,
+			"c": {"d": "nested"}}
+		for item in list {
+			out = out + Sprintf("%v;", item)
+		}
+		for key in m {
+			out = out + key + ","
+		}
+		m["e"] = []
+		return Sprintf("%s %d %v %d", out, lenArray(list), m["b"], lenArray(m["e"]))
+	}`, `literals`, `1;5;str;[10 20];a,b,c, 4 10 0`},
+		{`func find(list array, val int) int {
+		var i int
+		for item in list {
+			if item == val {
+				return i
+			}
+			i = i + 1
+		}
+		return -1
+	}
+	func loops string {
+		var sum int
+		for i in [1, 2, 3, 4, 5, 6] {
+			if i == 2 {
+				continue
+			}
+			if i == 5 {
+				break
+			}
+			sum = sum + i
+		}
+		return Sprintf("%d %d %d", sum, find([7, 8, 9], 9), find([], 1))
+	}`, `loops`, `8 2 -1`},
+		{`func outrange string {
+		var list array
+		list = [1, 2]
+		return Sprintf("%v", list[2])
+	}`, `outrange`, `index 2 out of range`},
 	}
 	vm := NewVM()
 	vm.Extern = true
@@ -317,3 +361,54 @@ func TestVMCompile(t *testing.T) {
 	//fmt.Println(ret[0].(string))
 	//	fmt.Println(`Result`, err)
 }
+
+func TestVMArrayErrors(t *testing.T) {
+	test := []TestVM{
+		{`func f string {
+		var list array
+		list = [1, 2,]
+	}`, ``, `there is not value after separator`},
+		{`func f string {
+		var m map
+		m = {"a": 1, "b"}
+	}`, ``, `map must contain pairs of keys and values`},
+		{`func f string {
+		var m map
+		m = {"a", 1}
+	}`, ``, `there is not value of the key`},
+		{`func f string {
+		var list array
+		list = [1: 2]
+	}`, ``, `unexpected colon`},
+		{`func f string {
+		for i list {
+		}
+	}`, ``, `must be 'in' 4 list [Ln:2 Col:10]`},
+	}
+	vm := NewVM()
+	vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf}, nil})
+	for _, item := range test {
+		err := vm.Compile([]rune(item.Input), &OwnerInfo{StateID: 1, Active: true, TableID: 1})
+		if err == nil || err.Error() != item.Output {
+			t.Errorf(`wrong error %v != %s`, err, item.Output)
+		}
+	}
+
+	if err := vm.Compile([]rune(`func cost string {
+		var list array
+		var sum int
+		list = [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+		for i in list {
+			sum = sum + i
+		}
+		return Sprintf("%d", sum)
+	}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1}); err != nil {
+		t.Error(err)
+		return
+	}
+	rt := vm.RunInit(50)
+	_, err := rt.Run(vm.getObjByNameExt(`cost`, 1).Value.(*Block), nil, &map[string]interface{}{})
+	if err == nil || err.Error() != `paid CPU resource is over` {
+		t.Errorf(`wrong cost error %v`, err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The names of the operations of CostTable which are not the commands of the byte-code
+const (
+	CostNameVar      = `var_init`      // the initialization of the variable
+	CostNameCall     = `func_call`     // the calling of the function
+	CostNameContract = `contract_call` // the calling of the contract
+	CostNameExtend   = `extend_var`    // the reading of the extend variable
+	CostNameElement  = `element`       // the processing of one item of array or map
+	CostNameString   = `string`        // the processing of every 64 bytes of the string operand
+)
+
+// CostStringChunk is the size of the part of the string which is charged with the cost of string
+const CostStringChunk = 64
+
+// CostTable contains the costs of every command of the virtual machine and of the additional operations.
+// It is stored as JSON object in the system parameter so every node charges the same cost.
+type CostTable struct {
+	commands                                     [0x60]int64
+	Var, Call, Contract, Extend, Element, String int64
+}
+
+var defaultCostTable = NewCostTable()
+
+func costIndex(cmd uint16) int {
+	return int(cmd>>8)<<5 | int(cmd&0x1f)
+}
+
+// NewCostTable returns the default table of the costs. It charges 1 for every command and does not
+// charge the strings, so the cost of contracts doesn't change until vm_cost_table is set.
+func NewCostTable() *CostTable {
+	table := &CostTable{Var: 1, Call: CostCall, Contract: CostContract, Extend: CostExtend,
+		Element: CostElement}
+	for cmd := range cmdNames {
+		table.commands[costIndex(cmd)] = 1
+	}
+	return table
+}
+
+// ParseCostTable decodes the table of the costs from JSON object like {"add": 1, "func_call": 50}.
+// The missing names get the default values.
+func ParseCostTable(input string) (*CostTable, error) {
+	table := NewCostTable()
+	if len(strings.TrimSpace(input)) == 0 {
+		return table, nil
+	}
+	var costs map[string]int64
+	if err := json.Unmarshal([]byte(input), &costs); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling cost table")
+		return nil, fmt.Errorf(`cost table must be JSON object of integers`)
+	}
+	names := make(map[string]uint16)
+	for cmd, name := range cmdNames {
+		names[name] = cmd
+	}
+	for name, cost := range costs {
+		if cost < 0 {
+			return nil, fmt.Errorf(`cost of %s must not be negative`, name)
+		}
+		if cmd, ok := names[name]; ok {
+			table.commands[costIndex(cmd)] = cost
+			continue
+		}
+		switch name {
+		case CostNameVar:
+			table.Var = cost
+		case CostNameCall:
+			table.Call = cost
+		case CostNameContract:
+			table.Contract = cost
+		case CostNameExtend:
+			table.Extend = cost
+		case CostNameElement:
+			table.Element = cost
+		case CostNameString:
+			table.String = cost
+		default:
+			return nil, fmt.Errorf(`unknown name %s in cost table`, name)
+		}
+	}
+	return table, nil
+}
+
+// Command returns the cost of the command of the byte-code
+func (table *CostTable) Command(cmd uint16) int64 {
+	return table.commands[costIndex(cmd)]
+}
+
+// StringCost returns the cost of the processing of the string with the specified length
+func (table *CostTable) StringCost(size int) int64 {
+	return table.String * int64((size+CostStringChunk-1)/CostStringChunk)
+}
+
+// Map returns all costs of the table with their names. It is used for publishing the table.
+func (table *CostTable) Map() map[string]int64 {
+	ret := map[string]int64{CostNameVar: table.Var, CostNameCall: table.Call,
+		CostNameContract: table.Contract, CostNameExtend: table.Extend,
+		CostNameElement: table.Element, CostNameString: table.String}
+	for cmd, name := range cmdNames {
+		ret[name] = table.Command(cmd)
+	}
+	return ret
+}
+
+// JSON returns the table as JSON object with all names
+func (table *CostTable) JSON() string {
+	out, _ := json.Marshal(table.Map())
+	return string(out)
+}
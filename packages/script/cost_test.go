@@ -0,0 +1,93 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"testing"
+)
+
+func TestCostTable(t *testing.T) {
+	for _, input := range []string{`[1, 2]`, `{"add": -1}`, `{"unknown": 1}`, `{"add": "1"}`} {
+		if _, err := ParseCostTable(input); err == nil {
+			t.Errorf(`wrong cost table %s must be rejected`, input)
+		}
+	}
+	table, err := ParseCostTable(`{"while": 100, "string": 10}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if table.Command(cmdWhile) != 100 || table.Command(cmdAdd) != 1 || table.Call != CostCall {
+		t.Errorf(`wrong cost table %s`, table.JSON())
+	}
+	if table.StringCost(0) != 0 || table.StringCost(1) != 10 || table.StringCost(CostStringChunk+1) != 20 {
+		t.Errorf(`wrong cost of string`)
+	}
+
+	def := NewCostTable()
+	if def.Command(cmdWhile) != 1 || def.Command(cmdCallExtend) != 1 || def.StringCost(100) != 0 {
+		t.Errorf(`default cost table must charge as before %s`, def.JSON())
+	}
+
+	vm := NewVM()
+	if err = vm.Compile([]rune(`func loop int {
+		var i int
+		while i < 10 {
+			i = i + 1
+		}
+		return i
+	}
+	func concat string {
+		var s string
+		s = "0123456789"
+		return s + s + s + s + s + s + s + s
+	}
+	func grow int {
+		var a array
+		a[1000] = 1
+		return a[1000]
+	}
+	func huge int {
+		var a array
+		a[1000000000000] = 1
+		return 1
+	}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1}); err != nil {
+		t.Error(err)
+		return
+	}
+	spent := func(name string, costs *CostTable) int64 {
+		rt := vm.RunInit(CostDefault)
+		rt.SetCostTable(costs)
+		if _, err := rt.Run(vm.getObjByNameExt(name, 1).Value.(*Block), nil, &map[string]interface{}{}); err != nil {
+			t.Error(err)
+		}
+		return CostDefault - rt.Cost()
+	}
+	if def, cost := spent(`loop`, NewCostTable()), spent(`loop`, table); cost-def != 11*(100-1) {
+		t.Errorf(`wrong cost of while %d %d`, def, cost)
+	}
+	if def, cost := spent(`concat`, NewCostTable()), spent(`concat`, table); cost <= def {
+		t.Errorf(`wrong cost of strings %d %d`, def, cost)
+	}
+	if cost := spent(`grow`, NewCostTable()); cost < 1001*CostElement {
+		t.Errorf(`growth of array must be paid %d`, cost)
+	}
+	rt := vm.RunInit(CostDefault)
+	if _, err = rt.Run(vm.getObjByNameExt(`huge`, 1).Value.(*Block), nil, &map[string]interface{}{}); err == nil {
+		t.Errorf(`huge array must be rejected`)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TraceItem is the information about the executed command of the byte-code
+type TraceItem struct {
+	Name  string                 `json:"name"` // the name of the contract or the function
+	Line  uint32                 `json:"line"`
+	Cmd   string                 `json:"cmd"`
+	Stack []interface{}          `json:"stack"`
+	Vars  map[string]interface{} `json:"vars"`
+	Cost  int64                  `json:"cost"` // the spent cost since the debugger has been attached
+}
+
+// Breakpoint is the line of the contract or the function where the execution is paused.
+// Empty Name matches any contract or function.
+type Breakpoint struct {
+	Name string `json:"name"`
+	Line uint32 `json:"line"`
+}
+
+// DebugAction is the action which OnPause handler returns to go on
+type DebugAction int
+
+const (
+	// DebugContinue continues the execution till the next breakpoint
+	DebugContinue DebugAction = iota
+	// DebugStep continues the execution till the next line of the source code
+	DebugStep
+	// DebugStop breaks the execution with the error
+	DebugStop
+)
+
+// Debugger traces the execution of the byte-code and pauses it on breakpoints and steps
+type Debugger struct {
+	Trace       bool // every executed command is saved in Items if it is true
+	MaxItems    int  // the maximum count of Items, 0 means no limit
+	Items       []*TraceItem
+	Breakpoints []Breakpoint
+	Step        bool // the execution is paused on the next line if it is true
+	// OnPause is called when the execution has been paused
+	OnPause func(item *TraceItem) DebugAction
+
+	attached bool
+	limit    int64
+	name     string
+	line     uint32
+	names    map[*Block]string
+}
+
+// SetDebugger attaches the debugger to the runtime
+func (rt *RunTime) SetDebugger(debugger *Debugger) {
+	if !debugger.attached {
+		debugger.attached = true
+		debugger.limit = rt.cost
+		debugger.names = make(map[*Block]string)
+	}
+	rt.debug = debugger
+}
+
+// blockName returns the name of the contract or the function on the top level of the source code
+func (d *Debugger) blockName(block *Block) string {
+	if name, ok := d.names[block]; ok {
+		return name
+	}
+	var name string
+	top := block
+	for top.Parent != nil && top.Parent.Parent != nil {
+		top = top.Parent
+	}
+	if top.Type == ObjContract {
+		name = top.Info.(*ContractInfo).Name
+	} else if top.Parent != nil {
+		for key, obj := range top.Parent.Objects {
+			if obj.Type == ObjFunc && obj.Value.(*Block) == top {
+				name = key
+				break
+			}
+		}
+	}
+	d.names[block] = name
+	return name
+}
+
+func (d *Debugger) isBreakpoint(name string, line uint32) bool {
+	for _, item := range d.Breakpoints {
+		if item.Line == line && (len(item.Name) == 0 || item.Name == name) {
+			return true
+		}
+	}
+	return false
+}
+
+// visibleVars returns the values of the variables which are visible in the block
+func (rt *RunTime) visibleVars(block *Block) map[string]interface{} {
+	ret := make(map[string]interface{})
+	var chain []*Block
+	for parent := block; parent != nil; parent = parent.Parent {
+		chain = append(chain, parent)
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		for k := len(rt.blocks) - 1; k >= 0; k-- {
+			if rt.blocks[k].Block != chain[i] {
+				continue
+			}
+			for name, obj := range chain[i].Objects {
+				if obj.Type == ObjVar {
+					ret[name] = snapshotValue(rt.vars[rt.blocks[k].Offset+obj.Value.(int)])
+				}
+			}
+			break
+		}
+	}
+	return ret
+}
+
+// snapshotValue returns the copy of the value so the saved item isn't changed by the further execution.
+// Arrays and maps are changed in place by the byte-code so they are copied recursively
+func snapshotValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for i, item := range v {
+			ret[i] = snapshotValue(item)
+		}
+		return ret
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			ret[key] = snapshotValue(item)
+		}
+		return ret
+	}
+	return value
+}
+
+// check is called before the execution of every command
+func (d *Debugger) check(rt *RunTime, block *Block, ci int) error {
+	var line uint32
+	if ci < len(block.Lines) {
+		line = block.Lines[ci]
+	}
+	name := d.blockName(block)
+	pause := false
+	if line > 0 && (line != d.line || name != d.name) {
+		pause = d.OnPause != nil && (d.Step || d.isBreakpoint(name, line))
+	}
+	d.name, d.line = name, line
+	if !pause && (!d.Trace || (d.MaxItems > 0 && len(d.Items) >= d.MaxItems)) {
+		return nil
+	}
+	item := &TraceItem{Name: name, Line: line, Cmd: cmdNames[block.Code[ci].Cmd],
+		Stack: snapshotValue(rt.stack).([]interface{}), Vars: rt.visibleVars(block), Cost: d.limit - rt.cost}
+	if d.Trace && (d.MaxItems == 0 || len(d.Items) < d.MaxItems) {
+		d.Items = append(d.Items, item)
+	}
+	if pause {
+		switch d.OnPause(item) {
+		case DebugStep:
+			d.Step = true
+		case DebugContinue:
+			d.Step = false
+		case DebugStop:
+			log.WithFields(log.Fields{"type": consts.VMError, "name": name, "line": line}).Warning("execution has been stopped by debugger")
+			return fmt.Errorf(`execution has been stopped by debugger`)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"testing"
+)
+
+func TestDebugger(t *testing.T) {
+	vm := NewVM()
+	if err := vm.Compile([]rune(`func debug int {
+		var a, b int
+		a = 5
+		b = a * 2
+		if b > 5 {
+			a = 1
+		}
+		return a + b
+	}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1}); err != nil {
+		t.Error(err)
+		return
+	}
+	block := vm.getObjByNameExt(`debug`, 1).Value.(*Block)
+
+	var lines []uint32
+	debugger := &Debugger{Trace: true, Breakpoints: []Breakpoint{{Name: `debug`, Line: 4}},
+		OnPause: func(item *TraceItem) DebugAction {
+			lines = append(lines, item.Line)
+			if item.Line == 4 && item.Vars[`a`] != int64(5) {
+				t.Errorf(`wrong value of a %v`, item.Vars[`a`])
+			}
+			if item.Line == 5 {
+				return DebugContinue
+			}
+			return DebugStep
+		}}
+	rt := vm.RunInit(1000)
+	rt.SetDebugger(debugger)
+	ret, err := rt.Run(block, nil, &map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(ret) != 1 || ret[0] != int64(11) {
+		t.Errorf(`wrong result %v`, ret)
+	}
+	if len(lines) != 2 || lines[0] != 4 || lines[1] != 5 {
+		t.Errorf(`wrong paused lines %v`, lines)
+	}
+	if len(debugger.Items) == 0 || debugger.Items[len(debugger.Items)-1].Line != 8 ||
+		debugger.Items[len(debugger.Items)-1].Cost == 0 {
+		t.Errorf(`wrong trace %v`, debugger.Items)
+	}
+
+	debugger = &Debugger{Step: true, OnPause: func(item *TraceItem) DebugAction {
+		return DebugStop
+	}}
+	rt = vm.RunInit(1000)
+	rt.SetDebugger(debugger)
+	if _, err = rt.Run(block, nil, &map[string]interface{}{}); err == nil ||
+		err.Error() != `execution has been stopped by debugger` {
+		t.Errorf(`wrong stop error %v`, err)
+	}
+}
+
+func TestDebuggerSnapshot(t *testing.T) {
+	vm := NewVM()
+	if err := vm.Compile([]rune(`func snapshot int {
+		var list array
+		list[0] = 1
+		list[0] = 2
+		return list[0]
+	}`), &OwnerInfo{StateID: 1, Active: true, TableID: 1}); err != nil {
+		t.Error(err)
+		return
+	}
+	block := vm.getObjByNameExt(`snapshot`, 1).Value.(*Block)
+	var paused []*TraceItem
+	debugger := &Debugger{Breakpoints: []Breakpoint{{Line: 4}, {Line: 5}},
+		OnPause: func(item *TraceItem) DebugAction {
+			paused = append(paused, item)
+			return DebugContinue
+		}}
+	rt := vm.RunInit(1000)
+	rt.SetDebugger(debugger)
+	if _, err := rt.Run(block, nil, &map[string]interface{}{}); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(paused) != 2 {
+		t.Errorf(`wrong count of pauses %d`, len(paused))
+		return
+	}
+	for i, value := range []int64{1, 2} {
+		if list, ok := paused[i].Vars[`list`].([]interface{}); !ok || len(list) != 1 || list[0] != value {
+			t.Errorf(`wrong snapshot of list at line %d %v`, paused[i].Line, paused[i].Vars[`list`])
+		}
+	}
+}
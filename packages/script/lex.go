@@ -58,6 +58,7 @@ const (
 	isRPar   = 0x2901 // )
 	isComma  = 0x2c01 // ,
 	isDot    = 0x2e01 // .
+	isColon  = 0x3a01 // :
 	isEq     = 0x3d01 // =
 	isLCurly = 0x7b01 // {
 	isRCurly = 0x7d01 // }
@@ -106,6 +107,8 @@ const (
 	keyCond
 	keyTail
 	keyError
+	keyFor
+	keyIn
 )
 
 var (
@@ -114,7 +117,7 @@ var (
 		`if`: keyIf, `else`: keyElse, `error`: keyError, `warning`: keyWarning, `info`: keyInfo,
 		`while`: keyWhile, `data`: keyTX, `settings`: keySettings, `nil`: keyNil, `action`: keyAction, `conditions`: keyCond,
 		`true`: keyTrue, `false`: keyFalse, `break`: keyBreak, `continue`: keyContinue,
-		`var`: keyVar, `...`: keyTail}
+		`var`: keyVar, `...`: keyTail, `for`: keyFor, `in`: keyIn}
 	// list of available types
 	// The list of types which save the corresponding 'reflect' type
 	types = map[string]reflect.Type{`bool`: reflect.TypeOf(true), `bytes`: reflect.TypeOf([]byte{}),
@@ -178,8 +181,8 @@ func lexParser(input []rune) (Lexems, error) {
 			todo(input[off])
 		}
 		if curState == lexError {
-			return nil, fmt.Errorf(`unknown lexem %s [Ln:%d Col:%d]`,
-				string(input[off:off+1]), line, off-offline+1)
+			return nil, &CompileError{fmt.Sprintf(`unknown lexem %s [Ln:%d Col:%d]`,
+				string(input[off:off+1]), line, off-offline+1), line, off - offline + 1}
 		}
 		if (flags & lexfSkip) != 0 {
 			off++
@@ -233,13 +236,15 @@ func lexParser(input []rune) (Lexems, error) {
 						value = val
 					} else {
 						log.WithFields(log.Fields{"error": err, "value": name, "lex_line": line, "lex_col": off - offline + 1, "type": consts.ConvertionError}).Error("converting lex number to float")
-						return nil, fmt.Errorf(`%v %s [Ln:%d Col:%d]`, err, name, line, off-offline+1)
+						return nil, &CompileError{fmt.Sprintf(`%v %s [Ln:%d Col:%d]`, err, name, line, off-offline+1),
+							line, off - offline + 1}
 					}
 				} else if val, err := strconv.ParseInt(name, 10, 64); err == nil {
 					value = val
 				} else {
 					log.WithFields(log.Fields{"error": err, "value": name, "lex_line": line, "lex_col": off - offline + 1, "type": consts.ConvertionError}).Error("converting lex number to int")
-					return nil, fmt.Errorf(`%v %s [Ln:%d Col:%d]`, err, name, line, off-offline+1)
+					return nil, &CompileError{fmt.Sprintf(`%v %s [Ln:%d Col:%d]`, err, name, line, off-offline+1),
+						line, off - offline + 1}
 				}
 			case lexIdent:
 				name := string(input[lexOff:right])
@@ -3,44 +3,44 @@ package script
 	
 var (
 		alphabet = []byte{0,0,0,0,0,0,0,0,0,2,1,0,0,2,0,0,0,0,0,0,0,0,0,0,0,
-			0,0,0,0,0,0,0,2,20,4,14,22,0,12,0,6,7,21,24,16,25,15,26,28,
-			29,29,29,29,29,29,29,29,29,0,5,17,19,18,0,23,30,30,30,30,30,30,30,30,
-			30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,8,27,9,0,31,3,
-			30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,30,
-			30,30,10,13,11,0,0,32,
+			0,0,0,0,0,0,0,2,21,4,15,23,0,13,0,6,7,22,25,17,26,16,27,29,
+			30,30,30,30,30,30,30,30,30,12,5,18,20,19,0,24,31,31,31,31,31,31,31,31,
+			31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,8,28,9,0,32,3,
+			31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,31,
+			31,31,10,14,11,0,0,33,
 		}
-		lexTable = [][33]uint32{
-			{ 0xff0000, 0x501, 0x1, 0x30003, 0xe0003, 0x501, 0x101, 0x101, 0x101, 0x101, 0x101, 0x101, 0x40003, 0x50003, 0x101, 0xa0003, 0x101, 0xf0003, 0xf0003, 0x60003, 0xf0003, 0x201, 0x80003, 0x80003, 0x201, 0x201, 0x70003, 0xff0000, 0xc0003, 0xc0003, 0x80003, 0x80003, 0x80003,
+		lexTable = [][34]uint32{
+			{ 0xff0000, 0x501, 0x1, 0x70003, 0x10003, 0x501, 0x101, 0x101, 0x101, 0x101, 0x101, 0x101, 0x101, 0x20003, 0xd0003, 0x101, 0xb0003, 0x101, 0xe0003, 0xe0003, 0x30003, 0xe0003, 0x201, 0xf0003, 0xf0003, 0x201, 0x201, 0xa0003, 0xff0000, 0xc0003, 0xc0003, 0xf0003, 0xf0003, 0xf0003,
 			},
-			{ 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0x405, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000,
+			{ 0x10001, 0x10001, 0x10001, 0x10001, 0x605, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x80008, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001,
 			},
-			{ 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0x705, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001,
+			{ 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0x205, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000,
 			},
-			{ 0x30001, 0x30001, 0x30001, 0x605, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001, 0x30001,
+			{ 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x205, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104,
 			},
-			{ 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0x205, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000,
+			{ 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x50001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001,
 			},
-			{ 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0x205, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000,
+			{ 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x705, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001, 0x40001,
 			},
-			{ 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x205, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104,
+			{ 0x60001, 0x0, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001, 0x60001,
 			},
-			{ 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0xd0001, 0x204, 0x204, 0x204, 0x204, 0x90005, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204,
+			{ 0x70001, 0x70001, 0x70001, 0x605, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001, 0x70001,
 			},
-			{ 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x80001, 0x80001, 0x80001, 0x80001, 0x80001,
+			{ 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001, 0x10001,
 			},
-			{ 0x90001, 0x0, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001, 0x90001,
+			{ 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0x405, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000,
 			},
-			{ 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x10001, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104,
+			{ 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x40001, 0x204, 0x204, 0x204, 0x204, 0x60005, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204,
 			},
-			{ 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001,
+			{ 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x90001, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104, 0x104,
 			},
-			{ 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0xc0001, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0xc0001, 0xc0001, 0xff0000, 0xff0000, 0xff0000,
+			{ 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0xc0001, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0x304, 0xc0001, 0xc0001, 0xff0000, 0xff0000, 0xff0000,
 			},
-			{ 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0x20001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001, 0xd0001,
+			{ 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0x205, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000, 0xff0000,
 			},
-			{ 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0x605, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xb0008, 0xe0001, 0xe0001, 0xe0001, 0xe0001, 0xe0001,
+			{ 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x205, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204,
 			},
-			{ 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x205, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204, 0x204,
+			{ 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0x404, 0xf0001, 0xf0001, 0xf0001, 0xf0001, 0xf0001,
 			},
 			}
 )
@@ -0,0 +1,397 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+const (
+	// LintError is the level of the problem which doesn't allow to compile the source code
+	LintError = `error`
+	// LintWarning is the level of the problem which can break the execution of the contract
+	LintWarning = `warning`
+)
+
+// LintMessage describes the problem which has been found in the source code
+type LintMessage struct {
+	Line    uint32 `json:"line"`
+	Column  uint32 `json:"column"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// lintScope contains the types of the variables of the block, nil type means any type
+type lintScope map[string]reflect.Type
+
+type linter struct {
+	vm       *VM
+	lexems   Lexems
+	funcs    map[string]*FuncInfo
+	messages []*LintMessage
+}
+
+// Lint compiles the source code without loading it into the virtual machine and returns the found problems.
+// Besides compile errors it reports unreachable code, unused fields of contracts, calls of
+// extended functions without the defined cost and the wrong types or count of parameters of calls.
+func (vm *VM) Lint(input []rune, owner *OwnerInfo) []*LintMessage {
+	lvm := *vm
+	lvm.Extern = true
+	root, err := lvm.CompileBlock(input, owner)
+	if err != nil {
+		msg := &LintMessage{Level: LintError, Message: err.Error()}
+		if cerr, ok := err.(*CompileError); ok {
+			msg.Line, msg.Column = cerr.Line, cerr.Column
+		}
+		return []*LintMessage{msg}
+	}
+	lexems, _ := lexParser(input)
+	l := &linter{vm: &lvm, lexems: lexems, funcs: make(map[string]*FuncInfo)}
+	l.collectFuncs(root)
+	l.unreachable()
+	l.unusedFields()
+	l.calls()
+	sort.SliceStable(l.messages, func(i, j int) bool {
+		if l.messages[i].Line == l.messages[j].Line {
+			return l.messages[i].Column < l.messages[j].Column
+		}
+		return l.messages[i].Line < l.messages[j].Line
+	})
+	return l.messages
+}
+
+func (l *linter) warning(lexem *Lexem, format string, params ...interface{}) {
+	l.messages = append(l.messages, &LintMessage{lexem.Line, lexem.Column, LintWarning,
+		fmt.Sprintf(format, params...)})
+}
+
+// collectFuncs gathers the functions which have been declared in the source code
+func (l *linter) collectFuncs(block *Block) {
+	names := make([]string, 0, len(block.Objects))
+	for name := range block.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		obj := block.Objects[name]
+		if _, ok := l.funcs[name]; !ok && obj.Type == ObjFunc {
+			l.funcs[name] = obj.Value.(*Block).Info.(*FuncInfo)
+		}
+	}
+	for _, child := range block.Children {
+		l.collectFuncs(child)
+	}
+}
+
+// next returns the index of the next lexeme skipping new lines and comments
+func (l *linter) next(i int) int {
+	for i++; i < len(l.lexems) && (l.lexems[i].Type == lexNewLine || l.lexems[i].Type == lexComment); i++ {
+	}
+	return i
+}
+
+// statementEnd returns the index of the last lexeme of the statement which starts at i position
+func (l *linter) statementEnd(i int) int {
+	depth := 0
+	for j := i + 1; j < len(l.lexems); j++ {
+		switch l.lexems[j].Type {
+		case isLPar, isLBrack, isLCurly:
+			depth++
+		case isRPar, isRBrack:
+			depth--
+		case isRCurly:
+			if depth == 0 {
+				return j - 1
+			}
+			depth--
+		case lexNewLine:
+			if depth == 0 && l.lexems[j-1].Type != isComma && l.lexems[j-1].Type&0xff != lexOper {
+				return j - 1
+			}
+		}
+	}
+	return len(l.lexems) - 1
+}
+
+// unreachable reports the statements after return, error, break and continue in the same block
+func (l *linter) unreachable() {
+	for i, lexem := range l.lexems {
+		if lexem.Type&0xff != lexKeyword {
+			continue
+		}
+		end := i
+		switch lexem.Type >> 8 {
+		case keyReturn, keyError, keyWarning, keyInfo:
+			end = l.statementEnd(i)
+		case keyBreak, keyContinue:
+		default:
+			continue
+		}
+		if next := l.next(end); next < len(l.lexems) && l.lexems[next].Type != isRCurly {
+			l.warning(l.lexems[next], `unreachable code`)
+		}
+	}
+}
+
+// unusedFields reports the fields of the contracts which are not used as $name in the contracts
+func (l *linter) unusedFields() {
+	var (
+		fields               []*Lexem
+		used                 map[string]bool
+		depth, contractDepth int
+		contract, data       bool
+	)
+	for _, lexem := range l.lexems {
+		switch lexem.Type {
+		case lexKeyword | (keyContract << 8):
+			contract, contractDepth = true, depth
+			fields, used = nil, make(map[string]bool)
+		case lexKeyword | (keyTX << 8):
+			data = contract
+		case isLCurly:
+			depth++
+		case isRCurly:
+			depth--
+			if data && depth == contractDepth+1 {
+				data = false
+			}
+			if contract && depth == contractDepth {
+				contract = false
+				for _, field := range fields {
+					if !used[field.Value.(string)] {
+						l.warning(field, `field %s is not used`, field.Value.(string))
+					}
+				}
+			}
+		case lexExtend:
+			if contract {
+				used[lexem.Value.(string)] = true
+			}
+		case lexIdent:
+			if data && depth == contractDepth+2 {
+				fields = append(fields, lexem)
+			}
+		}
+	}
+}
+
+const (
+	declNone = iota
+	declVar  // var a, b int
+	declFunc // func name(a int, b string)
+	declFor  // for item in list
+)
+
+// calls checks the calls of the functions and keeps track of the declared variables
+func (l *linter) calls() {
+	var (
+		names   []string
+		blocks  []bool
+		pending lintScope
+		target  lintScope
+		decl    int
+	)
+	scopes := []lintScope{make(lintScope)}
+	for i, lexem := range l.lexems {
+		switch lexem.Type {
+		case lexKeyword | (keyVar << 8):
+			decl, target = declVar, scopes[len(scopes)-1]
+		case lexKeyword | (keyFunc << 8), lexKeyword | (keyFor << 8):
+			decl, pending = declFunc, make(lintScope)
+			if lexem.Type == lexKeyword|(keyFor<<8) {
+				decl = declFor
+			}
+			target = pending
+		case lexKeyword | (keyIn << 8):
+			for _, name := range names {
+				target[name] = nil
+			}
+			decl, names = declNone, nil
+		case lexType:
+			if decl != declNone {
+				for _, name := range names {
+					target[name] = lexem.Value.(reflect.Type)
+				}
+				names = nil
+			}
+		case lexNewLine:
+			if decl == declVar {
+				decl, names = declNone, nil
+			}
+		case isLCurly:
+			isBlock := !isLintLiteral(&l.lexems, i)
+			blocks = append(blocks, isBlock)
+			if isBlock {
+				if pending == nil {
+					pending = make(lintScope)
+				}
+				scopes = append(scopes, pending)
+				decl, names, pending = declNone, nil, nil
+			}
+		case isRCurly:
+			if decl == declVar {
+				decl, names = declNone, nil
+			}
+			if len(blocks) > 0 {
+				if blocks[len(blocks)-1] && len(scopes) > 1 {
+					scopes = scopes[:len(scopes)-1]
+				}
+				blocks = blocks[:len(blocks)-1]
+			}
+		case lexIdent:
+			call := i+1 < len(l.lexems) && l.lexems[i+1].Type == isLPar
+			if decl == declNone {
+				if call {
+					l.checkCall(i, scopes)
+				}
+			} else if !call && (decl != declFunc || l.lexems[i-1].Type != lexKeyword|(keyFunc<<8)) {
+				names = append(names, lexem.Value.(string))
+			}
+		}
+	}
+}
+
+// checkCall checks the cost of the extended function and the parameters of the call at i position
+func (l *linter) checkCall(i int, scopes []lintScope) {
+	var (
+		params   []reflect.Type
+		variadic bool
+	)
+	lexem := l.lexems[i]
+	name := lexem.Value.(string)
+	if finfo, ok := l.funcs[name]; ok {
+		params, variadic = finfo.Params, finfo.Variadic
+	} else if obj, ok := l.vm.Objects[name]; ok && obj.Type == ObjExtFunc {
+		finfo := obj.Value.(ExtFuncInfo)
+		if l.vm.ExtCost != nil && l.vm.ExtCost(name) == -1 {
+			if _, ok := l.vm.FuncCallsDB[name]; !ok {
+				l.warning(lexem, `cost of function %s is not defined`, name)
+			}
+		}
+		for k, par := range finfo.Params {
+			if len(finfo.Auto[k]) == 0 {
+				params = append(params, par)
+			}
+		}
+		if name == `CallContract` && len(params) > 0 {
+			// the compiler passes the ecosystem itself
+			params = params[1:]
+		}
+		variadic = finfo.Variadic
+	} else {
+		return
+	}
+	args := l.callArgs(i + 1)
+	if variadic && len(params) > 0 {
+		params = params[:len(params)-1]
+		if len(args) < len(params) {
+			l.warning(lexem, `function %s expects at least %d parameters but %d are passed`, name,
+				len(params), len(args))
+			return
+		}
+	} else if len(args) != len(params) {
+		l.warning(lexem, `function %s expects %d parameters but %d are passed`, name, len(params), len(args))
+		return
+	}
+	for k, par := range params {
+		if len(args[k]) != 1 || par.Kind() == reflect.Interface {
+			continue
+		}
+		if atype := argType(args[k][0], scopes); atype != nil && atype != par {
+			l.warning(args[k][0], `parameter %d of function %s must be %s but it is %s`, k+1, name,
+				typeName(par), typeName(atype))
+		}
+	}
+}
+
+// callArgs returns the lexemes of the parameters of the call which starts at the left parenthesis
+func (l *linter) callArgs(i int) [][]*Lexem {
+	var (
+		args [][]*Lexem
+		cur  []*Lexem
+	)
+	depth := 0
+	for i++; i < len(l.lexems); i++ {
+		lexem := l.lexems[i]
+		switch lexem.Type {
+		case isLPar, isLBrack, isLCurly:
+			depth++
+		case isRPar, isRBrack, isRCurly:
+			if depth == 0 {
+				if len(cur) > 0 || len(args) > 0 {
+					args = append(args, cur)
+				}
+				return args
+			}
+			depth--
+		case isComma:
+			if depth == 0 {
+				args, cur = append(args, cur), nil
+				continue
+			}
+		case lexNewLine, lexComment:
+			continue
+		}
+		cur = append(cur, lexem)
+	}
+	return args
+}
+
+// argType returns the type of the parameter which is a literal or a declared variable
+func argType(lexem *Lexem, scopes []lintScope) reflect.Type {
+	switch lexem.Type {
+	case lexString:
+		return reflect.TypeOf(``)
+	case lexNumber:
+		if lexem.Value != nil {
+			return reflect.TypeOf(lexem.Value)
+		}
+	case lexIdent:
+		for k := len(scopes) - 1; k >= 0; k-- {
+			if itype, ok := scopes[k][lexem.Value.(string)]; ok {
+				return itype
+			}
+		}
+	}
+	return nil
+}
+
+// typeName returns the name of the type in the contract language
+func typeName(itype reflect.Type) string {
+	for name, item := range types {
+		if item == itype {
+			return name
+		}
+	}
+	return itype.String()
+}
+
+// isLintLiteral returns true if the curly bracket starts the literal of map. isLiteral is called only
+// inside expressions, but the linter checks all curly brackets so the keywords like else or while
+// which are followed by the block must be excluded.
+func isLintLiteral(lexems *Lexems, i int) bool {
+	if i > 0 && (*lexems)[i-1].Type&0xff == lexKeyword {
+		switch (*lexems)[i-1].Type >> 8 {
+		case keyReturn, keyIn, keyError, keyWarning, keyInfo:
+			return true
+		}
+		return false
+	}
+	return isLiteral(lexems, i)
+}
@@ -0,0 +1,118 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package script
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	vm := NewVM()
+	vm.Extend(&ExtendData{map[string]interface{}{"Sprintf": fmt.Sprintf, "lenArray": lenArray}, nil})
+	vm.ExtCost = func(name string) int64 {
+		if name == `lenArray` {
+			return 10
+		}
+		return -1
+	}
+	input := `contract Lint {
+	data {
+		Name string
+		Amount int
+	}
+	func sum(a int, b int) int {
+		return a + b
+		a = 1
+	}
+	action {
+		var s string
+		var list array
+		s = Sprintf("%s", $Name)
+		sum(1, s)
+		sum(2)
+		for item in list {
+			lenArray(item)
+			break
+			s = ""
+		}
+		lenArray(s)
+		Unknown(1)
+	}
+}`
+	want := []LintMessage{
+		{4, 4, LintWarning, `field Amount is not used`},
+		{8, 4, LintWarning, `unreachable code`},
+		{13, 8, LintWarning, `cost of function Sprintf is not defined`},
+		{14, 11, LintWarning, `parameter 2 of function sum must be int but it is string`},
+		{15, 4, LintWarning, `function sum expects 2 parameters but 1 are passed`},
+		{19, 5, LintWarning, `unreachable code`},
+		{21, 13, LintWarning, `parameter 1 of function lenArray must be array but it is string`},
+	}
+	messages := vm.Lint([]rune(input), &OwnerInfo{StateID: 1})
+	if len(messages) != len(want) {
+		for _, msg := range messages {
+			t.Log(*msg)
+		}
+		t.Fatalf(`wrong count of messages %d != %d`, len(messages), len(want))
+	}
+	for i, msg := range messages {
+		if *msg != want[i] {
+			t.Errorf(`wrong message %v != %v`, *msg, want[i])
+		}
+	}
+	messages = vm.Lint([]rune(`contract Err {
+	action {
+		var i int
+		i = j + 1
+	}
+}`), &OwnerInfo{StateID: 1})
+	if len(messages) != 1 {
+		t.Fatalf(`wrong count of messages %d`, len(messages))
+	}
+	if *messages[0] != (LintMessage{4, 8, LintError, `unknown identifier j`}) {
+		t.Errorf(`wrong compile error %v`, *messages[0])
+	}
+	if _, ok := vm.Objects[`@1Lint`]; ok {
+		t.Errorf(`contract must not be loaded`)
+	}
+}
+
+func TestLintBlocks(t *testing.T) {
+	vm := NewVM()
+	messages := vm.Lint([]rune(`contract Blocks {
+	func get(a int) map {
+		if a > 0 {
+			return {"a": a}
+		} else {
+			return {"b": {"c": 1}}
+			a = 2
+		}
+		return {}
+	}
+	action {
+		get(1)
+	}
+}`), &OwnerInfo{StateID: 1})
+	// the block of else must be a scope but the map literals must not be
+	if len(messages) != 1 || *messages[0] != (LintMessage{7, 5, LintWarning, `unreachable code`}) {
+		for _, msg := range messages {
+			t.Log(*msg)
+		}
+		t.Errorf(`wrong messages of blocks`)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -57,6 +58,8 @@ type RunTime struct {
 	vm     *VM
 	cost   int64
 	err    error
+	debug  *Debugger
+	costs  *CostTable
 }
 
 func (rt *RunTime) callFunc(cmd uint16, obj *ObjInfo) (err error) {
@@ -293,12 +296,47 @@ func (rt *RunTime) Cost() int64 {
 	return rt.cost
 }
 
+// forItems returns the items of array or the sorted keys of map for the iteration
+func forItems(value interface{}) ([]interface{}, error) {
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.Slice:
+		items := make([]interface{}, val.Len())
+		for i := range items {
+			items[i] = val.Index(i).Interface()
+		}
+		return items, nil
+	case reflect.Map:
+		keys := make([]string, 0, val.Len())
+		for _, key := range val.MapKeys() {
+			if key.Kind() != reflect.String {
+				return nil, fmt.Errorf(`key of map must be string`)
+			}
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		items := make([]interface{}, len(keys))
+		for i, key := range keys {
+			items[i] = key
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf(`Type %T doesn't support iteration`, value)
+}
+
 // RunInit creates a new RunTime for the virtual machine
 func (vm *VM) RunInit(cost int64) *RunTime {
-	rt := RunTime{stack: make([]interface{}, 0, 1024), vm: vm, cost: cost}
+	rt := RunTime{stack: make([]interface{}, 0, 1024), vm: vm, cost: cost, costs: defaultCostTable}
 	return &rt
 }
 
+// SetCostTable sets the table of the costs of the commands for the runtime
+func (rt *RunTime) SetCostTable(costs *CostTable) {
+	if costs != nil {
+		rt.costs = costs
+	}
+}
+
 // RunCode executes Block
 func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	top := make([]interface{}, 8)
@@ -313,7 +351,7 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	start := len(rt.stack)
 	varoff := len(rt.vars)
 	for vkey, vpar := range block.Vars {
-		rt.cost--
+		rt.cost -= rt.costs.Var
 		var value interface{}
 		if block.Type == ObjFunc && vkey < len(block.Info.(*FuncInfo).Params) {
 			value = rt.stack[start-len(block.Info.(*FuncInfo).Params)+vkey]
@@ -350,12 +388,17 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 	labels := make([]int, 0)
 	//main:
 	for ci := 0; ci < len(block.Code); ci++ { //_, cmd := range block.Code {
-		rt.cost--
+		cmd := block.Code[ci]
+		rt.cost -= rt.costs.Command(cmd.Cmd)
 		if rt.cost <= 0 {
 			rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warn("paid CPU resource is over")
 			return 0, fmt.Errorf(`paid CPU resource is over`)
 		}
-		cmd := block.Code[ci]
+		if rt.debug != nil {
+			if err = rt.debug.check(rt, block, ci); err != nil {
+				return 0, err
+			}
+		}
 		var bin interface{}
 		size := len(rt.stack)
 		if size < int(cmd.Cmd>>8) {
@@ -364,6 +407,9 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 		}
 		for i := 1; i <= int(cmd.Cmd>>8); i++ {
 			top[i-1] = rt.stack[size-i]
+			if str, ok := top[i-1].(string); ok {
+				rt.cost -= rt.costs.StringCost(len(str))
+			}
 		}
 		switch cmd.Cmd {
 		case cmdPush:
@@ -460,13 +506,13 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 						rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warning("paid CPU resource is over")
 						return 0, fmt.Errorf(`paid CPU resource is over`)
 					} else if cost == -1 {
-						rt.cost -= CostCall
+						rt.cost -= rt.costs.Call
 					} else {
 						rt.cost -= cost
 					}
 				}
 			} else {
-				rt.cost -= CostCall
+				rt.cost -= rt.costs.Call
 			}
 			err = rt.callFunc(cmd.Cmd, cmd.Value.(*ObjInfo))
 
@@ -486,7 +532,7 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 			//rt.stack = append(rt.stack, rt.vars[voff+ivar.Obj.Value.(int)])
 		case cmdExtend, cmdCallExtend:
 			if val, ok := (*rt.extend)[cmd.Value.(string)]; ok {
-				rt.cost -= CostExtend
+				rt.cost -= rt.costs.Extend
 				if cmd.Cmd == cmdCallExtend {
 					err = rt.extendFunc(cmd.Value.(string))
 					if err != nil {
@@ -515,6 +561,11 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				}
 				rt.stack = rt.stack[:size-1]
 			case itype[:2] == brackets:
+				if ind := rt.stack[size-1].(int64); ind < 0 || ind >= int64(reflect.ValueOf(rt.stack[size-2]).Len()) {
+					rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "index": ind}).Error("index out of range")
+					err = fmt.Errorf(`index %d out of range`, ind)
+					break
+				}
 				if strings.Contains(itype, Interface) {
 					rt.stack[size-2] = rt.stack[size-2].([]interface{})[rt.stack[size-1].(int64)]
 				} else {
@@ -539,7 +590,21 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				ind := rt.stack[size-2].(int64)
 				if strings.Contains(itype, Interface) {
 					slice := rt.stack[size-3].([]interface{})
-					if int(ind) >= len(slice) {
+					if ind < 0 {
+						rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "index": ind}).Error("negative index")
+						err = fmt.Errorf(`index out of range`)
+						break
+					}
+					if ind >= int64(len(slice)) {
+						// the new elements of the slice are paid before the allocation
+						grow := ind - int64(len(slice)) + 1
+						if rt.costs.Element > 0 && grow > rt.cost/rt.costs.Element {
+							rt.cost = 0
+							rt.vm.logger.WithFields(log.Fields{"type": consts.VMError}).Warn("paid CPU resource is over")
+							err = fmt.Errorf(`paid CPU resource is over`)
+							break
+						}
+						rt.cost -= grow * rt.costs.Element
 						slice = append(slice, make([]interface{}, int(ind)-len(slice)+1)...)
 						for i := 0; i < len(rt.vars); i++ {
 							if reflect.TypeOf(rt.vars[i]).String()[:2] == brackets {
@@ -564,6 +629,48 @@ func (rt *RunTime) RunCode(block *Block) (status int, err error) {
 				rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "vm_type": itype}).Error("type does not support indexing")
 				err = fmt.Errorf(`Type %s doesn't support indexing`, itype)
 			}
+		case cmdArrayInit:
+			count := cmd.Value.(int)
+			rt.cost -= int64(count) * rt.costs.Element
+			list := make([]interface{}, count)
+			copy(list, rt.stack[size-count:])
+			rt.stack = append(rt.stack[:size-count], list)
+		case cmdMapInit:
+			count := cmd.Value.(int)
+			rt.cost -= int64(count/2) * rt.costs.Element
+			imap := make(map[string]interface{})
+			for i := size - count; i < size; i += 2 {
+				key, ok := rt.stack[i].(string)
+				if !ok {
+					rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "key": rt.stack[i]}).Error("key of map must be string")
+					err = fmt.Errorf(`key of map must be string`)
+					break
+				}
+				imap[key] = rt.stack[i+1]
+			}
+			rt.stack = append(rt.stack[:size-count], imap)
+		case cmdFor:
+			var items []interface{}
+			items, err = forItems(rt.stack[size-1])
+			rt.stack = rt.stack[:size-1]
+			if err != nil {
+				rt.vm.logger.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("for loop")
+				break
+			}
+			rt.cost -= int64(len(items)) * rt.costs.Element
+			for _, item := range items {
+				rt.stack = append(rt.stack, item)
+				status, err = rt.RunCode(cmd.Value.(*Block))
+				if err != nil || status == statusReturn {
+					break
+				}
+				rt.stack = rt.stack[:size-1]
+				if status == statusBreak {
+					status = statusNormal
+					break
+				}
+				status = statusNormal
+			}
 		case cmdSign:
 			switch top[0].(type) {
 			case float64:
@@ -53,12 +53,14 @@ const (
 	// ObjExtend is an extended variable. $myvar
 	ObjExtend
 
-	// CostCall is the cost of the function calling
+	// CostCall is the default cost of the function calling
 	CostCall = 50
-	// CostContract is the cost of the contract calling
+	// CostContract is the default cost of the contract calling
 	CostContract = 100
-	// CostExtend is the cost of the extend function calling
+	// CostExtend is the default cost of the extend function calling
 	CostExtend = 10
+	// CostElement is the default cost of the processing of one item of array or map
+	CostElement = 1
 	// CostDefault is the default maximum cost of F
 	CostDefault = int64(10000000)
 )
@@ -139,6 +141,7 @@ type Block struct {
 	Parent   *Block
 	Vars     []reflect.Type
 	Code     ByteCodes
+	Lines    []uint32 // the lines of the source code for the commands of Code
 	Children Blocks
 }
 
@@ -236,7 +239,7 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) error {
 			break
 		}
 	}
-	rt.cost -= CostContract
+	rt.cost -= rt.costs.Contract
 	var stackCont func(interface{}, string)
 	if stack, ok := (*rt.extend)[`stack_cont`]; ok && (*rt.extend)[`parser`] != nil {
 		stackCont = stack.(func(interface{}, string))
@@ -253,6 +256,8 @@ func ExecContract(rt *RunTime, name, txs string, params ...interface{}) error {
 	for _, method := range []string{`init`, `conditions`, `action`} {
 		if block, ok := (*cblock).Objects[method]; ok && block.Type == ObjFunc {
 			rtemp := rt.vm.RunInit(rt.cost)
+			rtemp.debug = rt.debug
+			rtemp.costs = rt.costs
 			(*rt.extend)[`parent`] = parent
 			_, err := rtemp.Run(block.Value.(*Block), nil, rt.extend)
 			rt.cost = rtemp.cost
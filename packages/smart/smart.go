@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/AplaProject/go-apla/packages/config/syspar"
 	"github.com/AplaProject/go-apla/packages/consts"
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/model"
@@ -100,6 +101,11 @@ func CompileBlock(src string, owner *script.OwnerInfo) (*script.Block, error) {
 	return smartVM.CompileBlock([]rune(src), owner)
 }
 
+// Lint checks the source code with smartVM and returns the found problems
+func Lint(src string, owner *script.OwnerInfo) []*script.LintMessage {
+	return smartVM.Lint([]rune(src), owner)
+}
+
 // CompileEval calls CompileEval for smartVM
 func CompileEval(src string, prefix uint32) error {
 	return smartVM.CompileEval(src, prefix)
@@ -131,12 +137,22 @@ func Extend(ext *script.ExtendData) {
 
 // Run executes Block in smartVM
 func Run(block *script.Block, params []interface{}, extend *map[string]interface{}) (ret []interface{}, err error) {
+	return RunDebug(block, params, extend, nil)
+}
+
+// RunDebug executes Block in smartVM, if debugger is not nil then it traces the execution
+func RunDebug(block *script.Block, params []interface{}, extend *map[string]interface{},
+	debugger *script.Debugger) (ret []interface{}, err error) {
 	var extcost int64
 	cost := script.CostDefault
 	if ecost, ok := (*extend)[`txcost`]; ok {
 		cost = ecost.(int64)
 	}
 	rt := smartVM.RunInit(cost)
+	rt.SetCostTable(syspar.GetCostTable())
+	if debugger != nil {
+		rt.SetDebugger(debugger)
+	}
 	ret, err = rt.Run(block, params, extend)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.VMError, "error": err}).Error("running block in smart vm")
@@ -204,6 +220,30 @@ func GetContractByID(id int32) *Contract {
 		Block: smartVM.Children[idcont]}
 }
 
+// GetContractsByState returns the list of the actual contracts of the specified state.
+// The contracts which have been replaced by the recompiled ones are skipped.
+func GetContractsByState(state uint32) []*Contract {
+	list := make([]*Contract, 0)
+	for _, item := range smartVM.Children {
+		if item == nil || item.Type != script.ObjContract {
+			continue
+		}
+		cinfo, ok := item.Info.(*script.ContractInfo)
+		if !ok || cinfo.Owner == nil || cinfo.Owner.StateID != state {
+			continue
+		}
+		obj, ok := smartVM.Objects[cinfo.Name]
+		if !ok {
+			continue
+		}
+		// the recompiled contract replaces the previous block in Objects
+		if block, ok := obj.Value.(*script.Block); ok && block == item {
+			list = append(list, &Contract{Name: cinfo.Name, Block: item})
+		}
+	}
+	return list
+}
+
 // GetFunc returns the block of the specified function in the contract
 func (contract *Contract) GetFunc(name string) *script.Block {
 	if block, ok := (*contract).Block.Objects[name]; ok && block.Type == script.ObjFunc {
@@ -0,0 +1,406 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// ProtocolVersion is the current version of the protocol
+	ProtocolVersion = 2
+	// MinProtocolVersion is the minimal supported version of the protocol.
+	// Version 1 is the protocol without handshake
+	MinProtocolVersion = 1
+	// AuthProtocolVersion is the first version of the protocol with the authentication and the encryption
+	AuthProtocolVersion = 2
+
+	// DataTypeHandshake is the type of the request which starts the handshake
+	DataTypeHandshake = 100
+
+	challengeSize = 32
+)
+
+// The features which can be negotiated in the handshake
+const (
+	// FeatureAuth means that the peer authenticates with the key of the full node
+	FeatureAuth uint32 = 1 << iota
+	// FeatureEncryption means that the connection between full nodes is encrypted
+	FeatureEncryption
+)
+
+// SupportedFeatures is the set of the features which the node supports
+const SupportedFeatures = FeatureAuth | FeatureEncryption
+
+const (
+	authFailed uint8 = iota
+	authOK
+)
+
+var (
+	errVersion   = errors.New("unsupported protocol version")
+	errAuth      = errors.New("authentication failed")
+	errKeyID     = errors.New("key of the peer does not match the session")
+	errNotSecure = errors.New("connection is not encrypted")
+)
+
+// HandshakeRequest starts the handshake, KeyID is zero for anonymous peer
+type HandshakeRequest struct {
+	Version    uint16
+	MinVersion uint16
+	Features   uint32
+	KeyID      int64
+}
+
+// HandshakeResponse contains the agreed version and features, Version is zero if there is not common version
+type HandshakeResponse struct {
+	Version   uint16
+	Features  uint32
+	Challenge []byte `size:"32"`
+}
+
+// AuthRequest contains the signature of the challenge
+type AuthRequest struct {
+	Signature []byte
+}
+
+// AuthResponse is the result of the authentication
+type AuthResponse struct {
+	Status uint8
+}
+
+// KeyExchange contains the ephemeral public key which is signed by the key of the full node
+type KeyExchange struct {
+	KeyID     int64
+	Public    []byte `size:"64"`
+	Signature []byte
+}
+
+// Session is the state of the connection with the peer after the handshake
+type Session struct {
+	Host     string
+	Version  uint16
+	Features uint32
+	KeyID    int64         // the key of the authenticated full node, zero for anonymous peer
+	Conn     io.ReadWriter // the connection for the requests after the handshake
+}
+
+// NodeIdentity is the key of the node for the authentication in the handshake
+type NodeIdentity struct {
+	KeyID      int64
+	PrivateKey string
+}
+
+// Authenticated returns true if the peer has proved the key of the full node
+func (s *Session) Authenticated() bool {
+	return s.KeyID != 0
+}
+
+// Encrypted returns true if the connection is encrypted
+func (s *Session) Encrypted() bool {
+	return s.Features&FeatureEncryption != 0
+}
+
+// LocalIdentity returns the key of this node or nil if this node is not the full node
+var LocalIdentity = func() (*NodeIdentity, error) {
+	config := &model.Config{}
+	if _, err := config.Get(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting config")
+		return nil, err
+	}
+	if syspar.GetNode(config.KeyID) == nil {
+		return nil, nil
+	}
+	nodeKey := &model.MyNodeKey{}
+	if err := nodeKey.GetNodeWithMaxBlockID(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting node key")
+		return nil, err
+	}
+	return &NodeIdentity{KeyID: config.KeyID, PrivateKey: nodeKey.PrivateKey}, nil
+}
+
+func isEncryptionEnabled() bool {
+	return *utils.TCPEncryption != utils.TCPEncryptionDisabled
+}
+
+func isEncryptionRequired() bool {
+	return *utils.TCPEncryption == utils.TCPEncryptionRequired
+}
+
+func keyExchangeForSign(challenge, public []byte) string {
+	return fmt.Sprintf("keyexchange,%x,%x", challenge, public)
+}
+
+// newKeyExchange generates the ephemeral key and signs it with the key of the node
+func newKeyExchange(node *NodeIdentity, challenge []byte) (*KeyExchange, []byte, error) {
+	private, public, err := crypto.GenBytesKeys()
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("generating ephemeral keys")
+		return nil, nil, err
+	}
+	signature, err := crypto.Sign(node.PrivateKey, keyExchangeForSign(challenge, public))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("signing ephemeral key")
+		return nil, nil, err
+	}
+	return &KeyExchange{KeyID: node.KeyID, Public: public, Signature: signature}, converter.FillLeft(private), nil
+}
+
+// checkKeyExchange checks that the ephemeral key is signed by the full node
+func checkKeyExchange(kx *KeyExchange, challenge []byte) error {
+	node := syspar.GetNode(kx.KeyID)
+	if node == nil {
+		return errAuth
+	}
+	ok, err := crypto.CheckSign(node.Public, keyExchangeForSign(challenge, kx.Public), kx.Signature)
+	if err != nil || !ok {
+		return errAuth
+	}
+	return nil
+}
+
+// hostName returns the host without the port
+func hostName(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		return name
+	}
+	return host
+}
+
+// serverKeyExchange agrees the key of the encryption with the authenticated client
+func serverKeyExchange(session *Session, node *NodeIdentity, challenge []byte, rw io.ReadWriter) error {
+	kx := &KeyExchange{}
+	if err := ReadRequest(kx, rw); err != nil {
+		return err
+	}
+	if kx.KeyID != session.KeyID {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": kx.KeyID, "host": session.Host}).Warning("key exchange of other node")
+		return errKeyID
+	}
+	if err := checkKeyExchange(kx, challenge); err != nil {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": kx.KeyID, "host": session.Host}).Warning("wrong signature of key exchange")
+		return err
+	}
+	resp, private, err := newKeyExchange(node, kx.Public)
+	if err != nil {
+		return err
+	}
+	if err = SendRequest(resp, rw); err != nil {
+		return err
+	}
+	shared, err := crypto.SharedKey(private, kx.Public)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("getting shared key")
+		return err
+	}
+	session.Conn, err = newSecureConn(rw, shared, false)
+	return err
+}
+
+// clientKeyExchange agrees the key of the encryption with the full node on the host
+func clientKeyExchange(session *Session, node *NodeIdentity, challenge []byte, rw io.ReadWriter) error {
+	kx, private, err := newKeyExchange(node, challenge)
+	if err != nil {
+		return err
+	}
+	if err = SendRequest(kx, rw); err != nil {
+		return err
+	}
+	resp := &KeyExchange{}
+	if err = ReadRequest(resp, rw); err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "host": session.Host}).Error("reading key exchange")
+		return err
+	}
+	if err = checkKeyExchange(resp, kx.Public); err != nil {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": resp.KeyID, "host": session.Host}).Error("wrong signature of key exchange")
+		return err
+	}
+	if peer := syspar.GetNode(resp.KeyID); hostName(peer.Host) != hostName(session.Host) {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": resp.KeyID, "host": session.Host}).Error("full node has other host")
+		return errKeyID
+	}
+	shared, err := crypto.SharedKey(private, resp.Public)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("getting shared key")
+		return err
+	}
+	session.KeyID = resp.KeyID
+	session.Conn, err = newSecureConn(rw, shared, true)
+	return err
+}
+
+// handshakeForSign returns the data which the peer must sign
+func handshakeForSign(challenge []byte, keyID int64, version uint16) string {
+	return fmt.Sprintf("handshake,%x,%d,%d", challenge, keyID, version)
+}
+
+// versionFeatures returns the features which can be negotiated in the version of the protocol
+func versionFeatures(version uint16) uint32 {
+	if version < AuthProtocolVersion {
+		return 0
+	}
+	return SupportedFeatures
+}
+
+func agreeVersion(req *HandshakeRequest) uint16 {
+	if req.Version < MinProtocolVersion || req.MinVersion > ProtocolVersion || req.MinVersion > req.Version {
+		return 0
+	}
+	if req.Version < ProtocolVersion {
+		return req.Version
+	}
+	return ProtocolVersion
+}
+
+// serverHandshake processes the handshake of the peer and fills the session
+func serverHandshake(session *Session, rw io.ReadWriter) error {
+	req := &HandshakeRequest{}
+	if err := ReadRequest(req, rw); err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "host": session.Host}).Error("reading handshake request")
+		return err
+	}
+	resp := &HandshakeResponse{Version: agreeVersion(req), Challenge: make([]byte, challengeSize)}
+	resp.Features = req.Features & versionFeatures(resp.Version)
+	var local *NodeIdentity
+	if resp.Features&FeatureEncryption != 0 && req.KeyID != 0 && isEncryptionEnabled() {
+		var err error
+		if local, err = LocalIdentity(); err != nil {
+			return err
+		}
+	}
+	if local == nil {
+		resp.Features &^= FeatureEncryption
+	}
+	if _, err := rand.Read(resp.Challenge); err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("generating challenge")
+		return err
+	}
+	if err := SendRequest(resp, rw); err != nil {
+		return err
+	}
+	if resp.Version == 0 {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "version": req.Version, "host": session.Host}).Warning("unsupported protocol version")
+		return errVersion
+	}
+	session.Version, session.Features, session.Conn = resp.Version, resp.Features, rw
+	if req.KeyID == 0 || resp.Version < AuthProtocolVersion {
+		return nil
+	}
+	auth := &AuthRequest{}
+	if err := ReadRequest(auth, rw); err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err, "host": session.Host}).Error("reading auth request")
+		return err
+	}
+	status := authFailed
+	if node := syspar.GetNode(req.KeyID); node != nil {
+		ok, err := crypto.CheckSign(node.Public, handshakeForSign(resp.Challenge, req.KeyID, resp.Version), auth.Signature)
+		if err == nil && ok {
+			status = authOK
+		}
+	}
+	if err := SendRequest(&AuthResponse{Status: status}, rw); err != nil {
+		return err
+	}
+	if status != authOK {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": req.KeyID, "host": session.Host}).Warning("handshake authentication failed")
+		return errAuth
+	}
+	session.KeyID = req.KeyID
+	if session.Encrypted() {
+		return serverKeyExchange(session, local, resp.Challenge, rw)
+	}
+	return nil
+}
+
+// Handshake makes the handshake with the node on the host. The peer is anonymous if node is nil.
+// The connection is encrypted if both nodes are full nodes and they support the encryption.
+// The authentication and the encryption are skipped if the agreed version is less than AuthProtocolVersion.
+// The requests must be sent to Conn of the returned session.
+func Handshake(rw io.ReadWriter, host string, node *NodeIdentity) (*Session, error) {
+	req := &HandshakeRequest{Version: ProtocolVersion, MinVersion: MinProtocolVersion, Features: SupportedFeatures}
+	if node != nil {
+		req.KeyID = node.KeyID
+	}
+	if node == nil || !isEncryptionEnabled() {
+		req.Features &^= FeatureEncryption
+	}
+	if err := SendRequest(&TransactionType{Type: DataTypeHandshake}, rw); err != nil {
+		return nil, err
+	}
+	if err := SendRequest(req, rw); err != nil {
+		return nil, err
+	}
+	resp := &HandshakeResponse{}
+	if err := ReadRequest(resp, rw); err != nil {
+		log.WithFields(log.Fields{"type": consts.ProtocolError, "error": err}).Error("reading handshake response")
+		return nil, err
+	}
+	if resp.Version == 0 {
+		log.WithFields(log.Fields{"type": consts.ProtocolError}).Error("unsupported protocol version")
+		return nil, errVersion
+	}
+	session := &Session{Host: host, Version: resp.Version, Features: resp.Features & versionFeatures(resp.Version),
+		Conn: rw}
+	if req.KeyID == 0 {
+		return session, nil
+	}
+	if session.Version < AuthProtocolVersion {
+		if isEncryptionRequired() {
+			log.WithFields(log.Fields{"type": consts.AccessDenied, "host": host, "version": session.Version}).Error("protocol version without encryption")
+			return nil, errNotSecure
+		}
+		return session, nil
+	}
+	signature, err := crypto.Sign(node.PrivateKey, handshakeForSign(resp.Challenge, req.KeyID, resp.Version))
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("signing handshake challenge")
+		return nil, err
+	}
+	if err = SendRequest(&AuthRequest{Signature: signature}, rw); err != nil {
+		return nil, err
+	}
+	auth := &AuthResponse{}
+	if err = ReadRequest(auth, rw); err != nil {
+		return nil, err
+	}
+	if auth.Status != authOK {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": req.KeyID}).Error("handshake authentication failed")
+		return nil, errAuth
+	}
+	if session.Encrypted() {
+		if err = clientKeyExchange(session, node, resp.Challenge, rw); err != nil {
+			return nil, err
+		}
+	} else if isEncryptionRequired() {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "host": host}).Error("connection is not encrypted")
+		return nil, errNotSecure
+	}
+	return session, nil
+}
@@ -0,0 +1,107 @@
+package tcpserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func handshakePipe(handshake func(net.Conn) (*Session, error)) (client, server *Session, clientErr, serverErr error) {
+	clientConn, serverConn := net.Pipe()
+	server = &Session{Host: `test`}
+	done := make(chan error, 1)
+	go func() {
+		dType := &TransactionType{}
+		err := ReadRequest(dType, serverConn)
+		if err == nil {
+			if dType.Type != DataTypeHandshake {
+				err = errors.New("wrong type of request")
+			} else {
+				err = serverHandshake(server, serverConn)
+			}
+		}
+		serverConn.Close()
+		done <- err
+	}()
+	client, clientErr = handshake(clientConn)
+	clientConn.Close()
+	serverErr = <-done
+	return
+}
+
+func TestHandshake(t *testing.T) {
+	client, server, clientErr, serverErr := handshakePipe(func(conn net.Conn) (*Session, error) {
+		return Handshake(conn, `test`, nil)
+	})
+	if clientErr != nil || serverErr != nil {
+		t.Fatalf("anonymous handshake failed: %v %v", clientErr, serverErr)
+	}
+	if client.Version != ProtocolVersion || server.Version != ProtocolVersion ||
+		client.Features != FeatureAuth || client.Encrypted() || server.Authenticated() {
+		t.Errorf("wrong sessions %+v %+v", client, server)
+	}
+
+	var auth AuthResponse
+	_, server, _, serverErr = handshakePipe(func(conn net.Conn) (*Session, error) {
+		SendRequest(&TransactionType{Type: DataTypeHandshake}, conn)
+		SendRequest(&HandshakeRequest{Version: ProtocolVersion, MinVersion: MinProtocolVersion, KeyID: 12345}, conn)
+		resp := &HandshakeResponse{}
+		if err := ReadRequest(resp, conn); err != nil {
+			return nil, err
+		}
+		SendRequest(&AuthRequest{Signature: make([]byte, 64)}, conn)
+		return nil, ReadRequest(&auth, conn)
+	})
+	if serverErr != errAuth || auth.Status != authFailed || server.Authenticated() {
+		t.Errorf("unknown node must not be authenticated: %v", serverErr)
+	}
+}
+
+func TestHandshakeVersion(t *testing.T) {
+	var resp HandshakeResponse
+	_, server, _, serverErr := handshakePipe(func(conn net.Conn) (*Session, error) {
+		SendRequest(&TransactionType{Type: DataTypeHandshake}, conn)
+		SendRequest(&HandshakeRequest{Version: MinProtocolVersion, MinVersion: MinProtocolVersion,
+			Features: SupportedFeatures, KeyID: 12345}, conn)
+		return nil, ReadRequest(&resp, conn)
+	})
+	if serverErr != nil || resp.Version != MinProtocolVersion || resp.Features != 0 ||
+		server.Features != 0 || server.Authenticated() {
+		t.Errorf("version without authentication must not have features: %v %+v %+v", serverErr, resp, server)
+	}
+}
+
+func TestAgreeVersion(t *testing.T) {
+	for _, item := range []struct {
+		version, min, want uint16
+	}{
+		{ProtocolVersion, MinProtocolVersion, ProtocolVersion},
+		{ProtocolVersion + 5, MinProtocolVersion, ProtocolVersion},
+		{MinProtocolVersion, MinProtocolVersion, MinProtocolVersion},
+		{ProtocolVersion + 5, ProtocolVersion + 1, 0},
+		{0, 0, 0},
+	} {
+		if v := agreeVersion(&HandshakeRequest{Version: item.version, MinVersion: item.min}); v != item.want {
+			t.Errorf("wrong version %d for %d-%d", v, item.min, item.version)
+		}
+	}
+}
+
+func TestPeerLimiter(t *testing.T) {
+	l := &peerLimiter{requests: make(map[string]int)}
+	for i := 0; i < maxPeerRequests; i++ {
+		if !l.acquire(`host`) {
+			t.Fatalf("request %d must be accepted", i)
+		}
+	}
+	if l.acquire(`host`) {
+		t.Errorf("too many requests must be rejected")
+	}
+	if !l.acquire(`other`) {
+		t.Errorf("request of other peer must be accepted")
+	}
+	l.release(`host`)
+	if !l.acquire(`host`) {
+		t.Errorf("request must be accepted after release")
+	}
+}
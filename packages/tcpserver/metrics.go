@@ -0,0 +1,64 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/AplaProject/go-apla/packages/metrics"
+)
+
+var (
+	connections = metrics.NewGauge(`apla_tcp_connections`,
+		`The number of the open incoming connections`)
+	connectionsTotal = metrics.NewCounter(`apla_tcp_connections_total`,
+		`The number of the accepted incoming connections`)
+	requestsTotal = metrics.NewCounter(`apla_tcp_requests_total`,
+		`The number of the incoming requests by the type`, `type`)
+	receivedBytes = metrics.NewCounter(`apla_tcp_received_bytes_total`,
+		`The number of the bytes received by the type of the request`, `type`)
+	sentBytes = metrics.NewCounter(`apla_tcp_sent_bytes_total`,
+		`The number of the bytes sent by the type of the request`, `type`)
+)
+
+// countingConn counts the bytes which have been read from and written to the connection
+type countingConn struct {
+	io.ReadWriter
+	received int64
+	sent     int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriter.Read(p)
+	c.received += int64(n)
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriter.Write(p)
+	c.sent += int64(n)
+	return n, err
+}
+
+// observe adds the request and its traffic to the metrics
+func (c *countingConn) observe(requestType uint16) {
+	label := strconv.Itoa(int(requestType))
+	requestsTotal.Inc(label)
+	receivedBytes.Add(float64(c.received), label)
+	sentBytes.Add(float64(c.sent), label)
+}
@@ -18,6 +18,50 @@ type TransactionType struct {
 	Type uint16
 }
 
+// type 14
+type VotesRequest struct {
+	FromID uint32 // the votes for the blocks starting with FromID are requested
+	Data   []byte // the votes of the client in JSON
+}
+type VotesResponse struct {
+	Data []byte // the votes of the host in JSON
+}
+
+// type 13
+type PeersRequest struct {
+	Host []byte // the address of the TCP server of the client, it is empty if the client doesn't accept connections
+}
+type PeersResponse struct {
+	Data []byte // the addresses of the peers separated by commas
+}
+
+// type 12
+type SnapshotRequest struct {
+	BlockID uint32 // zero BlockID requests the last snapshot
+	Offset  uint64 // the offset in the file of the snapshot to resume the download
+}
+type SnapshotResponse struct {
+	BlockID       uint32 // zero BlockID means that the host has no snapshot
+	Hash          []byte `size:"32"`
+	Size          uint64
+	CommittedID   uint32 // the block of the snapshot_hash parameter of the host
+	CommittedHash []byte `size:"32"`
+}
+type SnapshotChunk struct {
+	Data []byte // empty Data finishes the snapshot
+}
+
+// type 11
+type BlocksRangeRequest struct {
+	FromID  uint32 // zero FromID finishes the session
+	ToID    uint32
+	MaxSize uint32 // the byte budget of the batch of blocks
+}
+type BlockBody struct {
+	BlockID uint32 // zero BlockID finishes the batch
+	Data    []byte
+}
+
 // type 10
 type MaxBlockRequest struct{}
 type MaxBlockResponse struct {
@@ -158,6 +202,10 @@ func SendRequest(request interface{}, w io.Writer) error {
 
 func readUint(r io.Reader, byteCount int) (uint64, error) {
 	buf, err := readBytes(r, uint64(byteCount))
+	if err == io.EOF {
+		// the closed connection is returned as is, so the caller can detect it
+		return 0, err
+	}
 	if err != nil {
 		return 0, utils.ErrInfo(err)
 	}
@@ -18,9 +18,10 @@ package tcpserver
 
 import (
 	"flag"
+	"fmt"
 	"io"
 	"net"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -28,33 +29,87 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-var (
-	counter int64
-)
+// maxPeerRequests is the maximum number of the simultaneous requests from one peer
+const maxPeerRequests = 20
+
+// peerLimiter counts the simultaneous requests of every peer. The peer is the key
+// of the full node for authenticated session and the host for anonymous one
+type peerLimiter struct {
+	mutex    sync.Mutex
+	requests map[string]int
+}
+
+var limiter = &peerLimiter{requests: make(map[string]int)}
+
+func (l *peerLimiter) acquire(peer string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.requests[peer] >= maxPeerRequests {
+		return false
+	}
+	l.requests[peer]++
+	return true
+}
+
+func (l *peerLimiter) release(peer string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.requests[peer]--; l.requests[peer] <= 0 {
+		delete(l.requests, peer)
+	}
+}
 
 func init() {
 	flag.Parse()
 }
 
-// HandleTCPRequest proceed TCP requests
-func HandleTCPRequest(rw io.ReadWriter) {
-	defer func() {
-		atomic.AddInt64(&counter, -1)
-	}()
-
-	count := atomic.AddInt64(&counter, +1)
-	if count > 20 {
+// HandleTCPRequest proceed TCP requests from the host. The request can be preceded by the handshake
+func HandleTCPRequest(conn io.ReadWriter, host string) {
+	raw := conn
+	counter := &countingConn{ReadWriter: conn}
+	conn = counter
+	session := &Session{Host: host, Version: MinProtocolVersion, Conn: conn}
+	peer := host
+	if !limiter.acquire(peer) {
+		log.WithFields(log.Fields{"type": consts.ParameterExceeded, "host": host}).Warning("too many requests from peer")
 		return
 	}
-
 	dType := &TransactionType{}
-	err := ReadRequest(dType, rw)
+	defer func() {
+		limiter.release(peer)
+		counter.observe(dType.Type)
+	}()
+
+	err := ReadRequest(dType, conn)
 	if err != nil {
 		log.Errorf("read request type failed: %s", err)
 		return
 	}
+	if dType.Type == DataTypeHandshake {
+		if err = serverHandshake(session, conn); err != nil {
+			return
+		}
+		if session.Authenticated() {
+			keyPeer := fmt.Sprintf("key:%d", session.KeyID)
+			if !limiter.acquire(keyPeer) {
+				log.WithFields(log.Fields{"type": consts.ParameterExceeded, "key_id": session.KeyID}).Warning("too many requests from peer")
+				return
+			}
+			limiter.release(peer)
+			peer = keyPeer
+		}
+		if err = ReadRequest(dType, session.Conn); err != nil {
+			log.Errorf("read request type failed: %s", err)
+			return
+		}
+	}
+	if !session.Encrypted() && isEncryptionRequired() {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "host": host, "request_type": dType.Type}).Warning("connection is not encrypted")
+		return
+	}
+	rw := session.Conn
 
-	log.WithFields(log.Fields{"request_type": dType.Type}).Debug("tcpserver got request type")
+	log.WithFields(log.Fields{"request_type": dType.Type, "version": session.Version, "key_id": session.KeyID}).Debug("tcpserver got request type")
 	var response interface{}
 
 	switch dType.Type {
@@ -62,7 +117,7 @@ func HandleTCPRequest(rw io.ReadWriter) {
 		req := &DisRequest{}
 		err = ReadRequest(req, rw)
 		if err == nil {
-			err = Type1(req, rw)
+			err = Type1(session, req, rw)
 		}
 
 	case 2:
@@ -88,6 +143,26 @@ func HandleTCPRequest(rw io.ReadWriter) {
 
 	case 10:
 		response, err = Type10()
+
+	case 11:
+		err = Type11(rw, raw)
+
+	case 12:
+		err = Type12(rw)
+
+	case 13:
+		req := &PeersRequest{}
+		err = ReadRequest(req, rw)
+		if err == nil {
+			response, err = Type13(req, host)
+		}
+
+	case 14:
+		req := &VotesRequest{}
+		err = ReadRequest(req, rw)
+		if err == nil {
+			response, err = Type14(req)
+		}
 	}
 
 	if err != nil {
@@ -120,8 +195,12 @@ func TcpListener(laddr string) error {
 				time.Sleep(time.Second)
 			} else {
 				go func(conn net.Conn) {
-					HandleTCPRequest(conn)
+					connectionsTotal.Inc()
+					connections.Add(1)
+					host, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+					HandleTCPRequest(conn, host)
 					conn.Close()
+					connections.Add(-1)
 				}(conn)
 			}
 		}
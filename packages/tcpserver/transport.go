@@ -0,0 +1,132 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/crypto"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxFrameSize is the maximum size of the encrypted frame
+const maxFrameSize = 64 * 1024
+
+var errFrameSize = errors.New("wrong size of encrypted frame")
+
+// secureConn encrypts the data of the connection with AES-GCM.
+// Every frame is the size of 4 bytes and the sealed data, the nonce is the counter of frames.
+type secureConn struct {
+	rw         io.ReadWriter
+	reader     cipher.AEAD
+	writer     cipher.AEAD
+	readCount  uint64
+	writeCount uint64
+	buf        []byte
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newSecureConn creates the encrypted connection from the shared key of ephemeral keys.
+// The client and the server use the different keys for sending data.
+func newSecureConn(rw io.ReadWriter, shared []byte, isClient bool) (*secureConn, error) {
+	keys := make([][]byte, 2)
+	for i, direction := range []string{`client`, `server`} {
+		key, err := crypto.Hash(append(append([]byte{}, shared...), direction...))
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	if !isClient {
+		keys[0], keys[1] = keys[1], keys[0]
+	}
+	conn := &secureConn{rw: rw}
+	var err error
+	if conn.writer, err = newAEAD(keys[0]); err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("creating cipher")
+		return nil, err
+	}
+	if conn.reader, err = newAEAD(keys[1]); err != nil {
+		log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("creating cipher")
+		return nil, err
+	}
+	return conn, nil
+}
+
+func frameNonce(aead cipher.AEAD, count uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], count)
+	return nonce
+}
+
+func (c *secureConn) Write(data []byte) (int, error) {
+	var written int
+	for len(data) > 0 {
+		size := len(data)
+		if size > maxFrameSize {
+			size = maxFrameSize
+		}
+		sealed := c.writer.Seal(nil, frameNonce(c.writer, c.writeCount), data[:size], nil)
+		c.writeCount++
+		if _, err := c.rw.Write(append(converter.DecToBin(len(sealed), 4), sealed...)); err != nil {
+			return written, err
+		}
+		written += size
+		data = data[size:]
+	}
+	return written, nil
+}
+
+func (c *secureConn) Read(data []byte) (int, error) {
+	if len(c.buf) == 0 {
+		size, err := readUint(c.rw, 4)
+		if err != nil {
+			return 0, err
+		}
+		if size <= uint64(c.reader.Overhead()) || size > uint64(maxFrameSize+c.reader.Overhead()) {
+			log.WithFields(log.Fields{"type": consts.ProtocolError, "size": size}).Error("wrong size of encrypted frame")
+			return 0, errFrameSize
+		}
+		sealed, err := readBytes(c.rw, size)
+		if err != nil {
+			return 0, err
+		}
+		if c.buf, err = c.reader.Open(sealed[:0], frameNonce(c.reader, c.readCount), sealed, nil); err != nil {
+			log.WithFields(log.Fields{"type": consts.CryptoError, "error": err}).Error("decrypting frame")
+			return 0, fmt.Errorf("decrypting frame: %s", err)
+		}
+		c.readCount++
+	}
+	n := copy(data, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
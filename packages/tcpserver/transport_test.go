@@ -0,0 +1,48 @@
+package tcpserver
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSecureConn(t *testing.T) {
+	shared := bytes.Repeat([]byte{7}, 32)
+	wire := &bytes.Buffer{}
+	client, err := newSecureConn(wire, shared, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := newSecureConn(wire, shared, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte(`block data `), maxFrameSize/5)
+	if err = SendRequest(&GetBodyResponse{Data: data}, client); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(wire.Bytes(), []byte(`block data`)) {
+		t.Errorf("data is not encrypted")
+	}
+	resp := &GetBodyResponse{}
+	if err = ReadRequest(resp, server); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(resp.Data, data) {
+		t.Errorf("wrong decrypted data")
+	}
+
+	// the client can't read own frames because the directions have different keys
+	client.Write([]byte(`test`))
+	if _, err = io.ReadFull(client, make([]byte, 4)); err == nil {
+		t.Errorf("frame of other direction must not be decrypted")
+	}
+
+	client.Write([]byte(`test`))
+	sealed := wire.Bytes()
+	sealed[len(sealed)-1] ^= 1
+	if _, err = io.ReadFull(server, make([]byte, 4)); err == nil {
+		t.Errorf("changed frame must not be decrypted")
+	}
+}
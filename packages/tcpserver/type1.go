@@ -34,7 +34,9 @@ import (
 // get the list of transactions which belong to the sender from 'disseminator' daemon
 // do not load the blocks here because here could be the chain of blocks that are loaded for a long time
 // download the transactions here, because they are small and definitely will be downloaded in 60 sec
-func Type1(r *DisRequest, rw io.ReadWriter) error {
+// The sender must be authenticated by the handshake if tcp_auth_required system parameter is set
+// and the connection must be encrypted if the encryption is required by tcpEncryption flag
+func Type1(session *Session, r *DisRequest, rw io.ReadWriter) error {
 
 	buf := bytes.NewBuffer(r.Data)
 
@@ -56,6 +58,16 @@ func Type1(r *DisRequest, rw io.ReadWriter) error {
 	// full_node_id of the sender to know where to take a data when it will be downloaded by another daemon
 	fullNodeID := converter.BinToDec(buf.Next(8))
 	log.Debug("fullNodeID", fullNodeID)
+	if session.Authenticated() {
+		position, err := syspar.GetNodePositionByKeyID(session.KeyID)
+		if err != nil || position != fullNodeID {
+			log.WithFields(log.Fields{"type": consts.AccessDenied, "key_id": session.KeyID, "full_node_id": fullNodeID}).Warning("full node id does not match the session")
+			return errors.New("full node id does not match the session")
+		}
+	} else if syspar.IsTCPAuthRequired() {
+		log.WithFields(log.Fields{"type": consts.AccessDenied, "host": session.Host}).Warning("full node is not authenticated")
+		return errors.New("full node is not authenticated")
+	}
 
 	// get data type (0 - block and transactions, 1 - only transactions)
 	newDataType := converter.BinToDec(buf.Next(1))
@@ -0,0 +1,98 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"io"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/utils"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxRangeSize is the maximum byte budget of one batch of blocks
+	maxRangeSize = 32 * 1024 * 1024
+	// rangeChunk is the count of blocks which are read from the database at once
+	rangeChunk = 100
+	// rangeIdleTimeout is the maximum time of waiting for the next request while the client plays the received blocks
+	rangeIdleTimeout = 60 * time.Second
+)
+
+// readDeadliner is the connection which limits the time of reading
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// Type11 streams the bodies of the blocks by ranges over one connection.
+// The client sends BlocksRangeRequest and gets the blocks until the byte budget of the request is exhausted.
+// The batch is finished by the block with zero BlockID and the client requests the next batch
+// or finishes the session by the request with zero FromID.
+// The session is closed if the next request is not received within rangeIdleTimeout.
+// blocksCollection daemon sends this request
+func Type11(rw io.ReadWriter, conn io.ReadWriter) error {
+	deadliner, _ := conn.(readDeadliner)
+	for {
+		if deadliner != nil {
+			deadliner.SetReadDeadline(time.Now().Add(rangeIdleTimeout))
+		}
+		req := &BlocksRangeRequest{}
+		if err := ReadRequest(req, rw); err != nil {
+			return err
+		}
+		if req.FromID == 0 {
+			return nil
+		}
+		if err := sendBlocksRange(req, rw); err != nil {
+			return err
+		}
+	}
+}
+
+func sendBlocksRange(req *BlocksRangeRequest, w io.Writer) error {
+	budget := int64(req.MaxSize)
+	if budget <= 0 || budget > maxRangeSize {
+		budget = maxRangeSize
+	}
+	from := int64(req.FromID)
+	for from <= int64(req.ToID) && budget > 0 {
+		blocks, err := model.GetBlocksRange(from, int64(req.ToID), rangeChunk)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err, "block_id": from}).Error("getting range of blocks")
+			return utils.ErrInfo(err)
+		}
+		for _, block := range blocks {
+			if block.ID != from {
+				break
+			}
+			if err = SendRequest(&BlockBody{BlockID: uint32(block.ID), Data: block.Data}, w); err != nil {
+				return err
+			}
+			from++
+			if budget -= int64(len(block.Data)); budget <= 0 {
+				break
+			}
+		}
+		if len(blocks) < rangeChunk || (len(blocks) > 0 && from <= blocks[len(blocks)-1].ID) {
+			break
+		}
+	}
+	return SendRequest(&BlockBody{}, w)
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"io"
+	"os"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/parser"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshotChunk is the size of the chunks of the snapshot file
+const snapshotChunk = 1024 * 1024
+
+// Type12 sends the last snapshot of the state which has been made by the node.
+// The client sends SnapshotRequest and gets SnapshotResponse followed by the chunks of the file starting with Offset.
+// The host sends only the description of the last snapshot if the client requests another block,
+// new nodes send this request during the first load of the blockchain. The response contains the hash
+// which has been committed by the blockchain of the host
+func Type12(rw io.ReadWriter) error {
+	req := &SnapshotRequest{}
+	err := ReadRequest(req, rw)
+	if err != nil {
+		return err
+	}
+	resp := &SnapshotResponse{Hash: make([]byte, 32), CommittedHash: make([]byte, 32)}
+	committedID, committed, err := model.GetSnapshotHash(nil)
+	if err != nil {
+		return err
+	}
+	if len(committed) == len(resp.CommittedHash) {
+		resp.CommittedID = uint32(committedID)
+		copy(resp.CommittedHash, committed)
+	}
+	var file *os.File
+	if last := parser.LastSnapshot(); last != nil && len(last.Hash) == len(resp.Hash) {
+		if file, err = os.Open(last.Name); err == nil {
+			defer file.Close()
+			var info os.FileInfo
+			if info, err = file.Stat(); err != nil {
+				log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("getting info of snapshot file")
+				return err
+			}
+			resp.BlockID, resp.Size = uint32(last.BlockID), uint64(info.Size())
+			copy(resp.Hash, last.Hash)
+		} else if !os.IsNotExist(err) {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("opening snapshot file")
+			return err
+		}
+	}
+	if err = SendRequest(resp, rw); err != nil {
+		return err
+	}
+	if resp.BlockID == 0 || (req.BlockID != 0 && req.BlockID != resp.BlockID) {
+		return nil
+	}
+	if req.Offset < resp.Size {
+		if _, err = file.Seek(int64(req.Offset), io.SeekStart); err != nil {
+			log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("seeking snapshot file")
+			return err
+		}
+		buf := make([]byte, snapshotChunk)
+		for {
+			n, err := file.Read(buf)
+			if n > 0 {
+				if err := SendRequest(&SnapshotChunk{Data: buf[:n]}, rw); err != nil {
+					return err
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading snapshot file")
+				return err
+			}
+		}
+	}
+	return SendRequest(&SnapshotChunk{}, rw)
+}
@@ -0,0 +1,76 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/config/syspar"
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxPeersResponse is the maximum count of the addresses in the response
+const maxPeersResponse = 100
+
+// PeerAddress returns the address of the node with the TCP port
+func PeerAddress(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, consts.TCP_PORT)
+}
+
+// Type13 exchanges the addresses of the peers. The address of the client is added into the address book
+// if it belongs to the host of the connection. The response contains the full nodes and the best peers.
+// PeerDiscovery daemon sends this request
+func Type13(req *PeersRequest, host string) (*PeersResponse, error) {
+	if len(req.Host) > 0 {
+		addr := PeerAddress(string(req.Host))
+		if hostName(addr) == host {
+			if err := model.AddPeers([]string{addr}); err != nil {
+				log.WithFields(log.Fields{"type": consts.DBError, "error": err, "host": addr}).Error("adding peer")
+				return nil, err
+			}
+		}
+	}
+	peers, err := model.GetPeers(maxPeersResponse)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting peers")
+		return nil, err
+	}
+	list := make([]string, 0, maxPeersResponse)
+	added := make(map[string]bool)
+	add := func(addr string) {
+		if len(list) < maxPeersResponse && !added[addr] {
+			list = append(list, addr)
+			added[addr] = true
+		}
+	}
+	for _, item := range syspar.GetHosts() {
+		add(PeerAddress(item))
+	}
+	for _, peer := range peers {
+		if peer.Score >= 0 {
+			add(peer.Host)
+		}
+	}
+	return &PeersResponse{Data: []byte(strings.Join(list, `,`))}, nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package tcpserver
+
+import (
+	"encoding/json"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/finality"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxVotesResponse is the maximum count of the votes in the response
+const maxVotesResponse = 1000
+
+// Type14 exchanges the votes of the full nodes for the blocks. The votes of the client are verified and saved,
+// the response contains the votes of the host for the blocks starting with FromID.
+// Finality daemon sends this request
+func Type14(req *VotesRequest) (*VotesResponse, error) {
+	if len(req.Data) > 0 {
+		votes := make([]model.Vote, 0)
+		if err := json.Unmarshal(req.Data, &votes); err != nil {
+			log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling votes")
+			return nil, err
+		}
+		if err := finality.AddVotes(votes); err != nil {
+			return nil, err
+		}
+	}
+	votes, err := model.GetVotes(int64(req.FromID), maxVotesResponse)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting votes")
+		return nil, err
+	}
+	data, err := json.Marshal(votes)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling votes")
+		return nil, err
+	}
+	return &VotesResponse{Data: data}, nil
+}
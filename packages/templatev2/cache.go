@@ -0,0 +1,126 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package templatev2
+
+import (
+	"sync"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/model"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	parseCacheSize = 2048
+	queryCacheSize = 1024
+)
+
+// tplCall is the parsed part of the template, it is either the text or the call of the function
+type tplCall struct {
+	text     string
+	fn       *tplFunc
+	params   *[]string
+	tailpars *[]*[]string
+}
+
+// callCache keeps the parsed templates. When the current generation is full it becomes the previous one,
+// so the templates which are used often stay in the cache.
+type callCache struct {
+	sync.RWMutex
+	current  map[string][]tplCall
+	previous map[string][]tplCall
+}
+
+var parseCache = &callCache{current: make(map[string][]tplCall)}
+
+func (c *callCache) get(input string) ([]tplCall, bool) {
+	c.RLock()
+	calls, ok := c.current[input]
+	if !ok {
+		calls, ok = c.previous[input]
+	}
+	c.RUnlock()
+	if ok {
+		c.set(input, calls)
+	}
+	return calls, ok
+}
+
+func (c *callCache) set(input string, calls []tplCall) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.current[input]; ok {
+		return
+	}
+	if len(c.current) >= parseCacheSize {
+		c.previous = c.current
+		c.current = make(map[string][]tplCall)
+	}
+	c.current[input] = calls
+}
+
+// resultCache keeps the results of the queries of DBFind with Cached tail until the next block
+type resultCache struct {
+	sync.Mutex
+	blockID int64
+	results map[string]interface{}
+}
+
+var queryCache = &resultCache{results: make(map[string]interface{})}
+
+// blockID returns the id of the last block, it is got once for the rendering of the template
+func blockID(vars *map[string]string) (int64, error) {
+	if id, ok := (*vars)[`_block_id`]; ok {
+		return converter.StrToInt64(id), nil
+	}
+	infoBlock := &model.InfoBlock{}
+	if _, err := infoBlock.Get(); err != nil {
+		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting info block")
+		return 0, err
+	}
+	(*vars)[`_block_id`] = converter.Int64ToStr(infoBlock.BlockID)
+	return infoBlock.BlockID, nil
+}
+
+// cachedQuery returns the cached result of the query for the current block or gets it with the function
+func cachedQuery(vars *map[string]string, key string, get func() (interface{}, error)) (interface{}, error) {
+	block, err := blockID(vars)
+	if err != nil {
+		return nil, err
+	}
+	queryCache.Lock()
+	if queryCache.blockID != block {
+		queryCache.blockID = block
+		queryCache.results = make(map[string]interface{})
+	}
+	result, ok := queryCache.results[key]
+	queryCache.Unlock()
+	if ok {
+		return result, nil
+	}
+	if result, err = get(); err != nil {
+		return nil, err
+	}
+	queryCache.Lock()
+	if queryCache.blockID == block && len(queryCache.results) < queryCacheSize {
+		queryCache.results[key] = result
+	}
+	queryCache.Unlock()
+	return result, nil
+}
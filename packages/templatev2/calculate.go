@@ -0,0 +1,138 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package templatev2
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+const maxCalcLength = 256
+
+type calcParser struct {
+	input []rune
+	pos   int
+}
+
+// calculate evaluates the arithmetic expression with decimal numbers, + - * / operators and parentheses
+func calculate(exp string) (decimal.Decimal, error) {
+	if len(exp) > maxCalcLength {
+		return decimal.Zero, fmt.Errorf(`expression is too long`)
+	}
+	p := &calcParser{input: []rune(exp)}
+	ret, err := p.expression(0)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if p.skipSpaces(); p.pos < len(p.input) {
+		return decimal.Zero, fmt.Errorf(`unexpected %c at %d`, p.input[p.pos], p.pos)
+	}
+	return ret, nil
+}
+
+func (p *calcParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// next returns the next operator from the list or zero
+func (p *calcParser) next(ops string) rune {
+	p.skipSpaces()
+	if p.pos < len(p.input) && strings.ContainsRune(ops, p.input[p.pos]) {
+		p.pos++
+		return p.input[p.pos-1]
+	}
+	return 0
+}
+
+func (p *calcParser) expression(level int) (decimal.Decimal, error) {
+	if level > 32 {
+		return decimal.Zero, fmt.Errorf(`expression is too complex`)
+	}
+	ret, err := p.term(level)
+	if err != nil {
+		return ret, err
+	}
+	for op := p.next(`+-`); op != 0; op = p.next(`+-`) {
+		right, err := p.term(level)
+		if err != nil {
+			return ret, err
+		}
+		if op == '+' {
+			ret = ret.Add(right)
+		} else {
+			ret = ret.Sub(right)
+		}
+	}
+	return ret, nil
+}
+
+func (p *calcParser) term(level int) (decimal.Decimal, error) {
+	ret, err := p.factor(level)
+	if err != nil {
+		return ret, err
+	}
+	for op := p.next(`*/`); op != 0; op = p.next(`*/`) {
+		right, err := p.factor(level)
+		if err != nil {
+			return ret, err
+		}
+		if op == '*' {
+			ret = ret.Mul(right)
+		} else {
+			if right.Cmp(decimal.Zero) == 0 {
+				return ret, fmt.Errorf(`division by zero`)
+			}
+			ret = ret.Div(right)
+		}
+	}
+	return ret, nil
+}
+
+func (p *calcParser) factor(level int) (decimal.Decimal, error) {
+	switch p.next(`-+(`) {
+	case '-':
+		ret, err := p.factor(level + 1)
+		return decimal.Zero.Sub(ret), err
+	case '+':
+		return p.factor(level + 1)
+	case '(':
+		ret, err := p.expression(level + 1)
+		if err != nil {
+			return ret, err
+		}
+		if p.next(`)`) == 0 {
+			return ret, fmt.Errorf(`there is not pair for (`)
+		}
+		return ret, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos < len(p.input) {
+			return decimal.Zero, fmt.Errorf(`unexpected %c at %d`, p.input[p.pos], p.pos)
+		}
+		return decimal.Zero, fmt.Errorf(`unexpected end of expression`)
+	}
+	return decimal.NewFromString(string(p.input[start:p.pos]))
+}
@@ -0,0 +1,202 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package templatev2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AplaProject/go-apla/packages/converter"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+)
+
+// contractField is the field of the contract which is attached to the form tree so front-ends
+// can validate inputs before calling the contract
+type contractField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Tags     string `json:"tags,omitempty"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// required returns true if the value of the field must be sent by the form
+func (field *contractField) required() bool {
+	return !field.Optional && !strings.Contains(field.Tags, `signature`)
+}
+
+// formInputs are the tags which send the values to the contract
+var formInputs = map[string]bool{`input`: true, `select`: true, `imageinput`: true}
+
+func addWarning(n *node, format string, args ...interface{}) {
+	warnings, _ := n.Attr[`warnings`].([]string)
+	n.Attr[`warnings`] = append(warnings, fmt.Sprintf(format, args...))
+}
+
+// contractFields returns the fields of the contract of the ecosystem
+func contractFields(name string, state int64) ([]contractField, error) {
+	contract := smart.GetContract(name, int32(state))
+	if contract == nil {
+		return nil, fmt.Errorf(`unknown contract %s`, name)
+	}
+	fields := make([]contractField, 0)
+	if info := contract.Block.Info.(*script.ContractInfo); info.Tx != nil {
+		for _, fitem := range *info.Tx {
+			fields = append(fields, contractField{Name: fitem.Name, Type: fitem.Type.String(), Tags: fitem.Tags,
+				Optional: strings.Contains(fitem.Tags, `optional`)})
+		}
+	}
+	return fields, nil
+}
+
+// buttonFields attaches the fields of the contract to the button and checks its parameters
+func buttonFields(par parFunc) {
+	name, _ := par.Node.Attr[`contract`].(string)
+	if len(name) == 0 || (*par.Vars)[`_full`] == `1` {
+		return
+	}
+	fields, err := contractFields(name, converter.StrToInt64((*par.Vars)[`ecosystem_id`]))
+	if err != nil {
+		addWarning(par.Node, `%v`, err)
+		return
+	}
+	par.Node.Attr[`fields`] = fields
+	params, _ := par.Node.Attr[`params`].(map[string]interface{})
+	for _, key := range sortedKeys(params) {
+		if findField(fields, key) == nil {
+			addWarning(par.Node, `parameter %s is not the field of contract %s`, key, name)
+		}
+	}
+}
+
+func findField(fields []contractField, name string) *contractField {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// walkForm collects the inputs and the buttons with contracts of the form
+func walkForm(n *node, inputs *[]*node, buttons *[]*node) {
+	for _, child := range n.Children {
+		if formInputs[child.Tag] {
+			*inputs = append(*inputs, child)
+		} else if child.Tag == `button` && child.Attr[`fields`] != nil {
+			*buttons = append(*buttons, child)
+		}
+		walkForm(child, inputs, buttons)
+	}
+}
+
+// valBindings returns the fields of the contract which get the values of the inputs by Val parameters
+// of the button, the key is the name of the input
+func valBindings(button *node) map[string]string {
+	params, _ := button.Attr[`params`].(map[string]interface{})
+	fields := button.Attr[`fields`].([]contractField)
+	bindings := make(map[string]string)
+	for _, key := range sortedKeys(params) {
+		param, _ := params[key].(map[string]interface{})
+		if param[`type`] != `Val` || findField(fields, key) == nil {
+			continue
+		}
+		if list, _ := param[`params`].([]string); len(list) > 0 && len(list[0]) > 0 {
+			if _, ok := bindings[list[0]]; !ok {
+				bindings[list[0]] = key
+			}
+		}
+	}
+	return bindings
+}
+
+// inputField returns the field of the contract which gets the value of the input. The field which is bound
+// to the input by Val parameter has priority over the field with the same name
+func inputField(name string, buttons []*node, bindings []map[string]string) *contractField {
+	for i, button := range buttons {
+		if key, ok := bindings[i][name]; ok {
+			return findField(button.Attr[`fields`].([]contractField), key)
+		}
+	}
+	for _, button := range buttons {
+		if field := findField(button.Attr[`fields`].([]contractField), name); field != nil {
+			return field
+		}
+	}
+	return nil
+}
+
+// validateForm attaches the fields of the contracts to the inputs of the form and to their Validate
+// and adds warnings for the inputs which are not the fields of the contracts and for the required fields
+// without inputs
+func validateForm(form *node) {
+	var inputs, buttons []*node
+	walkForm(form, &inputs, &buttons)
+	if len(buttons) == 0 {
+		return
+	}
+	bindings := make([]map[string]string, len(buttons))
+	for i, button := range buttons {
+		bindings[i] = valBindings(button)
+	}
+	// bound contains the fields which get the values of the inputs
+	bound := make(map[string]bool)
+	for _, input := range inputs {
+		name, _ := input.Attr[`name`].(string)
+		if len(name) == 0 {
+			continue
+		}
+		field := inputField(name, buttons, bindings)
+		if field == nil {
+			addWarning(form, `input %s is not the field of the contract`, name)
+			continue
+		}
+		bound[field.Name] = true
+		input.Attr[`field`] = field
+		if validate, ok := input.Attr[`validate`].(map[string]interface{}); ok {
+			validate[`field`] = field
+		}
+	}
+	for _, button := range buttons {
+		params, _ := button.Attr[`params`].(map[string]interface{})
+		for _, field := range button.Attr[`fields`].([]contractField) {
+			if _, ok := params[field.Name]; ok || bound[field.Name] || !field.required() {
+				continue
+			}
+			addWarning(button, `field %s of contract %s has no input`, field.Name, button.Attr[`contract`])
+		}
+	}
+}
+
+// formTag processes the form and validates its inputs with the contracts of the buttons
+func formTag(par parFunc) string {
+	defaultTailTag(par)
+	if (*par.Vars)[`_full`] != `1` {
+		validateForm(par.Node)
+	}
+	return ``
+}
@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"html"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -29,26 +30,34 @@ import (
 	"github.com/AplaProject/go-apla/packages/converter"
 	"github.com/AplaProject/go-apla/packages/language"
 	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/smart"
 
+	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
 	funcs = map[string]tplFunc{
-		`Address`:     {addressTag, defaultTag, `address`, `Wallet`},
-		`CmpTime`:     {cmpTimeTag, defaultTag, `cmptime`, `Time1,Time2`},
-		`DateTime`:    {dateTimeTag, defaultTag, `datetime`, `DateTime,Format`},
-		`EcosysParam`: {ecosysparTag, defaultTag, `ecosyspar`, `Name,Index,Source`},
-		`Em`:          {defaultTag, defaultTag, `em`, `Body,Class`},
-		`GetVar`:      {getvarTag, defaultTag, `getvar`, `Name`},
-		`ImageInput`:  {defaultTag, defaultTag, `imageinput`, `Name,Width,Ratio`},
-		`InputErr`:    {defaultTag, defaultTag, `inputerr`, `*`},
-		`LangRes`:     {langresTag, defaultTag, `langres`, `Name,Lang`},
-		`MenuGroup`:   {defaultTag, defaultTag, `menugroup`, `Title,Body,Icon`},
-		`MenuItem`:    {defaultTag, defaultTag, `menuitem`, `Title,Page,PageParams,Icon`},
-		`Now`:         {nowTag, defaultTag, `now`, `Format,Interval`},
-		`SetVar`:      {setvarTag, defaultTag, `setvar`, `Name,Value`},
-		`Strong`:      {defaultTag, defaultTag, `strong`, `Body,Class`},
+		`Address`:      {addressTag, defaultTag, `address`, `Wallet`},
+		`Calculate`:    {calculateTag, defaultTag, `calculate`, `Exp,Type,Prec`},
+		`CmpTime`:      {cmpTimeTag, defaultTag, `cmptime`, `Time1,Time2`},
+		`DateTime`:     {dateTimeTag, defaultTag, `datetime`, `DateTime,Format`},
+		`EcosysParam`:  {ecosysparTag, defaultTag, `ecosyspar`, `Name,Index,Source`},
+		`Em`:           {defaultTag, defaultTag, `em`, `Body,Class`},
+		`GetVar`:       {getvarTag, defaultTag, `getvar`, `Name`},
+		`ImageInput`:   {defaultTag, defaultTag, `imageinput`, `Name,Width,Ratio`},
+		`InputErr`:     {defaultTag, defaultTag, `inputerr`, `*`},
+		`JsonToSource`: {jsontosourceTag, defaultTag, `jsontosource`, `Source,Data`},
+		`LangRes`:      {langresTag, defaultTag, `langres`, `Name,Lang`},
+		`MenuGroup`:    {defaultTag, defaultTag, `menugroup`, `Title,Body,Icon`},
+		`MenuItem`:     {defaultTag, defaultTag, `menuitem`, `Title,Page,PageParams,Icon`},
+		`Money`:        {moneyTag, defaultTag, `money`, `Exp,Digit`},
+		`Now`:          {nowTag, defaultTag, `now`, `Format,Interval`},
+		`Range`:        {rangeTag, defaultTag, `range`, `Source,From,To,Step`},
+		`Replace`:      {replaceTag, defaultTag, `replace`, `Source,Search,Replace`},
+		`SetVar`:       {setvarTag, defaultTag, `setvar`, `Name,Value`},
+		`Strong`:       {defaultTag, defaultTag, `strong`, `Body,Class`},
+		`Substr`:       {substrTag, defaultTag, `substr`, `Source,Offset,Length`},
 	}
 	tails = map[string]forTails{
 		`button`: {map[string]tailInfo{
@@ -87,6 +96,11 @@ var (
 			`Order`:     {tplFunc{tailTag, defaultTailFull, `order`, `Order`}, false},
 			`Limit`:     {tplFunc{tailTag, defaultTailFull, `limit`, `Limit`}, false},
 			`Offset`:    {tplFunc{tailTag, defaultTailFull, `offset`, `Offset`}, false},
+			`Count`:     {tplFunc{tailGroupTag, defaultTailFull, `count`, `Name`}, false},
+			`Cached`:    {tplFunc{tailGroupTag, defaultTailFull, `cached`, `Cached`}, false},
+			`Paging`:    {tplFunc{tailGroupTag, defaultTailFull, `paging`, `Param`}, false},
+			`Sort`:      {tplFunc{tailGroupTag, defaultTailFull, `sort`, `Param,Columns`}, false},
+			`Search`:    {tplFunc{tailGroupTag, defaultTailFull, `search`, `Param,Columns`}, false},
 			`Ecosystem`: {tplFunc{tailTag, defaultTailFull, `ecosystem`, `Ecosystem`}, false},
 			`Custom`:    {tplFunc{customTag, defaultTailFull, `custom`, `Column,Body`}, false},
 			`Vars`:      {tplFunc{tailTag, defaultTailFull, `vars`, `Prefix`}, false},
@@ -111,7 +125,8 @@ var (
 func init() {
 	funcs[`Button`] = tplFunc{buttonTag, buttonTag, `button`, `Body,Page,Class,Contract,Params,PageParams`}
 	funcs[`Div`] = tplFunc{defaultTailTag, defaultTailTag, `div`, `Class,Body`}
-	funcs[`Form`] = tplFunc{defaultTailTag, defaultTailTag, `form`, `Class,Body`}
+	funcs[`ForList`] = tplFunc{forlistTag, defaultTag, `forlist`, `Source,Body`}
+	funcs[`Form`] = tplFunc{formTag, defaultTailTag, `form`, `Class,Body`}
 	funcs[`If`] = tplFunc{ifTag, ifFull, `if`, `Condition,Body`}
 	funcs[`Image`] = tplFunc{defaultTailTag, defaultTailTag, `image`, `Src,Alt,Class`}
 	funcs[`Include`] = tplFunc{includeTag, defaultTag, `include`, `Name`}
@@ -166,6 +181,7 @@ func ecosysparTag(par parFunc) string {
 			item, _ = language.LangText(item, state, (*par.Vars)[`accept_lang`])
 			data = append(data, []string{converter.IntToStr(key + 1), item})
 		}
+		setSource(par.Vars, (*par.Pars)[`Source`], cols, data)
 		node := node{Tag: `data`, Attr: map[string]interface{}{`columns`: &cols, `types`: &types,
 			`data`: &data, `source`: (*par.Pars)[`Source`]}}
 		par.Owner.Children = append(par.Owner.Children, &node)
@@ -234,7 +250,7 @@ func nowTag(par parFunc) string {
 func andTag(par parFunc) string {
 	count := len(*par.Pars)
 	for i := 0; i < count; i++ {
-		if !ifValue((*par.Pars)[strconv.Itoa(i)], par.Vars) {
+		if !ifValue((*par.Pars)[strconv.Itoa(i)], par.Vars, par.Profile) {
 			return `0`
 		}
 	}
@@ -244,7 +260,7 @@ func andTag(par parFunc) string {
 func orTag(par parFunc) string {
 	count := len(*par.Pars)
 	for i := 0; i < count; i++ {
-		if ifValue((*par.Pars)[strconv.Itoa(i)], par.Vars) {
+		if ifValue((*par.Pars)[strconv.Itoa(i)], par.Vars, par.Profile) {
 			return `1`
 		}
 	}
@@ -314,6 +330,7 @@ func dataTag(par parFunc) string {
 	setAllAttr(par)
 	delete(par.Node.Attr, `customs`)
 	delete(par.Node.Attr, `custombody`)
+	setSource(par.Vars, (*par.Pars)[`Source`], cols, data)
 	par.Node.Attr[`columns`] = &cols
 	par.Node.Attr[`types`] = &types
 	par.Node.Attr[`data`] = &data
@@ -323,38 +340,59 @@ func dataTag(par parFunc) string {
 
 func dbfindTag(par parFunc) string {
 	var (
-		fields string
-		state  int64
+		columns []string
+		state   int64
+		err     error
 	)
 	if len((*par.Pars)[`Name`]) == 0 {
 		return ``
 	}
 	defaultTail(par, `dbfind`)
 	prefix := ``
-	where := ``
+	filter := model.Filter{}
 	order := ``
 	limit := 25
+	offset := 0
 	if par.Node.Attr[`columns`] != nil {
-		fields = converter.Escape(par.Node.Attr[`columns`].(string))
-	}
-	if len(fields) == 0 {
-		fields = `*`
+		columns = strings.Split(par.Node.Attr[`columns`].(string), `,`)
 	}
 	if par.Node.Attr[`where`] != nil {
-		where = ` where ` + converter.Escape(par.Node.Attr[`where`].(string))
+		if filter, err = model.ParseFilter(par.Node.Attr[`where`].(string)); err != nil {
+			return err.Error()
+		}
 	}
 	if par.Node.Attr[`whereid`] != nil {
-		where = fmt.Sprintf(` where id='%d'`, converter.StrToInt64(par.Node.Attr[`whereid`].(string)))
+		filter = model.Filter{`id`: converter.StrToInt64(par.Node.Attr[`whereid`].(string))}
 	}
 	if par.Node.Attr[`order`] != nil {
-		order = ` order by ` + converter.EscapeName(par.Node.Attr[`order`].(string))
+		order = par.Node.Attr[`order`].(string)
 	}
 	if par.Node.Attr[`limit`] != nil {
 		limit = converter.StrToInt(par.Node.Attr[`limit`].(string))
 	}
+	if limit < 1 {
+		limit = 1
+	}
 	if limit > 250 {
 		limit = 250
 	}
+	if par.Node.Attr[`offset`] != nil {
+		offset = converter.StrToInt(par.Node.Attr[`offset`].(string))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	sorting := pageSort(par)
+	if sorting != nil && len(sorting[`column`].(string)) > 0 {
+		order = sorting[`column`].(string)
+		if sorting[`desc`].(bool) {
+			order += ` desc`
+		}
+	}
+	search := pageSearch(par)
+	if search != nil && len(search[`value`].(string)) > 0 {
+		filter = searchFilter(filter, search[`columns`].([]string), search[`value`].(string))
+	}
 	if par.Node.Attr[`prefix`] != nil {
 		prefix = par.Node.Attr[`prefix`].(string)
 		limit = 1
@@ -364,10 +402,32 @@ func dbfindTag(par parFunc) string {
 	} else {
 		state = converter.StrToInt64((*par.Vars)[`ecosystem_id`])
 	}
-	tblname := fmt.Sprintf(`"%d_%s"`, state, strings.Trim(converter.EscapeName((*par.Pars)[`Name`]), `"`))
-	list, err := model.GetAll(`select `+fields+` from `+tblname+where+order, limit)
+	query, err := model.NewReadQuery(state, (*par.Pars)[`Name`], columns, filter, order, func(cond string) (bool, error) {
+		return smart.EvalIf(cond, uint32(state), &map[string]interface{}{
+			`ecosystem_id`: converter.StrToInt64((*par.Vars)[`ecosystem_id`]),
+			`key_id`:       converter.StrToInt64((*par.Vars)[`key_id`])})
+	})
+	if err != nil {
+		return err.Error()
+	}
+	paging := pageParams(par)
+	if paging != nil || par.Node.Attr[`count`] != nil {
+		count, err := dbfindCount(par, query)
+		if err != nil {
+			return err.Error()
+		}
+		if paging != nil {
+			offset = setPaging(paging, count, limit)
+		}
+		if par.Node.Attr[`count`] != nil {
+			if name := par.Node.Attr[`count`].(map[string]interface{})[`name`]; name != nil {
+				(*par.Vars)[name.(string)] = converter.Int64ToStr(count)
+			}
+			par.Node.Attr[`count`] = count
+		}
+	}
+	list, err := dbfindRows(par, query.Select(int64(offset), int64(limit)), limit, query.Args)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all from db")
 		return err.Error()
 	}
 	/*	list := []map[string]string{{"id": "1", "amount": "200"}, {"id": "2", "amount": "300"}}
@@ -379,7 +439,7 @@ func dbfindTag(par parFunc) string {
 	defcol := 0
 	for _, item := range list {
 		if lencol == 0 {
-			for key := range item {
+			for _, key := range query.Columns {
 				cols = append(cols, key)
 				types = append(types, `text`)
 			}
@@ -406,7 +466,7 @@ func dbfindTag(par parFunc) string {
 			} else {
 				body := replace(par.Node.Attr[`custombody`].([]string)[i-defcol], 0, &item)
 				root := node{}
-				process(body, &root, par.Vars)
+				process(body, &root, par.Vars, par.Profile)
 				out, err := json.Marshal(root.Children)
 				if err == nil {
 					log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling root children to JSON")
@@ -424,6 +484,20 @@ func dbfindTag(par parFunc) string {
 	delete(par.Node.Attr, `customs`)
 	delete(par.Node.Attr, `custombody`)
 	delete(par.Node.Attr, `prefix`)
+	delete(par.Node.Attr, `cached`)
+	if paging != nil {
+		par.Node.Attr[`paging`] = paging
+	}
+	if sorting != nil {
+		par.Node.Attr[`sort`] = sorting
+		if source, ok := par.Node.Attr[`source`].(string); ok {
+			(*par.Vars)[`_sort_`+source] = strings.Join(sorting[`columns`].([]string), `,`)
+		}
+	}
+	if search != nil {
+		par.Node.Attr[`search`] = search
+	}
+	setSource(par.Vars, (*par.Pars)[`Source`], cols, data)
 	par.Node.Attr[`columns`] = &cols
 	par.Node.Attr[`types`] = &types
 	par.Node.Attr[`data`] = &data
@@ -431,6 +505,48 @@ func dbfindTag(par parFunc) string {
 	return ``
 }
 
+// dbfindQuery runs the query of DBFind. The result is cached until the next block if DBFind has Cached tail
+func dbfindQuery(par parFunc, sql string, args []interface{}, get func() (interface{}, error)) (interface{}, error) {
+	defer par.Profile.addQuery(sql, time.Now())
+	if par.Node.Attr[`cached`] == nil {
+		return get()
+	}
+	key, err := json.Marshal(append([]interface{}{sql}, args...))
+	if err != nil {
+		return get()
+	}
+	return cachedQuery(par.Vars, string(key), get)
+}
+
+func dbfindRows(par parFunc, sql string, limit int, args []interface{}) ([]map[string]string, error) {
+	ret, err := dbfindQuery(par, sql, args, func() (interface{}, error) {
+		list, err := model.GetAll(sql, limit, args...)
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting all from db")
+		}
+		return list, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ret.([]map[string]string), nil
+}
+
+func dbfindCount(par parFunc, query *model.ReadQuery) (int64, error) {
+	sql := `select count(*)` + query.From()
+	ret, err := dbfindQuery(par, sql, query.Args, func() (interface{}, error) {
+		count, err := model.Single(sql, query.Args...).Int64()
+		if err != nil {
+			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting count of rows")
+		}
+		return count, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return ret.(int64), nil
+}
+
 func customTag(par parFunc) string {
 	setAllAttr(par)
 	if par.Owner.Attr[`customs`] == nil {
@@ -451,9 +567,129 @@ func tailTag(par parFunc) string {
 	return ``
 }
 
+// tailGroupTag keeps the parameters of the tail as the separate attribute of the owner
+func tailGroupTag(par parFunc) string {
+	setAllAttr(par)
+	par.Owner.Attr[par.Node.Tag] = par.Node.Attr
+	return ``
+}
+
+// tailParam returns the parameter of the tail attribute and the name of the page parameter
+// which is bound to it
+func tailParam(par parFunc, tail, defParam string) (map[string]interface{}, string) {
+	attr, ok := par.Node.Attr[tail].(map[string]interface{})
+	if !ok {
+		return nil, ``
+	}
+	param, _ := attr[`param`].(string)
+	if len(param) == 0 {
+		param = defParam
+	}
+	return attr, param
+}
+
+func tailColumns(attr map[string]interface{}) []string {
+	columns := make([]string, 0)
+	if list, ok := attr[`columns`].(string); ok {
+		for _, column := range strings.Split(list, `,`) {
+			if column = strings.TrimSpace(column); len(column) > 0 {
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns
+}
+
+// pageParams returns the paging of DBFind. The number of the page is taken from the page parameter
+func pageParams(par parFunc) map[string]interface{} {
+	attr, param := tailParam(par, `paging`, `page`)
+	if attr == nil {
+		return nil
+	}
+	page := converter.StrToInt64((*par.Vars)[param])
+	if page < 1 {
+		page = 1
+	}
+	return map[string]interface{}{`param`: param, `page`: page}
+}
+
+// setPaging fills the paging with the total count of rows and returns the offset of the page.
+// The limit must be from 1 to 250
+func setPaging(paging map[string]interface{}, count int64, limit int) int {
+	pages := (count + int64(limit) - 1) / int64(limit)
+	page := paging[`page`].(int64)
+	if pages == 0 {
+		page = 1
+	} else if page > pages {
+		page = pages
+	}
+	paging[`page`] = page
+	paging[`pages`] = pages
+	paging[`limit`] = limit
+	paging[`count`] = count
+	return int(page-1) * limit
+}
+
+// pageSort returns the sorting of DBFind. The page parameter contains the name of the column
+// which is prefixed with minus for the descending order. Only listed columns can be sorted.
+func pageSort(par parFunc) map[string]interface{} {
+	attr, param := tailParam(par, `sort`, `sort`)
+	if attr == nil {
+		return nil
+	}
+	columns := tailColumns(attr)
+	column := strings.TrimSpace((*par.Vars)[param])
+	desc := strings.HasPrefix(column, `-`)
+	column = strings.TrimPrefix(column, `-`)
+	allowed := false
+	for _, item := range columns {
+		if item == column {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		column = ``
+		desc = false
+	}
+	return map[string]interface{}{`param`: param, `columns`: columns, `column`: column, `desc`: desc}
+}
+
+// pageSearch returns the search of DBFind. The page parameter contains the searched text
+func pageSearch(par parFunc) map[string]interface{} {
+	attr, param := tailParam(par, `search`, `search`)
+	if attr == nil {
+		return nil
+	}
+	return map[string]interface{}{`param`: param, `columns`: tailColumns(attr),
+		`value`: strings.TrimSpace((*par.Vars)[param])}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// searchFilter appends the case-insensitive search of the text in any of the columns to the filter
+func searchFilter(filter model.Filter, columns []string, value string) model.Filter {
+	if len(columns) == 0 {
+		return filter
+	}
+	like := `%` + likeEscaper.Replace(value) + `%`
+	conds := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		conds = append(conds, map[string]interface{}{column: map[string]interface{}{`$ilike`: like}})
+	}
+	search := map[string]interface{}{`$or`: conds}
+	if len(filter) == 0 {
+		return search
+	}
+	return model.Filter{`$and`: []interface{}{map[string]interface{}(filter), search}}
+}
+
 func includeTag(par parFunc) string {
 	if len((*par.Pars)[`Name`]) >= 0 && len((*par.Vars)[`_include`]) < 5 {
-		pattern, err := model.Single(`select value from "`+(*par.Vars)[`ecosystem_id`]+`_blocks" where name=?`, (*par.Pars)[`Name`]).String()
+		query := `select value from "` + (*par.Vars)[`ecosystem_id`] + `_blocks" where name=?`
+		start := time.Now()
+		pattern, err := model.Single(query, (*par.Pars)[`Name`]).String()
+		par.Profile.addQuery(query, start)
 		if err != nil {
 			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting block by name")
 			return err.Error()
@@ -461,7 +697,7 @@ func includeTag(par parFunc) string {
 		if len(pattern) > 0 {
 			root := node{}
 			(*par.Vars)[`_include`] += `1`
-			process(pattern, &root, par.Vars)
+			process(pattern, &root, par.Vars, par.Profile)
 			(*par.Vars)[`_include`] = (*par.Vars)[`_include`][:len((*par.Vars)[`_include`])-1]
 			for _, item := range root.Children {
 				par.Owner.Children = append(par.Owner.Children, item)
@@ -498,6 +734,15 @@ func tableTag(par parFunc) string {
 				imap = append(imap, map[string]string{`Title`: strings.TrimSpace(v[:off]), `Name`: strings.TrimSpace(v[off+1:])})
 			}
 		}
+		if sortable, ok := (*par.Vars)[`_sort_`+(*par.Pars)[`Source`]]; ok && len(sortable) > 0 {
+			for _, column := range strings.Split(sortable, `,`) {
+				for _, item := range imap {
+					if item[`Name`] == column {
+						item[`Sortable`] = `true`
+					}
+				}
+			}
+		}
 		if len(imap) > 0 {
 			par.Node.Attr[`columns`] = imap
 		}
@@ -523,7 +768,7 @@ func defaultTail(par parFunc, tag string) {
 			name := (*v)[len(*v)-1]
 			curFunc := tails[tag].Tails[name].tplFunc
 			pars := (*v)[:len(*v)-1]
-			callFunc(&curFunc, par.Node, par.Vars, &pars, nil)
+			callFunc(&curFunc, par.Node, par.Vars, &pars, nil, par.Profile)
 		}
 	}
 }
@@ -537,11 +782,12 @@ func defaultTailTag(par parFunc) string {
 func buttonTag(par parFunc) string {
 	defaultTag(par)
 	defaultTail(par, `button`)
+	buttonFields(par)
 	return ``
 }
 
 func ifTag(par parFunc) string {
-	cond := ifValue((*par.Pars)[`Condition`], par.Vars)
+	cond := ifValue((*par.Pars)[`Condition`], par.Vars, par.Profile)
 	if cond {
 		for _, item := range par.Node.Children {
 			par.Owner.Children = append(par.Owner.Children, item)
@@ -552,7 +798,7 @@ func ifTag(par parFunc) string {
 			name := (*v)[len(*v)-1]
 			curFunc := tails[`if`].Tails[name].tplFunc
 			pars := (*v)[:len(*v)-1]
-			callFunc(&curFunc, par.Owner, par.Vars, &pars, nil)
+			callFunc(&curFunc, par.Owner, par.Vars, &pars, nil, par.Profile)
 			if (*par.Vars)[`_cond`] == `1` {
 				(*par.Vars)[`_cond`] = `0`
 				break
@@ -570,14 +816,14 @@ func ifFull(par parFunc) string {
 			name := (*v)[len(*v)-1]
 			curFunc := tails[`if`].Tails[name].tplFunc
 			pars := (*v)[:len(*v)-1]
-			callFunc(&curFunc, par.Node, par.Vars, &pars, nil)
+			callFunc(&curFunc, par.Node, par.Vars, &pars, nil, par.Profile)
 		}
 	}
 	return ``
 }
 
 func elseifTag(par parFunc) string {
-	cond := ifValue((*par.Pars)[`Condition`], par.Vars)
+	cond := ifValue((*par.Pars)[`Condition`], par.Vars, par.Profile)
 	if cond {
 		for _, item := range par.Node.Children {
 			par.Owner.Children = append(par.Owner.Children, item)
@@ -651,3 +897,210 @@ func cmpTimeTag(par parFunc) string {
 	}
 	return `1`
 }
+
+// setSource keeps the columns and the rows of the named source so ForList can iterate them
+func setSource(vars *map[string]string, name string, cols []string, data [][]string) {
+	if len(name) == 0 {
+		return
+	}
+	out, err := json.Marshal(map[string]interface{}{`columns`: cols, `data`: data})
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling source to JSON")
+		return
+	}
+	(*vars)[`_source_`+name] = string(out)
+}
+
+func getSource(vars *map[string]string, name string) (cols []string, data [][]string, err error) {
+	value, ok := (*vars)[`_source_`+name]
+	if !ok {
+		return nil, nil, fmt.Errorf(`source %s has not been found`, name)
+	}
+	var source struct {
+		Columns []string   `json:"columns"`
+		Data    [][]string `json:"data"`
+	}
+	if err = json.Unmarshal([]byte(value), &source); err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling source from JSON")
+		return nil, nil, err
+	}
+	return source.Columns, source.Data, nil
+}
+
+// sourceNode appends the data node of the source which is built by the template function
+func sourceNode(par parFunc, cols []string, data [][]string) {
+	types := make([]string, len(cols))
+	for i := range types {
+		types[i] = `text`
+	}
+	setSource(par.Vars, (*par.Pars)[`Source`], cols, data)
+	setAllAttr(par)
+	delete(par.Node.Attr, `data`)
+	par.Node.Attr[`columns`] = &cols
+	par.Node.Attr[`types`] = &types
+	par.Node.Attr[`data`] = &data
+	par.Owner.Children = append(par.Owner.Children, par.Node)
+}
+
+// forlistTag processes the body for each row of the source. The values of the row are available
+// as #Source_column# variables and #Source_index# is the number of the row
+func forlistTag(par parFunc) string {
+	name := (*par.Pars)[`Source`]
+	if len(name) == 0 {
+		return ``
+	}
+	cols, data, err := getSource(par.Vars, name)
+	if err != nil {
+		return err.Error()
+	}
+	for i, row := range data {
+		for j, col := range cols {
+			if j < len(row) {
+				(*par.Vars)[name+`_`+col] = row[j]
+			}
+		}
+		(*par.Vars)[name+`_index`] = strconv.Itoa(i + 1)
+		process((*par.Pars)[`Body`], par.Owner, par.Vars, par.Profile)
+	}
+	for _, col := range cols {
+		delete(*par.Vars, name+`_`+col)
+	}
+	delete(*par.Vars, name+`_index`)
+	return ``
+}
+
+const maxRangeCount = 1000
+
+// rangeTag creates the source with id column which contains the numbers from From to To (not including) with Step
+func rangeTag(par parFunc) string {
+	if len((*par.Pars)[`Source`]) == 0 {
+		return ``
+	}
+	from := converter.StrToInt64((*par.Pars)[`From`])
+	to := converter.StrToInt64((*par.Pars)[`To`])
+	step := int64(1)
+	if len((*par.Pars)[`Step`]) > 0 {
+		step = converter.StrToInt64((*par.Pars)[`Step`])
+	}
+	if step == 0 {
+		return `step must not be zero`
+	}
+	data := make([][]string, 0)
+	for i := from; (step > 0 && i < to) || (step < 0 && i > to); i += step {
+		if len(data) == maxRangeCount {
+			return fmt.Sprintf(`range cannot be greater than %d`, maxRangeCount)
+		}
+		data = append(data, []string{converter.Int64ToStr(i)})
+	}
+	sourceNode(par, []string{`id`}, data)
+	return ``
+}
+
+// jsontosourceTag creates the source with key and value columns from JSON object
+func jsontosourceTag(par parFunc) string {
+	if len((*par.Pars)[`Source`]) == 0 {
+		return ``
+	}
+	var obj map[string]interface{}
+	if data := strings.TrimSpace((*par.Pars)[`Data`]); len(data) > 0 {
+		decoder := json.NewDecoder(strings.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&obj); err != nil {
+			return err.Error()
+		}
+	}
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	data := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		switch v := obj[key].(type) {
+		case string:
+			value = v
+		case nil:
+		default:
+			out, err := json.Marshal(v)
+			if err != nil {
+				return err.Error()
+			}
+			value = string(out)
+		}
+		data = append(data, []string{key, value})
+	}
+	sourceNode(par, []string{`key`, `value`}, data)
+	return ``
+}
+
+// calculateTag evaluates the arithmetic expression. Type can be int, float or decimal (default),
+// Prec is the number of digits after the point for float and decimal result
+func calculateTag(par parFunc) string {
+	ret, err := calculate((*par.Pars)[`Exp`])
+	if err != nil {
+		return err.Error()
+	}
+	prec := -1
+	if len((*par.Pars)[`Prec`]) > 0 {
+		prec = converter.StrToInt((*par.Pars)[`Prec`])
+	}
+	switch strings.ToLower((*par.Pars)[`Type`]) {
+	case `int`:
+		return ret.Truncate(0).String()
+	case `float`:
+		if prec < 0 {
+			prec = 6
+		}
+		return ret.StringFixed(int32(prec))
+	}
+	if prec >= 0 {
+		return ret.Round(int32(prec)).String()
+	}
+	return ret.String()
+}
+
+// moneyTag formats the amount of money in the minimal units with the number of digits of the ecosystem
+func moneyTag(par parFunc) string {
+	ret, err := calculate((*par.Pars)[`Exp`])
+	if err != nil {
+		return err.Error()
+	}
+	var digit int
+	if len((*par.Pars)[`Digit`]) > 0 {
+		digit = converter.StrToInt((*par.Pars)[`Digit`])
+	} else {
+		value, err := StateParam(converter.StrToInt64((*par.Vars)[`ecosystem_id`]), `money_digit`)
+		if err != nil {
+			return err.Error()
+		}
+		digit = converter.StrToInt(value)
+	}
+	if digit < 0 || digit > consts.EGS_DIGIT {
+		return fmt.Sprintf(`wrong number of digits %d`, digit)
+	}
+	return ret.Truncate(0).Mul(decimal.New(1, int32(-digit))).StringFixed(int32(digit))
+}
+
+// substrTag returns the part of the string. Offset and Length are counted in characters
+func substrTag(par parFunc) string {
+	source := []rune((*par.Pars)[`Source`])
+	off := converter.StrToInt((*par.Pars)[`Offset`])
+	if off < 0 || off >= len(source) {
+		return ``
+	}
+	end := len(source)
+	if len((*par.Pars)[`Length`]) > 0 {
+		if length := converter.StrToInt((*par.Pars)[`Length`]); length >= 0 && off+length < end {
+			end = off + length
+		}
+	}
+	return string(source[off:end])
+}
+
+func replaceTag(par parFunc) string {
+	if len((*par.Pars)[`Search`]) == 0 {
+		return (*par.Pars)[`Source`]
+	}
+	return strings.Replace((*par.Pars)[`Source`], (*par.Pars)[`Search`], (*par.Pars)[`Replace`], -1)
+}
@@ -0,0 +1,105 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package templatev2
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/AplaProject/go-apla/packages/consts"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProfileItem is the total time of the tag or SQL query
+type ProfileItem struct {
+	Name     string  `json:"name"`
+	Count    int     `json:"count"`
+	Duration float64 `json:"duration"` // in milliseconds
+}
+
+// Profile contains the time of the rendering of the template. The time of the tag includes the time of
+// its nested tags. The profile is passed to the functions of the template which is being rendered.
+type Profile struct {
+	Duration float64        `json:"duration"` // in milliseconds
+	Tags     []*ProfileItem `json:"tags"`
+	Queries  []*ProfileItem `json:"queries"`
+
+	tags    map[string]*ProfileItem
+	queries map[string]*ProfileItem
+}
+
+func addProfileItem(items map[string]*ProfileItem, name string, start time.Time) {
+	item := items[name]
+	if item == nil {
+		item = &ProfileItem{Name: name}
+		items[name] = item
+	}
+	item.Count++
+	item.Duration += time.Since(start).Seconds() * 1000
+}
+
+// addTag adds the time of the tag to the profile, nil profile is skipped
+func (p *Profile) addTag(tag string, start time.Time) {
+	if p != nil {
+		addProfileItem(p.tags, tag, start)
+	}
+}
+
+// addQuery adds the time of SQL query to the profile, nil profile is skipped
+func (p *Profile) addQuery(query string, start time.Time) {
+	if p != nil {
+		addProfileItem(p.queries, query, start)
+	}
+}
+
+func profileList(items map[string]*ProfileItem) []*ProfileItem {
+	list := make([]*ProfileItem, 0, len(items))
+	for _, item := range items {
+		list = append(list, item)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Duration == list[j].Duration {
+			return list[i].Name < list[j].Name
+		}
+		return list[i].Duration > list[j].Duration
+	})
+	return list
+}
+
+// Template2JSONProfile converts templates to JSON data and returns the time of the rendering of tags and SQL queries
+func Template2JSONProfile(input string, full bool, vars *map[string]string) ([]byte, *Profile) {
+	profile := &Profile{tags: make(map[string]*ProfileItem), queries: make(map[string]*ProfileItem)}
+	start := time.Now()
+	out := template2JSON(input, full, vars, profile)
+	profile.Duration = time.Since(start).Seconds() * 1000
+
+	profile.Tags = profileList(profile.tags)
+	profile.Queries = profileList(profile.queries)
+	return out, profile
+}
+
+// String returns the profile in JSON format
+func (p *Profile) String() string {
+	out, err := json.Marshal(p)
+	if err != nil {
+		log.WithFields(log.Fields{"type": consts.JSONMarshallError, "error": err}).Error("marshalling profile to JSON")
+		return err.Error()
+	}
+	return string(out)
+}
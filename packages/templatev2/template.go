@@ -22,6 +22,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/AplaProject/go-apla/packages/consts"
@@ -38,6 +39,9 @@ const (
 	tagData = `data`
 )
 
+// rawBody contains the tags which process their body themselves
+var rawBody = map[string]bool{`forlist`: true}
+
 type node struct {
 	Tag      string                 `json:"tag"`
 	Attr     map[string]interface{} `json:"attr,omitempty"`
@@ -47,11 +51,12 @@ type node struct {
 }
 
 type parFunc struct {
-	Owner *node
-	Node  *node
-	Vars  *map[string]string
-	Pars  *map[string]string
-	Tails *[]*[]string
+	Owner   *node
+	Node    *node
+	Vars    *map[string]string
+	Pars    *map[string]string
+	Tails   *[]*[]string
+	Profile *Profile // the profile of the rendering or nil
 }
 
 type nodeFunc func(par parFunc) string
@@ -114,14 +119,14 @@ func setAllAttr(par parFunc) {
 	}
 }
 
-func ifValue(val string, vars *map[string]string) bool {
+func ifValue(val string, vars *map[string]string, profile *Profile) bool {
 	var (
 		sep   string
 		owner node
 	)
 
 	if strings.IndexByte(val, '(') != -1 {
-		process(val, &owner, vars)
+		process(val, &owner, vars, profile)
 		if len(owner.Children) > 0 {
 			inode := owner.Children[0]
 			if inode.Tag == tagText {
@@ -239,14 +244,19 @@ func appendText(owner *node, text string) {
 	}
 }
 
-func callFunc(curFunc *tplFunc, owner *node, vars *map[string]string, params *[]string, tailpars *[]*[]string) {
+func callFunc(curFunc *tplFunc, owner *node, vars *map[string]string, params *[]string, tailpars *[]*[]string,
+	profile *Profile) {
 	var (
 		out     string
 		curNode node
 	)
+	if profile != nil {
+		defer profile.addTag(curFunc.Tag, time.Now())
+	}
 	pars := make(map[string]string)
 	parFunc := parFunc{
-		Vars: vars,
+		Vars:    vars,
+		Profile: profile,
 	}
 	if curFunc.Params == `*` {
 		for i, v := range *params {
@@ -284,8 +294,8 @@ func callFunc(curFunc *tplFunc, owner *node, vars *map[string]string, params *[]
 	if len(curFunc.Tag) > 0 {
 		curNode.Tag = curFunc.Tag
 		curNode.Attr = make(map[string]interface{})
-		if len(pars[`Body`]) > 0 {
-			process(pars[`Body`], &curNode, vars)
+		if len(pars[`Body`]) > 0 && ((*vars)[`_full`] == `1` || !rawBody[curFunc.Tag]) {
+			process(pars[`Body`], &curNode, vars, profile)
 		}
 		parFunc.Owner = owner
 		//		owner.Children = append(owner.Children, &curNode)
@@ -454,31 +464,50 @@ main:
 	return &params, utf8.RuneCountInString(input[:off]), tailpar
 }
 
-func process(input string, owner *node, vars *map[string]string) {
+func process(input string, owner *node, vars *map[string]string, profile *Profile) {
+	for _, item := range parse(input) {
+		if item.fn == nil {
+			appendText(owner, item.text)
+			continue
+		}
+		curFunc := *item.fn
+		callFunc(&curFunc, owner, vars, item.params, item.tailpars, profile)
+	}
+}
+
+// parse splits the template into the text and the calls of the functions. The result is cached
+// by the source of the template so the changed template is parsed again.
+func parse(input string) []tplCall {
+	if calls, ok := parseCache.get(input); ok {
+		return calls
+	}
 	var (
 		nameOff, shift int
-		curFunc        tplFunc
-		isFunc         bool
-		params         *[]string
-		tailpars       *[]*[]string
+		calls          []tplCall
 	)
 	name := make([]rune, 0, 128)
+	appendCall := func(text string) {
+		if len(text) > 0 {
+			calls = append(calls, tplCall{text: text})
+		}
+	}
 	for off, ch := range input {
 		if shift > 0 {
 			shift--
 			continue
 		}
 		if ch == '(' {
-			if curFunc, isFunc = funcs[string(name[nameOff:])]; isFunc {
-				appendText(owner, string(name[:nameOff]))
+			if curFunc, isFunc := funcs[string(name[nameOff:])]; isFunc {
+				appendCall(string(name[:nameOff]))
 				name = name[:0]
 				nameOff = 0
-				params, shift, tailpars = getFunc(input[off:], curFunc)
-				callFunc(&curFunc, owner, vars, params, tailpars)
+				fn := curFunc
+				params, skip, tailpars := getFunc(input[off:], curFunc)
+				shift = skip
+				calls = append(calls, tplCall{fn: &fn, params: params, tailpars: tailpars})
 				for off+shift+3 < len(input) && input[off+shift+1:off+shift+3] == `.(` {
-					var next int
-					params, next, tailpars = getFunc(input[off+shift+2:], curFunc)
-					callFunc(&curFunc, owner, vars, params, tailpars)
+					params, next, tailpars := getFunc(input[off+shift+2:], curFunc)
+					calls = append(calls, tplCall{fn: &fn, params: params, tailpars: tailpars})
 					shift += next + 2
 				}
 				continue
@@ -489,18 +518,26 @@ func process(input string, owner *node, vars *map[string]string) {
 		}
 		name = append(name, ch)
 	}
-	appendText(owner, string(name))
+	appendCall(string(name))
+	parseCache.set(input, calls)
+	return calls
 }
 
 // Template2JSON converts templates to JSON data
 func Template2JSON(input string, full bool, vars *map[string]string) []byte {
+	return template2JSON(input, full, vars, nil)
+}
+
+// template2JSON converts templates to JSON data and adds the time of the rendering to the profile if it is not nil
+func template2JSON(input string, full bool, vars *map[string]string, profile *Profile) []byte {
 	if full {
 		(*vars)[`_full`] = `1`
 	} else {
 		(*vars)[`_full`] = `0`
 	}
+	delete(*vars, `_block_id`)
 	root := node{}
-	process(input, &root, vars)
+	process(input, &root, vars, profile)
 	if root.Children == nil {
 		return []byte(`[]`)
 	}
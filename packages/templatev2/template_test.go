@@ -17,7 +17,13 @@
 package templatev2
 
 import (
+	"fmt"
+	"reflect"
 	"testing"
+
+	"github.com/AplaProject/go-apla/packages/model"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
 )
 
 type tplItem struct {
@@ -67,7 +73,7 @@ var forTest = tplList{
 		}.Else {Fourth}If(0).Else{ALL right}`,
 		`[{"tag":"text","text":"OK"},{"tag":"div","children":[{"tag":"text","text":"test"}]},{"tag":"text","text":"Else OK"},{"tag":"text","text":"ALL right"}]`},
 	{`Button(Contract: MyContract, Body:My Contract, Class: myclass, Params:"Name=myid,Id=i10,Value")`,
-		`[{"tag":"button","attr":{"class":"myclass","contract":"MyContract","params":{"Id":{"text":"i10","type":"text"},"Name":{"text":"myid","type":"text"},"Value":{"text":"Value","type":"text"}}},"children":[{"tag":"text","text":"My Contract"}]}]`},
+		`[{"tag":"button","attr":{"class":"myclass","contract":"MyContract","params":{"Id":{"text":"i10","type":"text"},"Name":{"text":"myid","type":"text"},"Value":{"text":"Value","type":"text"}},"warnings":["unknown contract MyContract"]},"children":[{"tag":"text","text":"My Contract"}]}]`},
 	{`Simple text +=<b>bold</b>`, `[{"tag":"text","text":"Simple text +=\u0026lt;b\u0026gt;bold\u0026lt;/b\u0026gt;"}]`},
 	{`Div(myclass control, Content of the Div)`, `[{"tag":"div","attr":{"class":"myclass control"},"children":[{"tag":"text","text":"Content of the Div"}]}]`},
 	{`input Div(myclass, Content Div(mypar) the Div)`,
@@ -86,7 +92,7 @@ var forTest = tplList{
 	{`Button(My Contract,, myclass, NewEcosystem, "Name=myid,Id=i10,Value").Style( .btn {
 		border: 10px 10px;
 	})`,
-		`[{"tag":"button","attr":{"class":"myclass","contract":"NewEcosystem","params":{"Id":{"text":"i10","type":"text"},"Name":{"text":"myid","type":"text"},"Value":{"text":"Value","type":"text"}},"style":".btn {\n\t\tborder: 10px 10px;\n\t}"},"children":[{"tag":"text","text":"My Contract"}]}]`},
+		`[{"tag":"button","attr":{"class":"myclass","contract":"NewEcosystem","params":{"Id":{"text":"i10","type":"text"},"Name":{"text":"myid","type":"text"},"Value":{"text":"Value","type":"text"}},"style":".btn {\n\t\tborder: 10px 10px;\n\t}","warnings":["unknown contract NewEcosystem"]},"children":[{"tag":"text","text":"My Contract"}]}]`},
 	{`Div(myclass)Div().Style{
 		.class {
 			text-style: italic;
@@ -169,3 +175,212 @@ var forFullTest = tplList{
 			}.Else {Fourth}If(0).Else{ALL right}.What`,
 		`[{"tag":"if","attr":{"condition":"true"},"children":[{"tag":"text","text":"OK"}],"tail":[{"tag":"else","children":[{"tag":"text","text":"false"}]}]},{"tag":"if","attr":{"condition":"false"},"children":[{"tag":"text","text":"FALSE"}],"tail":[{"tag":"elseif","attr":{"condition":"1"},"children":[{"tag":"text","text":"Else OK"}]},{"tag":"else","children":[{"tag":"text","text":"Fourth"}]}]},{"tag":"if","attr":{"condition":"0"},"tail":[{"tag":"else","children":[{"tag":"text","text":"ALL right"}]}]},{"tag":"text","text":".What"}]`},
 }
+
+func TestPaging(t *testing.T) {
+	vars := map[string]string{`page`: `3`, `order`: `-amount`, `find`: `50%_off`}
+	par := parFunc{Node: &node{Attr: map[string]interface{}{
+		`paging`: map[string]interface{}{},
+		`sort`:   map[string]interface{}{`param`: `order`, `columns`: `name, amount`},
+		`search`: map[string]interface{}{`param`: `find`, `columns`: `name`},
+	}}, Vars: &vars}
+
+	paging := pageParams(par)
+	if offset := setPaging(paging, 45, 20); offset != 40 || paging[`pages`].(int64) != 3 {
+		t.Errorf(`wrong paging %v offset %d`, paging, offset)
+	}
+	vars[`page`] = `10`
+	paging = pageParams(par)
+	if offset := setPaging(paging, 45, 20); offset != 40 || paging[`page`].(int64) != 3 {
+		t.Errorf(`wrong last page %v offset %d`, paging, offset)
+	}
+	if offset := setPaging(paging, 0, 20); offset != 0 || paging[`page`].(int64) != 1 || paging[`pages`].(int64) != 0 {
+		t.Errorf(`wrong empty paging %v offset %d`, paging, offset)
+	}
+	sorting := pageSort(par)
+	if sorting[`column`] != `amount` || sorting[`desc`] != true {
+		t.Errorf(`wrong sort %v`, sorting)
+	}
+	vars[`order`] = `key_id`
+	if sorting = pageSort(par); sorting[`column`] != `` {
+		t.Errorf(`not sortable column %v`, sorting)
+	}
+	search := pageSearch(par)
+	filter := searchFilter(model.Filter{`id`: 1}, search[`columns`].([]string), search[`value`].(string))
+	where, args, err := filter.Where(map[string]string{`id`: `bigint`, `name`: `character varying`})
+	if err != nil || where != `(("id" = ?) AND ((("name" ILIKE ?))))` || args[1] != `%50\%\_off%` {
+		t.Errorf(`wrong search %s %v %v`, where, args, err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	columns := map[string]string{`id`: `bigint`, `name`: `character varying`, `amount`: `numeric`}
+	cases := []struct {
+		filter string
+		where  string
+		args   int
+		err    bool
+	}{
+		{`{"$or": [{"name": "John"}, {"amount": {"$gt": 100}}]}`, `(("name" = ?) OR ("amount" > ?))`, 2, false},
+		{`{"id": {"$nin": [1, 2, 3]}, "$and": [{"name": {"$like": "J%"}}, {"amount": {"$lte": 5}}]}`,
+			`(("name" LIKE ?) AND ("amount" <= ?)) AND "id" NOT IN (?,?,?)`, 5, false},
+		{`{"$or": [{"$and": [{"id": 1}, {"name": null}]}, {"id": {"$in": [7]}}]}`,
+			`(((("id" = ?) AND ("name" IS NULL))) OR ("id" IN (?)))`, 2, false},
+		{`{"id": {"$nin": []}}`, ``, 0, true},
+		{`{"$or": []}`, ``, 0, true},
+		{`{"$and": [1, 2]}`, ``, 0, true},
+		{`{"amount": {"$like": "1%"}}`, ``, 0, true},
+		{`{"$or": [{"key_id": 1}]}`, ``, 0, true},
+	}
+	for _, c := range cases {
+		filter, err := model.ParseFilter(c.filter)
+		if err != nil {
+			t.Errorf(`%s: %v`, c.filter, err)
+			continue
+		}
+		where, args, err := filter.Where(columns)
+		if c.err {
+			if err == nil {
+				t.Errorf(`%s: error is expected`, c.filter)
+			}
+			continue
+		}
+		if err != nil || where != c.where || len(args) != c.args {
+			t.Errorf(`%s: wrong where %s %v %v`, c.filter, where, args, err)
+		}
+	}
+}
+
+func TestReadQuery(t *testing.T) {
+	types := map[string]string{`id`: `bigint`, `name`: `character varying`, `secret`: `text`}
+	perm := &model.ReadPermissions{Table: `true`, Columns: map[string]string{`secret`: `false`, `name`: `true`}}
+	eval := func(cond string) (bool, error) {
+		if cond == `error` {
+			return false, fmt.Errorf(`error`)
+		}
+		return cond == `true`, nil
+	}
+	denied, err := perm.Denied(eval)
+	if err != nil || len(denied) != 1 || !denied[`secret`] {
+		t.Fatalf(`wrong denied columns %v %v`, denied, err)
+	}
+	cases := []struct {
+		columns []string
+		filter  model.Filter
+		order   string
+		query   string
+		err     string
+	}{
+		{nil, model.Filter{`id`: 1}, ``, `select "id","name" from "1_test" where "id" = ? limit 10`, ``},
+		{[]string{`name`}, model.Filter{}, `id`, `select "name" from "1_test" order by "id" asc limit 10`, ``},
+		{[]string{`secret`}, model.Filter{}, ``, ``, `access denied to column secret`},
+		{[]string{`name`}, model.Filter{`$or`: []interface{}{map[string]interface{}{`secret`: `a`}}}, ``, ``,
+			`access denied to column secret`},
+		{[]string{`name`}, model.Filter{}, `secret`, ``, `access denied to column secret`},
+		{[]string{`unknown`}, model.Filter{}, ``, ``, `unknown column unknown`},
+	}
+	for i, c := range cases {
+		query, err := model.BuildReadQuery(`1_test`, types, denied, c.columns, c.filter, c.order)
+		if len(c.err) > 0 {
+			if err == nil || err.Error() != c.err {
+				t.Errorf(`%d: wrong error %v`, i, err)
+			}
+			continue
+		}
+		if err != nil || query.Select(0, 10) != c.query {
+			t.Errorf(`%d: wrong query %v %v`, i, query, err)
+		}
+	}
+
+	rawCases := []struct {
+		perm *model.ReadPermissions
+		err  error
+	}{
+		{&model.ReadPermissions{}, nil},
+		{&model.ReadPermissions{Table: `true`, Columns: map[string]string{`name`: `true`}}, nil},
+		{&model.ReadPermissions{Table: `false`}, model.ErrReadAccess},
+		{&model.ReadPermissions{Table: `error`}, model.ErrReadAccess},
+		{perm, &model.ColumnAccessError{Column: `secret`}},
+		{&model.ReadPermissions{Columns: map[string]string{`b`: `error`, `a`: `false`}},
+			&model.ColumnAccessError{Column: `a`}},
+	}
+	for i, c := range rawCases {
+		if err := c.perm.CheckRaw(eval); !reflect.DeepEqual(err, c.err) {
+			t.Errorf(`%d: wrong raw read error %v`, i, err)
+		}
+	}
+}
+
+func TestFunctions(t *testing.T) {
+	vars := map[string]string{`val`: `932780005`}
+	for _, item := range forFuncTest {
+		templ := Template2JSON(item.input, false, &vars)
+		if string(templ) != item.want {
+			t.Errorf(`wrong json %s != %s`, templ, item.want)
+		}
+	}
+}
+
+var forFuncTest = tplList{
+	{`Calculate(Exp: (342278783438 + 5000)*(#val# - 932780000), Type: int)`,
+		`[{"tag":"text","text":"1711393942190"}]`},
+	{`Calculate(10/3, Prec: 3)Span(Calculate(1/0))Calculate(5.5*2, float, 2)`,
+		`[{"tag":"text","text":"3.333"},{"tag":"span","children":[{"tag":"text","text":"division by zero"}]},{"tag":"text","text":"11.00"}]`},
+	{`Money(12345, 2)`, `[{"tag":"text","text":"123.45"}]`},
+	{`Substr(Привет мир, 3, 5)Span(Replace(a-b-c, -, +))`,
+		`[{"tag":"text","text":"вет м"},{"tag":"span","children":[{"tag":"text","text":"a+b+c"}]}]`},
+	{`Range(src, 1, 4)ForList(src){Span(#src_index#: #src_id#)}`,
+		`[{"tag":"range","attr":{"columns":["id"],"data":[["1"],["2"],["3"]],"from":"1","source":"src","to":"4","types":["text"]}},{"tag":"span","children":[{"tag":"text","text":"1: 1"}]},{"tag":"span","children":[{"tag":"text","text":"2: 2"}]},{"tag":"span","children":[{"tag":"text","text":"3: 3"}]}]`},
+	{`JsonToSource(pv, {"name": "John", "tags": [1, 2]})ForList(pv){Div(, #pv_key#=#pv_value#)}`,
+		`[{"tag":"jsontosource","attr":{"columns":["key","value"],"data":[["name","John"],["tags","[1,2]"]],"source":"pv","types":["text","text"]}},{"tag":"div","children":[{"tag":"text","text":"name=John"}]},{"tag":"div","children":[{"tag":"text","text":"tags=[1,2]"}]}]`},
+	{`ForList(unknown){Span(X)}`, `[{"tag":"text","text":"source unknown has not been found"}]`},
+	{`JsonToSource(pv, {"a": "x<i>", "b": "#c#"})ForList(pv){Div(#pv_key#){#pv_value#}}`,
+		`[{"tag":"jsontosource","attr":{"columns":["key","value"],"data":[["a","x\u003ci\u003e"],["b","#c#"]],"source":"pv","types":["text","text"]}},{"tag":"div","attr":{"class":"a"},"children":[{"tag":"text","text":"x\u0026lt;i\u0026gt;"}]},{"tag":"div","attr":{"class":"b"},"children":[{"tag":"text","text":"#c#"}]}]`},
+	{`Range(src,1,4)ForList(src){Span(Calculate(#src_id# * 2))}`,
+		`[{"tag":"range","attr":{"columns":["id"],"data":[["1"],["2"],["3"]],"from":"1","source":"src","to":"4","types":["text"]}},{"tag":"span","children":[{"tag":"text","text":"2"}]},{"tag":"span","children":[{"tag":"text","text":"4"}]},{"tag":"span","children":[{"tag":"text","text":"6"}]}]`},
+	{`Range(src,150,451,150)ForList(src){Span(Money(#src_id#, 2))}`,
+		`[{"tag":"range","attr":{"columns":["id"],"data":[["150"],["300"],["450"]],"from":"150","source":"src","step":"150","to":"451","types":["text"]}},{"tag":"span","children":[{"tag":"text","text":"1.50"}]},{"tag":"span","children":[{"tag":"text","text":"3.00"}]},{"tag":"span","children":[{"tag":"text","text":"4.50"}]}]`},
+	{`Range(src,1,4)ForList(src){If(#src_id# == 2){Span(two)}.Else{Span(#src_id#)}}`,
+		`[{"tag":"range","attr":{"columns":["id"],"data":[["1"],["2"],["3"]],"from":"1","source":"src","to":"4","types":["text"]}},{"tag":"span","children":[{"tag":"text","text":"1"}]},{"tag":"span","children":[{"tag":"text","text":"two"}]},{"tag":"span","children":[{"tag":"text","text":"3"}]}]`},
+}
+
+func TestProfile(t *testing.T) {
+	input := `Div(){Span(Calculate(1+2))Span(Text)}`
+	vars := make(map[string]string)
+	want := Template2JSON(input, false, &vars)
+	if _, ok := parseCache.get(input); !ok {
+		t.Errorf(`template has not been cached`)
+	}
+	out, profile := Template2JSONProfile(input, false, &vars)
+	if string(out) != string(want) {
+		t.Errorf(`wrong json %s != %s`, out, want)
+	}
+	counts := make(map[string]int)
+	for _, item := range profile.Tags {
+		counts[item.Name] = item.Count
+	}
+	if len(counts) != 3 || counts[`div`] != 1 || counts[`span`] != 2 || counts[`calculate`] != 1 {
+		t.Errorf(`wrong profile %s`, profile)
+	}
+	if _, next := Template2JSONProfile(input, false, &vars); len(next.Tags) != 3 || next.Tags[0].Count > 2 {
+		t.Errorf(`profiles of the renderings have been mixed %s`, next)
+	}
+}
+
+func TestFormFields(t *testing.T) {
+	if err := smart.Compile(`contract TplForm {
+		data {
+			Name string
+			Amount money
+			Comment string "optional"
+		}
+	}`, &script.OwnerInfo{StateID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{`ecosystem_id`: `1`}
+	templ := Template2JSON(`Form(){Input(Name)Input(Wallet)Input(amt).Validate(minLength: 1)
+		Button(Send, Contract: TplForm, Params: "Comment=Val(Name),Amount=Val(amt),Id=10")}`, false, &vars)
+	want := `[{"tag":"form","attr":{"warnings":["input Wallet is not the field of the contract"]},"children":[{"tag":"input","attr":{"field":{"name":"Comment","type":"string","tags":"optional","optional":true},"name":"Name"}},{"tag":"input","attr":{"name":"Wallet"}},{"tag":"input","attr":{"field":{"name":"Amount","type":"decimal.Decimal"},"name":"amt","validate":{"field":{"name":"Amount","type":"decimal.Decimal"},"minlength":"1"}}},{"tag":"button","attr":{"contract":"TplForm","fields":[{"name":"Name","type":"string"},{"name":"Amount","type":"decimal.Decimal"},{"name":"Comment","type":"string","tags":"optional","optional":true}],"params":{"Amount":{"params":["amt"],"type":"Val"},"Comment":{"params":["Name"],"type":"Val"},"Id":{"text":"10","type":"text"}},"warnings":["parameter Id is not the field of contract TplForm","field Name of contract TplForm has no input"]},"children":[{"tag":"text","text":"Send"}]}]}]`
+	if string(templ) != want {
+		t.Errorf(`wrong json %s != %s`, templ, want)
+	}
+}
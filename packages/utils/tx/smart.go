@@ -7,10 +7,17 @@ type SmartContract struct {
 	TokenEcosystem int64
 	MaxSum         string
 	PayOver        string
+	Nonce          int64
 	Data           []byte
 }
 
-func (s SmartContract) ForSign() string {
-	return fmt.Sprintf("%d,%d,%d,%d,%s,%s", s.Type, s.Time, s.KeyID,
+// ForSign returns the signed part of the header, the nonce is signed only if it has been specified
+// and nonceFork is true, so the transactions before the activation of the nonce are signed as earlier
+func (s SmartContract) ForSign(nonceFork bool) string {
+	forSign := fmt.Sprintf("%d,%d,%d,%d,%s,%s", s.Type, s.Time, s.KeyID,
 		s.TokenEcosystem, s.MaxSum, s.PayOver)
+	if nonceFork && s.Nonce != 0 {
+		forSign += fmt.Sprintf(",%d", s.Nonce)
+	}
+	return forSign
 }
@@ -72,6 +72,14 @@ var (
 	WalletAddress = flag.String("walletAddress", "", "walletAddress for forging ")
 	// TCPHost is the tcp host
 	TCPHost = flag.String("tcpHost", "", "tcpHost (e.g. 127.0.0.1)")
+	// TCPEncryption is the mode of the encryption of TCP connections between full nodes
+	TCPEncryption = flag.Int64("tcpEncryption", TCPEncryptionEnabled, "0 - disable, 1 - enable, 2 - require encryption between full nodes")
+	// SnapshotHash is the trusted hash of the snapshot for the first load of the blockchain
+	SnapshotHash = flag.String("snapshotHash", "", "Trusted snapshot for the first load in the format block_id,hash")
+	// MempoolKeyLimit is the maximum number of the pending transactions of one key
+	MempoolKeyLimit = flag.Int64("mempoolKeyLimit", 100, "The maximum number of the pending transactions of one key")
+	// MempoolMaxSize is the maximum size of the pending transactions in bytes
+	MempoolMaxSize = flag.Int64("mempoolMaxSize", 32<<20, "The maximum size of the pending transactions in bytes")
 	// ListenHTTPPort is HTTP port
 	ListenHTTPPort = flag.String("listenHttpPort", "7079", "ListenHTTPPort")
 	// GenerateFirstBlock show if the first block must be generated
@@ -718,6 +726,13 @@ func TypeInt(txType string) int64 {
 	return 0
 }
 
+// The modes of the encryption of TCP connections between full nodes
+const (
+	TCPEncryptionDisabled = iota
+	TCPEncryptionEnabled
+	TCPEncryptionRequired
+)
+
 // TCPConn connects to the address
 func TCPConn(Addr string) (net.Conn, error) {
 	conn, err := net.DialTimeout("tcp", Addr, 10*time.Second)
@@ -747,9 +762,13 @@ func GetBlockBody(host string, blockID int64, dataTypeBlockBody int64) ([]byte,
 		return nil, ErrInfo(err)
 	}
 	defer conn.Close()
+	return ReadBlockBody(conn, blockID, dataTypeBlockBody)
+}
 
+// ReadBlockBody requests the block data through the connection
+func ReadBlockBody(conn io.ReadWriter, blockID int64, dataTypeBlockBody int64) ([]byte, error) {
 	// send the type of data
-	_, err = conn.Write(converter.DecToBin(dataTypeBlockBody, 2))
+	_, err := conn.Write(converter.DecToBin(dataTypeBlockBody, 2))
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("writing data type block body to connection")
 		return nil, ErrInfo(err)
@@ -764,7 +783,7 @@ func GetBlockBody(host string, blockID int64, dataTypeBlockBody int64) ([]byte,
 
 	// recieve the data size as a response that server wants to transfer
 	buf := make([]byte, 4)
-	_, err = conn.Read(buf)
+	_, err = io.ReadFull(conn, buf)
 	if err != nil {
 		log.WithFields(log.Fields{"type": consts.IOError, "error": err}).Error("reading block data size from connection")
 		return nil, ErrInfo(err)
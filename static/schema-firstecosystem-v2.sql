@@ -550,4 +550,68 @@ contract Import {
         ImportList($list["tables"], "NewTable")
         ImportData($list["data"])
     }
-}', '%[1]d','ContractConditions(`MainCondition`)');
\ No newline at end of file
+}', '%[1]d','ContractConditions(`MainCondition`)'),
+('27','contract AddValidator {
+    data {
+        Host string
+        KeyID int
+        PublicKey string
+    }
+    conditions {
+        ContractConditions(`MainCondition`)
+    }
+    action {
+        AddFullNode($Host, $KeyID, $PublicKey)
+    }
+}', '%[1]d','ContractConditions(`MainCondition`)'),
+('28','contract RemoveValidator {
+    data {
+        KeyID int
+    }
+    conditions {
+        ContractConditions(`MainCondition`)
+    }
+    action {
+        RemoveFullNode($KeyID)
+    }
+}', '%[1]d','ContractConditions(`MainCondition`)'),
+('29','contract SuspendValidator {
+    data {
+        KeyID int
+    }
+    conditions {
+        if !IsValidator($key_id) || $key_id == $KeyID || !ValidatorFaulty($KeyID) {
+            ContractConditions(`MainCondition`)
+        }
+    }
+    action {
+        SuspendFullNode($KeyID)
+    }
+}', '%[1]d','ContractConditions(`MainCondition`)'),
+('30','contract ResumeValidator {
+    data {
+        KeyID int
+    }
+    conditions {
+        ContractConditions(`MainCondition`)
+    }
+    action {
+        ResumeFullNode($KeyID)
+    }
+}', '%[1]d','ContractConditions(`MainCondition`)'),
+('31','contract ReportDoubleSign {
+    data {
+        ForSign1 string
+        Sign1 string
+        ForSign2 string
+        Sign2 string
+    }
+    conditions {
+        if !IsValidator($key_id) {
+            ContractConditions(`MainCondition`)
+        }
+    }
+    action {
+        DoubleSignEvidence($ForSign1, $Sign1, $ForSign2, $Sign2)
+    }
+}', '%[1]d','ContractConditions(`MainCondition`)');
@@ -114,6 +114,14 @@ INSERT INTO system_parameters ("name", "value", "conditions") VALUES
 ('max_fuel_block', '100000', 'true'),
 ('commission_size', '3', 'true'),
 ('commission_wallet', '', 'true'),
+('tcp_auth_required', '0', 'true'),
+('snapshot_period', '0', 'true'),
+('snapshot_hash', '', 'false'),
+('validator_max_missed', '50', 'true'),
+('validator_min_slots', '100', 'true'),
+('validators_fork_height', '0', 'true'),
+('nonce_fork_height', '0', 'true'),
+('vm_cost_table', '', 'true'),
 ('fuel_rate', '[["1","1000000000000000"]]', 'true');
 
 CREATE TABLE "system_contracts" (
@@ -171,9 +179,15 @@ DROP TABLE IF EXISTS "transactions"; CREATE TABLE "transactions" (
 "key_id" bigint NOT NULL DEFAULT '0',
 "counter" smallint NOT NULL DEFAULT '0',
 "sent" smallint NOT NULL DEFAULT '0',
-"verified" smallint NOT NULL DEFAULT '1'
+"verified" smallint NOT NULL DEFAULT '1',
+"time" bigint NOT NULL DEFAULT '0',
+"nonce" bigint NOT NULL DEFAULT '0',
+"max_sum" decimal(30) NOT NULL DEFAULT '0',
+"payover" decimal(30,18) NOT NULL DEFAULT '0',
+"size" bigint NOT NULL DEFAULT '0'
 );
 ALTER TABLE ONLY "transactions" ADD CONSTRAINT transactions_pkey PRIMARY KEY (hash);
+CREATE INDEX "transactions_index_key" ON "transactions" (key_id, nonce);
 
 DROP SEQUENCE IF EXISTS rollback_tx_id_seq CASCADE;
 CREATE SEQUENCE rollback_tx_id_seq START WITH 1;
@@ -213,3 +227,60 @@ ALTER TABLE ONLY "my_node_keys" ADD CONSTRAINT my_node_keys_pkey PRIMARY KEY (id
 DROP TABLE IF EXISTS "stop_daemons"; CREATE TABLE "stop_daemons" (
 "stop_time" int NOT NULL DEFAULT '0'
 );
+
+DROP TABLE IF EXISTS "peers"; CREATE TABLE "peers" (
+"host" varchar(255) NOT NULL DEFAULT '',
+"score" int NOT NULL DEFAULT '0',
+"banned_until" bigint NOT NULL DEFAULT '0',
+"ban_reason" text NOT NULL DEFAULT '',
+"last_seen" bigint NOT NULL DEFAULT '0'
+);
+ALTER TABLE ONLY "peers" ADD CONSTRAINT peers_pkey PRIMARY KEY (host);
+
+DROP TABLE IF EXISTS "votes"; CREATE TABLE "votes" (
+"block_id" bigint NOT NULL DEFAULT '0',
+"round" int NOT NULL DEFAULT '0',
+"type" smallint NOT NULL DEFAULT '0',
+"key_id" bigint NOT NULL DEFAULT '0',
+"hash" bytea NOT NULL DEFAULT '',
+"signature" bytea NOT NULL DEFAULT ''
+);
+ALTER TABLE ONLY "votes" ADD CONSTRAINT votes_pkey PRIMARY KEY (block_id, round, type, key_id);
+
+DROP TABLE IF EXISTS "finalized_blocks"; CREATE TABLE "finalized_blocks" (
+"block_id" bigint NOT NULL DEFAULT '0',
+"hash" bytea NOT NULL DEFAULT '',
+"time" bigint NOT NULL DEFAULT '0'
+);
+ALTER TABLE ONLY "finalized_blocks" ADD CONSTRAINT finalized_blocks_pkey PRIMARY KEY (block_id);
+
+DROP TABLE IF EXISTS "validators"; CREATE TABLE "validators" (
+"id" bigint NOT NULL DEFAULT '0',
+"produced" bigint NOT NULL DEFAULT '0',
+"missed" bigint NOT NULL DEFAULT '0',
+"double_signed" bigint NOT NULL DEFAULT '0',
+"suspended" bigint NOT NULL DEFAULT '0',
+"node" text NOT NULL DEFAULT '',
+"rb_id" bigint NOT NULL DEFAULT '0'
+);
+ALTER TABLE ONLY "validators" ADD CONSTRAINT validators_pkey PRIMARY KEY (id);
+
+DROP TABLE IF EXISTS "validator_evidence"; CREATE TABLE "validator_evidence" (
+"id" bigint NOT NULL DEFAULT '0',
+"key_id" bigint NOT NULL DEFAULT '0',
+"block_id" bigint NOT NULL DEFAULT '0',
+"rb_id" bigint NOT NULL DEFAULT '0'
+);
+ALTER TABLE ONLY "validator_evidence" ADD CONSTRAINT validator_evidence_pkey PRIMARY KEY (id);
+CREATE UNIQUE INDEX "validator_evidence_index_key" ON "validator_evidence" (key_id, block_id);
+
+DROP TABLE IF EXISTS "double_signs"; CREATE TABLE "double_signs" (
+"key_id" bigint NOT NULL DEFAULT '0',
+"block_id" bigint NOT NULL DEFAULT '0',
+"for_sign1" text NOT NULL DEFAULT '',
+"sign1" bytea NOT NULL DEFAULT '',
+"for_sign2" text NOT NULL DEFAULT '',
+"sign2" bytea NOT NULL DEFAULT '',
+"time" bigint NOT NULL DEFAULT '0'
+);
+ALTER TABLE ONLY "double_signs" ADD CONSTRAINT double_signs_pkey PRIMARY KEY (key_id, block_id);
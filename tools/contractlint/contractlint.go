@@ -0,0 +1,75 @@
+// Copyright 2016 The go-daylight Authors
+// This file is part of the go-daylight library.
+//
+// The go-daylight library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-daylight library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-daylight library. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	// parser registers the extended functions and their cost in the virtual machine
+	_ "github.com/AplaProject/go-apla/packages/parser"
+	"github.com/AplaProject/go-apla/packages/script"
+	"github.com/AplaProject/go-apla/packages/smart"
+)
+
+// Message is the problem which has been found in the file
+type Message struct {
+	File string `json:"file"`
+	script.LintMessage
+}
+
+// The program checks the source code of contracts before sending it with NewContract or EditContract
+// and prints the found problems as JSON array. The exit code is 1 if some file cannot be compiled.
+//
+//	contractlint contract1.sim contract2.sim
+func main() {
+	// the command line has been already parsed by the imported packages, so there are only names of files
+	flag.Parse()
+	files := flag.Args()
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, `Usage: contractlint file1.sim [file2.sim ...]`)
+		os.Exit(2)
+	}
+	messages := make([]Message, 0)
+	failed := false
+	for _, file := range files {
+		src, err := ioutil.ReadFile(file)
+		if err != nil {
+			messages = append(messages, Message{file, script.LintMessage{Level: script.LintError, Message: err.Error()}})
+			failed = true
+			continue
+		}
+		for _, msg := range smart.Lint(string(src), &script.OwnerInfo{StateID: 1, Active: true}) {
+			if msg.Level == script.LintError {
+				failed = true
+			}
+			messages = append(messages, Message{file, *msg})
+		}
+	}
+	out, err := json.MarshalIndent(messages, ``, `  `)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	fmt.Println(string(out))
+	if failed {
+		os.Exit(1)
+	}
+}
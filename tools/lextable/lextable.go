@@ -33,7 +33,7 @@ type States map[string]Action
 
 const (
 	// AlphaSize is the length of alphabet
-	AlphaSize = 33
+	AlphaSize = 34
 )
 
 /* Здесь мы определяем алфавит, с которым будет работать наш язык и описываем конечный автомат, который
@@ -47,7 +47,7 @@ var (
 	lexem = map[string]uint32{``: 0, `sys`: 1, `oper`: 2, `number`: 3, `ident`: 4, `newline`: 5, `string`: 6,
 		`comment`: 7}
 	flags    = map[string]uint32{`next`: 1, `push`: 2, `pop`: 4, `skip`: 8}
-	alphabet = []byte{0x01, 0x0a, ' ', '`', '"', ';', '(', ')', '[', ']', '{', '}', '&',
+	alphabet = []byte{0x01, 0x0a, ' ', '`', '"', ';', '(', ')', '[', ']', '{', '}', ':', '&',
 		//           default  n    s    q    Q
 		'|', '#', '.', ',', '<', '>', '=', '!', '*', '$', '@',
 		'+', '-', '/', '\\', '0', '1', 'a', '_', 128}
@@ -70,7 +70,7 @@ var (
 	states = `{
 	"main": {
 			"n;": ["main", "newline", "next"],
-			"()#[],{}": ["main", "sys", "next"],
+			"()#[],{}:": ["main", "sys", "next"],
 			"s": ["main", "", "next"],
 			"q": ["string", "", "push next"],
 			"Q": ["dstring", "", "push next"],